@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errBadArgument    = errors.New("bad argument")
+)
+
+const (
+	// maxCommandLength is the longest command accepted, which leaves plenty of room for a message
+	maxCommandLength = 4096
+	// messageKey is the alert key of messages sent as commands, a new message replaces the one showing
+	messageKey  = "message"
+	maxContrast = 255
+	// minAcceptWait and maxAcceptWait bound how long to wait after failing to accept a connection
+	minAcceptWait = 5 * time.Millisecond
+	maxAcceptWait = time.Second
+)
+
+// commandFunc carries out a command given what follows its name, returning what it did
+type commandFunc func(c *controller, now time.Time, args string) (string, error)
+
+// commands are what can be sent to the control socket, to /command and over MQTT, by name
+var commands = map[string]commandFunc{
+	"next": func(c *controller, now time.Time, _ string) (string, error) {
+		return "showing page " + c.svc.display.pages.Next(now), nil
+	},
+	"previous": func(c *controller, now time.Time, _ string) (string, error) {
+		return "showing page " + c.svc.display.pages.Previous(now), nil
+	},
+	"pages": func(c *controller, _ time.Time, _ string) (string, error) {
+		return strings.Join(c.svc.display.pages.Names(), ", "), nil
+	},
+	"pin": func(c *controller, now time.Time, args string) (string, error) {
+		err := c.svc.display.pages.Pin(now, args)
+		if err != nil {
+			return "", fmt.Errorf("error pinning page: %w", err)
+		}
+
+		return "pinned page " + args, nil
+	},
+	"unpin": func(c *controller, now time.Time, _ string) (string, error) {
+		c.svc.display.pages.Unpin(now)
+
+		return "unpinned", nil
+	},
+	"brightness": (*controller).brightness,
+	"power":      (*controller).power,
+	"ack": func(c *controller, now time.Time, _ string) (string, error) {
+		return fmt.Sprintf("acknowledged %d alerts", c.svc.display.alerts.Acknowledge(now)), nil
+	},
+	"message": (*controller).message,
+	"reload": func(c *controller, _ time.Time, _ string) (string, error) {
+		err := c.svc.reloadConfig()
+		if err != nil {
+			return "", err
+		}
+
+		return "reloaded config", nil
+	},
+}
+
+// controller carries out the commands sent to luma-adsb. The pages, alerts, display and config are each safe to
+// change while a frame is being drawn, so commands act on them straight away.
+type controller struct {
+	svc      *services
+	oledData *oled.Display
+	// token is what commands posted over HTTP have to be sent with as a bearer token
+	token string
+}
+
+// run carries out line, a command's name followed by what it needs, logging what it did
+func (c *controller) run(line string) (string, error) {
+	name, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+	command, ok := commands[strings.ToLower(name)]
+	if !ok {
+		err := fmt.Errorf("%w %q, the commands are %s", errUnknownCommand, name,
+			strings.Join(slices.Sorted(maps.Keys(commands)), ", "))
+		fmt.Printf("%s\n", err)
+
+		return "", err
+	}
+
+	result, err := command(c, time.Now(), strings.TrimSpace(args))
+	if err != nil {
+		fmt.Printf("error running command %q: %s\n", name, err)
+
+		return "", err
+	}
+
+	fmt.Printf("command %q: %s\n", name, result)
+
+	return result, nil
+}
+
+// brightness sets the display's contrast, 0 to 255
+func (c *controller) brightness(_ time.Time, args string) (string, error) {
+	contrast, err := strconv.Atoi(args)
+	if err != nil || contrast < 0 || contrast > maxContrast {
+		return "", fmt.Errorf("%w: brightness should be 0 to %d", errBadArgument, maxContrast)
+	}
+
+	err = c.oledData.SetContrast(contrast)
+	if err != nil {
+		return "", fmt.Errorf("error setting brightness: %w", err)
+	}
+
+	return fmt.Sprintf("brightness set to %d", contrast), nil
+}
+
+// power turns the display "on" or "off"
+func (c *controller) power(_ time.Time, args string) (string, error) {
+	var on bool
+
+	switch strings.ToLower(args) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return "", fmt.Errorf("%w: power should be on or off", errBadArgument)
+	}
+
+	err := c.oledData.SetPower(on)
+	if err != nil {
+		return "", fmt.Errorf("error setting power: %w", err)
+	}
+
+	return "display turned " + strings.ToLower(args), nil
+}
+
+// message shows a message in place of the pages, given as a number of seconds followed by the text
+func (c *controller) message(now time.Time, args string) (string, error) {
+	secondsStr, text, _ := strings.Cut(args, " ")
+
+	seconds, err := strconv.Atoi(secondsStr)
+	if err != nil || seconds <= 0 || strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w: message should be a number of seconds followed by the text", errBadArgument)
+	}
+
+	c.svc.display.alerts.Remove(messageKey)
+
+	// messages are critical so they're shown straight away however many alerts there have been
+	c.svc.display.alerts.Add(now, layout.Alert{
+		Key:      messageKey,
+		Priority: layout.PriorityCritical,
+		Title:    "Message",
+		Message:  strings.TrimSpace(text),
+		Duration: time.Duration(seconds) * time.Second,
+	})
+
+	return fmt.Sprintf("showing message for %ds", seconds), nil
+}
+
+// reply runs line, giving what it did or why it failed as a single line
+func (c *controller) reply(line string) string {
+	result, err := c.run(line)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	return "ok: " + result
+}
+
+// ServeHTTP runs the command posted as the body of the request. Browsers can't send an Authorization header to
+// another site without asking first, so requiring the token also stops other sites' pages sending commands.
+func (c *controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || c.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(c.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "a bearer token is needed to send commands", http.StatusUnauthorized)
+
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCommandLength))
+	if err != nil {
+		http.Error(w, "command is too long", http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	result, err := c.run(string(body))
+
+	switch {
+	case errors.Is(err, errUnknownCommand), errors.Is(err, errBadArgument), errors.Is(err, layout.ErrUnknownPage):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprintln(w, result)
+	}
+}
+
+// serveControl takes commands on the Unix socket at path, a command on each line, answering each with a line starting
+// "ok:" or "error:"
+func serveControl(ctx context.Context, path string, control *controller) {
+	// a socket left behind by an earlier run would stop this one listening
+	if info, err := os.Lstat(path); err == nil && info.Mode().Type() == os.ModeSocket {
+		_ = os.Remove(path)
+	}
+
+	listenConfig := &net.ListenConfig{}
+
+	listener, err := listenConfig.Listen(ctx, "unix", path)
+	if err != nil {
+		fmt.Printf("error listening for commands: %s\n", err)
+
+		return
+	}
+
+	// only the user luma-adsb runs as can send commands
+	err = os.Chmod(path, 0o600)
+	if err != nil {
+		fmt.Printf("error setting control socket permissions: %s\n", err)
+	}
+
+	fmt.Printf("listening for commands on %s\n", path)
+
+	go func() {
+		<-ctx.Done()
+
+		_ = listener.Close()
+	}()
+
+	var wait time.Duration
+
+	for {
+		var conn net.Conn
+
+		conn, err = listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			// errors like running out of file descriptors pass, so wait a while and try again like net/http does
+			wait = min(max(wait*2, minAcceptWait), maxAcceptWait)
+			fmt.Printf("error accepting command connection, trying again in %s: %s\n", wait, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			continue
+		}
+
+		wait = 0
+
+		go control.serveConn(conn)
+	}
+}
+
+// serveConn answers each command sent on conn until it's closed
+func (c *controller) serveConn(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, maxCommandLength), maxCommandLength)
+
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		_, err := fmt.Fprintln(conn, c.reply(scanner.Text()))
+		if err != nil {
+			return
+		}
+	}
+}
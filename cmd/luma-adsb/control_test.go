@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+func TestControllerToken(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{"no token set", "", "Bearer ", http.StatusUnauthorized},
+		{"no authorization", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer guess", http.StatusUnauthorized},
+		{"not bearer", "secret", "Basic secret", http.StatusUnauthorized},
+		// an unknown command gets as far as being run without needing the display
+		{"right token", "secret", "Bearer secret", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/command", strings.NewReader("dance"))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+
+			(&controller{token: tt.token}).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+// newTestController gives a controller with the pages first, second and third and a display without a panel
+func newTestController(t *testing.T) *controller {
+	t.Helper()
+
+	pages, err := layout.NewPages([]config.PageConfig{
+		{Name: "first", Widgets: []config.WidgetConfig{{Type: "text", Text: "1"}}},
+		{Name: "second", Widgets: []config.WidgetConfig{{Type: "text", Text: "2"}}},
+		{Name: "third", Widgets: []config.WidgetConfig{{Type: "text", Text: "3"}}},
+	}, pageTypes)
+	if err != nil {
+		t.Fatalf("error creating pages: %s", err)
+	}
+
+	return &controller{
+		svc:      &services{display: &screens{pages: pages, alerts: layout.NewAlerts(layout.AlertOptions{})}},
+		oledData: &oled.Display{},
+	}
+}
+
+func TestControllerRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		line    string
+		want    string
+		wantErr error
+	}{
+		{"pages", "first, second, third", nil},
+		{"  NEXT  ", "showing page second", nil},
+		{"brightness 0", "brightness set to 0", nil},
+		{"brightness 255", "brightness set to 255", nil},
+		{"brightness 256", "", errBadArgument},
+		{"brightness -1", "", errBadArgument},
+		{"brightness bright", "", errBadArgument},
+		{"brightness", "", errBadArgument},
+		{"power on", "display turned on", nil},
+		{"power OFF", "display turned off", nil},
+		{"power dim", "", errBadArgument},
+		{"message 10 hello there", "showing message for 10s", nil},
+		{"message 10", "", errBadArgument},
+		{"message 10   ", "", errBadArgument},
+		{"message ten hello", "", errBadArgument},
+		{"message 0 hello", "", errBadArgument},
+		{"message -5 hello", "", errBadArgument},
+		{"ack", "acknowledged 0 alerts", nil},
+		{"pin nowhere", "", layout.ErrUnknownPage},
+		{"dance", "", errUnknownCommand},
+		{"", "", errUnknownCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := newTestController(t).run(tt.line)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %q and error %v, want %q and %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestControllerPages(t *testing.T) {
+	t.Parallel()
+
+	control := newTestController(t)
+
+	steps := []struct {
+		line string
+		want string
+		page string
+	}{
+		{"next", "showing page second", "second"},
+		{"next", "showing page third", "third"},
+		{"next", "showing page first", "first"},
+		{"previous", "showing page third", "third"},
+		{"pin second", "pinned page second", "second"},
+		// moving while pinned moves the pin
+		{"previous", "showing page first", "first"},
+		{"unpin", "unpinned", "first"},
+	}
+
+	for _, step := range steps {
+		got, err := control.run(step.line)
+		if err != nil || got != step.want {
+			t.Errorf("%s: got %q and error %v, want %q", step.line, got, err, step.want)
+		}
+
+		if page, _ := control.svc.display.pages.Current(time.Now()); page.Name() != step.page {
+			t.Errorf("%s: showing %s, want %s", step.line, page.Name(), step.page)
+		}
+	}
+}
+
+func TestControllerMessage(t *testing.T) {
+	t.Parallel()
+
+	control := newTestController(t)
+
+	_, err := control.run("message 10 hello")
+	if err != nil {
+		t.Fatalf("error sending message: %s", err)
+	}
+
+	// a new message replaces the one showing rather than queueing behind it
+	_, err = control.run("message 10 hello again")
+	if err != nil {
+		t.Fatalf("error sending message: %s", err)
+	}
+
+	if _, ok := control.svc.display.alerts.Current(time.Now()); !ok {
+		t.Fatalf("got no message showing")
+	}
+
+	if got := control.svc.display.alerts.Acknowledge(time.Now()); got != 1 {
+		t.Errorf("got %d alerts acknowledged, want the one message", got)
+	}
+
+	if _, ok := control.svc.display.alerts.Current(time.Now()); ok {
+		t.Errorf("got an alert showing after acknowledging the message")
+	}
+}
+
+func TestControllerServeConn(t *testing.T) {
+	t.Parallel()
+
+	control := newTestController(t)
+	client, server := net.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		control.serveConn(server)
+		close(done)
+	}()
+
+	reader := bufio.NewReader(client)
+
+	steps := []struct {
+		line string
+		want string
+	}{
+		{"pages\n", "ok: first, second, third\n"},
+		// blank lines aren't answered
+		{"\n  \nnext\n", "ok: showing page second\n"},
+		{"power dim\n", "error: bad argument: power should be on or off\n"},
+		{"dance\n", "error: unknown command \"dance\""},
+	}
+
+	for _, step := range steps {
+		_, err := client.Write([]byte(step.line))
+		if err != nil {
+			t.Fatalf("error writing %q: %s", step.line, err)
+		}
+
+		got, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error reading the answer to %q: %s", step.line, err)
+		}
+
+		if !strings.HasPrefix(got, step.want) {
+			t.Errorf("%q: got %q, want %q", step.line, got, step.want)
+		}
+	}
+
+	_ = client.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("still serving after the connection closed")
+	}
+}
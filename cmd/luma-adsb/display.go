@@ -0,0 +1,453 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image/draw"
+	"strings"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/aircraftdb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/oled"
+	"github.com/swills/luma-adsb/internal/routes"
+	"github.com/swills/luma-adsb/internal/watchlist"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var messagePrinter = message.NewPrinter(language.English)
+
+// fonts used for particular lines, these fall back to the default font unless set in LUMAADSB_FONTS
+const (
+	statusFont   = "status"
+	distanceFont = "distance"
+)
+
+// pageTypes are the page types defined here rather than in the layout package
+var pageTypes = map[string]layout.PageConstructor{
+	"classic": newClassicPage,
+}
+
+// screens are what the display shows, the configured pages and what's shown in their place
+type screens struct {
+	pages       *layout.Pages
+	alerts      *layout.Alerts
+	emergencies *layout.Emergencies
+	watchlist   *watchlist.Watchlist
+	airlines    *adsb.Airlines
+	routes      *routes.Client
+}
+
+// displayDataFunc builds the data shown by a page with pageFilter
+type displayDataFunc func(pageFilter adsb.Filter) *layout.Data
+
+// displayDataBuilder returns a function building the data shown by a page with the given filter from the latest
+// aircraft, feeders, update status, CPU temperature and config
+func displayDataBuilder(
+	myADSBData *adsb.Data,
+	feederStatus *map[string]adsb.FeederInfo,
+	updateStatus *bool,
+	cpuTemp *int,
+	station adsb.Station,
+	cfg func() *config.Config,
+	display *screens,
+) displayDataFunc {
+	return func(pageFilter adsb.Filter) *layout.Data {
+		return buildDisplayData(myADSBData, feederStatus, updateStatus, cpuTemp, station, cfg(), display, pageFilter)
+	}
+}
+
+// updateDisplay draws the current page, or the alert showing in its place, with the data built by displayData
+func updateDisplay(displayData displayDataFunc, display *screens, oledData *oled.Display) {
+	now := time.Now()
+
+	page, pageFilter := display.pages.Current(now)
+
+	// alerts are shown in place of the page while the pages keep rotating underneath
+	if alert, ok := display.alerts.Current(now); ok {
+		page = alert
+	}
+
+	data := displayData(pageFilter)
+
+	oled.Render(oledData, func(dst draw.Image) {
+		page.Draw(dst, data)
+	})
+}
+
+// buildDisplayData gathers everything the pages show into a single snapshot, counting and searching only the aircraft
+// that pass both the configured filter and the page's filter
+func buildDisplayData(
+	myADSBData *adsb.Data,
+	feederStatus *map[string]adsb.FeederInfo,
+	updateStatus *bool,
+	cpuTemp *int,
+	station adsb.Station,
+	cfg *config.Config,
+	display *screens,
+	pageFilter adsb.Filter,
+) *layout.Data {
+	feeders := *feederStatus
+
+	planes := filterPlanes(myADSBData.Planes, station, cfg.Filter, pageFilter)
+
+	// count the same aircraft the closest search considers
+	numPlanesWithPos := station.CountWithPosition(adsb.Data{Planes: planes})
+
+	goodCount, badCount := countFeeders(feeders)
+
+	data := &layout.Data{
+		Now:             time.Now(),
+		Station:         station,
+		UpdateAvailable: *updateStatus,
+		CPUTempC:        *cpuTemp,
+		Total:           len(planes),
+		WithPosition:    numPlanesWithPos,
+		WithoutPosition: len(planes) - numPlanesWithPos,
+		FeedersGood:     goodCount,
+		FeedersBad:      badCount,
+		Feeders:         feeders,
+		Aircraft:        planes,
+	}
+
+	data.Watched = watchedAircraft(display.watchlist, data.Aircraft)
+
+	if len(planes) > 0 {
+		data.Closest = findClosest(planes, data.Station, cfg)
+	}
+
+	if data.Closest != nil {
+		decodeFlight(display.airlines, data.Closest, cfg.AirlineNames)
+
+		if route, ok := display.routes.Cached(data.Now, data.Closest.CallSign); ok && route.Plausible {
+			data.Closest.Route = &route
+		}
+	}
+
+	return data
+}
+
+// newStation is the receiver's position along with how old a position it accepts
+func newStation(myLatFloat float64, myLonFloat float64, myAltFloat float64, cfg *config.Config) adsb.Station {
+	return adsb.Station{
+		Latitude:       myLatFloat,
+		Longitude:      myLonFloat,
+		Altitude:       myAltFloat,
+		MaxPositionAge: cfg.PositionMaxAge.Duration,
+	}
+}
+
+// watchedAircraft gives the name of the watchlist entry each watched aircraft matches, by hex
+func watchedAircraft(watched *watchlist.Watchlist, planes []adsb.Aircraft) map[string]string {
+	matches := make(map[string]string)
+
+	for _, plane := range planes {
+		if name, ok := watched.Match(plane); ok {
+			matches[plane.Hex] = name
+		}
+	}
+
+	return matches
+}
+
+// decodeFlight works out the closest aircraft's airline and flight number, naming it by them if airlineNames is set
+func decodeFlight(airlines *adsb.Airlines, closest *layout.Closest, airlineNames bool) {
+	flight, ok := airlines.Decode(closest.CallSign)
+	if !ok {
+		return
+	}
+
+	closest.Flight = &flight
+
+	if airlineNames {
+		closest.Name = flight.String()
+	}
+}
+
+// filterPlanes returns the aircraft that pass every filter
+func filterPlanes(planes []adsb.Aircraft, station adsb.Station, filters ...adsb.Filter) []adsb.Aircraft {
+	filtered := adsb.Data{Planes: planes}
+
+	for _, filter := range filters {
+		filtered = filter.Apply(station, filtered)
+	}
+
+	return filtered.Planes
+}
+
+// findClosest picks the closest aircraft either by where it is now or by where it's predicted to pass closest
+func findClosest(planes []adsb.Aircraft, station adsb.Station, cfg *config.Config) *layout.Closest {
+	var closestPlane adsb.Aircraft
+
+	var err error
+
+	if cfg.Closest == config.ClosestByApproach {
+		closestPlane, _ = adsb.FindClosestApproach(adsb.Data{Planes: planes}, station, cfg.ApproachHorizon.Duration)
+	} else {
+		var nearest []adsb.AircraftInfo
+
+		nearest, err = station.Nearest(adsb.Data{Planes: planes}, 1)
+		if len(nearest) > 0 {
+			closestPlane = nearest[0].Aircraft
+		}
+	}
+
+	if err != nil {
+		fmt.Printf("error finding closest aircraft: %s\n", err)
+
+		return nil
+	}
+
+	if closestPlane.Hex == "" {
+		return nil
+	}
+
+	closest := cmp.Or(strings.TrimSpace(closestPlane.CallSign), closestPlane.Registration, "none")
+
+	relative, err := station.RelativeTo(closestPlane)
+	if err != nil {
+		fmt.Printf("error locating closest aircraft: %s\n", err)
+
+		return nil
+	}
+
+	result := &layout.Closest{
+		Aircraft:         closestPlane,
+		RelativePosition: relative,
+		Name:             closest,
+	}
+
+	approach, ok, err := station.ClosestApproach(closestPlane, cfg.ApproachHorizon.Duration)
+	if err == nil && ok {
+		result.Approach = &approach
+	}
+
+	return result
+}
+
+// countFeeders counts the beast and mlat connections of enabled feeders that are good and bad
+func countFeeders(feederStatus map[string]adsb.FeederInfo) (int, int) {
+	var goodCount int
+
+	var badCount int
+
+	for _, value := range feederStatus {
+		if value.Enabled {
+			if value.BeastStatus == "good" {
+				goodCount++
+			} else if value.BeastStatus != "unknown" {
+				badCount++
+			}
+
+			if value.MLATStatus == "good" {
+				goodCount++
+			} else if value.MLATStatus != "disabled" {
+				badCount++
+			}
+		}
+	}
+
+	return goodCount, badCount
+}
+
+// classicPage is the original fixed layout: a status header followed by the closest aircraft
+type classicPage struct {
+	name     string
+	duration time.Duration
+	// overflow is what the closest aircraft's name does when it's too long, it scrolls unless set otherwise
+	overflow oled.Overflow
+	marquee  *oled.Marquee
+
+	// identity is which of the closest aircraft's names is showing, since when, and which aircraft it names
+	identity      int
+	identitySince time.Time
+	identityHex   string
+}
+
+func newClassicPage(cfg config.PageConfig) (layout.Page, error) {
+	overflow := oled.OverflowScroll
+
+	if cfg.Overflow != "" {
+		var err error
+
+		overflow, err = oled.ParseOverflow(cfg.Overflow)
+		if err != nil {
+			return nil, fmt.Errorf("error in classic page: %w", err)
+		}
+	}
+
+	return &classicPage{
+		name:     cfg.Name,
+		duration: cfg.Duration.Duration,
+		overflow: overflow,
+		marquee:  &oled.Marquee{Speed: cfg.ScrollSpeed, Pause: cfg.ScrollPause.Duration},
+	}, nil
+}
+
+func (p *classicPage) Name() string {
+	return p.name
+}
+
+func (p *classicPage) Duration() time.Duration {
+	return p.duration
+}
+
+func (p *classicPage) Draw(dst draw.Image, data *layout.Data) {
+	var updateString string
+
+	if data.UpdateAvailable {
+		updateString = " " + oled.Icon("up")
+	}
+
+	dispLines := []oled.Line{
+		{
+			Text:  data.Now.Format("15:04:05") + updateString,
+			Right: fmt.Sprintf("%s%2d %2d", oled.Icon("airplane"), data.WithPosition, data.WithoutPosition),
+			Font:  statusFont,
+		},
+	}
+
+	if data.Closest != nil {
+		dispLines = p.addClosest(data, dispLines)
+	}
+
+	oled.DrawLines(dst, dispLines)
+}
+
+const (
+	// hotCPUTempC is the temperature above which the thermometer is replaced by a warning
+	hotCPUTempC = 75
+	// identityTime is the least time the closest aircraft's name and what's known about it are each shown, those
+	// that scroll are shown until they've scrolled to their end and back
+	identityTime = 3 * time.Second
+)
+
+func (p *classicPage) addClosest(data *layout.Data, dispLines []oled.Line) []oled.Line {
+	closestPlane := data.Closest
+
+	icon := aircraftIcon(closestPlane.Category)
+	if _, ok := data.Watched[closestPlane.Hex]; ok {
+		icon = oled.Icon("star")
+	}
+
+	dispLines = append(dispLines, p.identityLine(data, icon))
+
+	distLine := oled.Line{
+		Text: messagePrinter.Sprintf("%.1fmi %s%s", closestPlane.SlantRange, oled.Icon(oled.ArrowIconName(
+			closestPlane.Bearing)), closestPlane.Compass),
+		Font: distanceFont,
+	}
+
+	switch {
+	case closestPlane.Approach != nil && closestPlane.Approach.Overhead() && closestPlane.Approach.Time > 0:
+		distLine.Right = oled.Icon("clock") + formatCountdown(closestPlane.Approach.Time)
+	case closestPlane.HasElevation:
+		distLine.Right = messagePrinter.Sprintf("%.0f°", closestPlane.Elevation)
+	}
+
+	// only add the type, or failing that the category, if there's room for it
+	kind := cmp.Or(closestPlane.TypeCode, closestPlane.Category)
+
+	withKind := distLine.Text + messagePrinter.Sprintf(" (%s)", kind)
+	if kind != "" && fits(distanceFont, withKind, distLine.Right) {
+		distLine.Text = withKind
+	}
+
+	dispLines = append(dispLines, distLine)
+
+	tempIcon := oled.Icon("thermometer")
+	if data.CPUTempC >= hotCPUTempC {
+		tempIcon = oled.Icon("warning")
+	}
+
+	// the icons separate the values, spaces would leave no room for the altitude
+	statusLine := oled.Line{
+		Right: messagePrinter.Sprintf("%s%dC%s%d%s%d", tempIcon, data.CPUTempC, oled.Icon("check"), data.FeedersGood,
+			oled.Icon("cross"), data.FeedersBad),
+	}
+
+	if _, ok := closestPlane.Altitude.(float64); ok {
+		statusLine.Text = messagePrinter.Sprintf("%.0fft", closestPlane.Altitude)
+	}
+
+	return append(dispLines, statusLine)
+}
+
+// identityLine names the closest aircraft, taking turns with what else is known about it, along with the country it's
+// registered in. A new aircraft starts with its name.
+func (p *classicPage) identityLine(data *layout.Data, icon string) oled.Line {
+	closestPlane := data.Closest
+
+	// the registration says more than the hex, unless it's already the name
+	tag := closestPlane.Hex
+	if closestPlane.Registration != "" && closestPlane.Registration != closestPlane.Name {
+		tag = closestPlane.Registration
+	}
+
+	names := []string{messagePrinter.Sprintf("%s (%s)", closestPlane.Name, tag)}
+
+	// take turns with what the aircraft database says about it and where it's going
+	if about := aboutAircraft(closestPlane.Aircraft); about != "" {
+		names = append(names, about)
+	}
+
+	if closestPlane.Route != nil {
+		names = append(names, closestPlane.Route.Codes(oled.Icon("arrow-e")))
+	}
+
+	if closestPlane.Hex != p.identityHex {
+		p.identity, p.identitySince, p.identityHex = 0, data.Now, closestPlane.Hex
+	}
+
+	country := closestPlane.Registry().CountryCode
+	line := func() oled.Line {
+		return oled.Line{Text: icon + names[p.identity%len(names)], Right: country, Overflow: p.overflow}
+	}
+
+	// move on once this one has been read, all of it if it scrolls
+	if data.Now.Sub(p.identitySince) >= max(identityTime, p.marquee.Cycle(line(), oled.Width)) {
+		p.identity, p.identitySince = (p.identity+1)%len(names), data.Now
+	}
+
+	return p.marquee.Scroll(line(), oled.Width, data.Now)
+}
+
+// aboutAircraft describes an aircraft from the aircraft database: its operator, or failing that its model, marked
+// if the database flags it as military or its address is in a military block
+func aboutAircraft(aircraft adsb.Aircraft) string {
+	about := cmp.Or(aircraft.Operator, aircraft.Description)
+
+	if aircraftdb.Flags(aircraft.DBFlags).Military() || aircraft.Registry().Military {
+		about = strings.TrimSpace("MIL " + about)
+	}
+
+	return about
+}
+
+// formatCountdown shows a short time in seconds and anything longer in minutes
+func formatCountdown(remaining time.Duration) string {
+	if remaining < time.Minute {
+		return fmt.Sprintf("%ds", int(remaining.Seconds()))
+	}
+
+	return fmt.Sprintf("%dm", int(remaining.Minutes()))
+}
+
+// fits reports whether text and right can be drawn on the same line without truncating text
+func fits(fontName string, text string, right string) bool {
+	face := oled.Face(fontName)
+
+	return oled.MeasureString(face, text)+oled.MeasureString(face, right) <= oled.Width
+}
+
+// aircraftIcon picks an icon for an ADS-B emitter category
+func aircraftIcon(category string) string {
+	if category == "A7" {
+		return oled.Icon("helicopter")
+	}
+
+	return oled.Icon("airplane")
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/events"
+	"github.com/swills/luma-adsb/internal/geofence"
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/watchlist"
+)
+
+// event types published here rather than by the packages that detect them
+const (
+	emergencyEventType        = "emergency"
+	emergencyClearedEventType = "emergency_cleared"
+	feederDownEventType       = "feeder_down"
+	feederUpEventType         = "feeder_up"
+	cpuHotEventType           = "cpu_hot"
+	cpuCooledEventType        = "cpu_cooled"
+	updateAvailableEventType  = "update_available"
+)
+
+// defaultPriorities are the priorities of event types unless they're set in the config, other types are normal
+var defaultPriorities = map[string]layout.Priority{
+	emergencyEventType:        layout.PriorityCritical,
+	emergencyClearedEventType: layout.PriorityLow,
+	string(geofence.Enter):    layout.PriorityHigh,
+	string(geofence.Dwell):    layout.PriorityHigh,
+	string(geofence.Exit):     layout.PriorityLow,
+	watchlist.Appeared:        layout.PriorityNormal,
+	watchlist.Left:            layout.PriorityLow,
+	feederDownEventType:       layout.PriorityHigh,
+	feederUpEventType:         layout.PriorityLow,
+	cpuHotEventType:           layout.PriorityHigh,
+	cpuCooledEventType:        layout.PriorityLow,
+	updateAvailableEventType:  layout.PriorityLow,
+}
+
+// notifyEventTypes are the event types sent as notifications to targets that don't list their own
+var notifyEventTypes = []string{
+	watchlist.Appeared, emergencyEventType, feederDownEventType, cpuHotEventType, updateAvailableEventType,
+}
+
+// newAlerts creates the alert queue from the config
+func newAlerts(cfg *config.Config) *layout.Alerts {
+	return layout.NewAlerts(layout.AlertOptions{
+		Duration:    cfg.NoticeDuration.Duration,
+		MinDuration: cfg.Alerts.MinDuration.Duration,
+		Cooldown:    cfg.Alerts.Cooldown.Duration,
+		RateLimit:   cfg.Alerts.RateLimit,
+		RateWindow:  time.Minute,
+	})
+}
+
+// alertPriorities combines the default priorities with those in the config
+func alertPriorities(cfg *config.Config) (map[string]layout.Priority, error) {
+	priorities := maps.Clone(defaultPriorities)
+
+	for eventType, name := range cfg.Alerts.Priorities {
+		priority, err := layout.ParsePriority(name)
+		if err != nil {
+			return nil, fmt.Errorf("error in priority of %s: %w", eventType, err)
+		}
+
+		priorities[eventType] = priority
+	}
+
+	return priorities, nil
+}
+
+// newEventBus creates the bus events are published on, logging each one and queueing it as an alert. Emergencies
+// are queued with their own screen when they're detected so they aren't queued again here.
+func newEventBus(alerts *layout.Alerts, priorities map[string]layout.Priority) *events.Bus {
+	bus := &events.Bus{}
+
+	bus.Subscribe(func(event events.Event) {
+		fmt.Printf("%s\n", event.Message)
+	})
+
+	bus.Subscribe(func(event events.Event) {
+		if event.Type == emergencyEventType {
+			return
+		}
+
+		priority, ok := priorities[event.Type]
+		if !ok {
+			priority = layout.PriorityNormal
+		}
+
+		alerts.Add(event.Time, layout.Alert{
+			Key:      event.Key,
+			Priority: priority,
+			Title:    event.Title,
+			Message:  event.Message,
+		})
+	})
+
+	return bus
+}
+
+// newZoneMonitor loads the configured zones, with none configured the monitor never has anything to report
+func newZoneMonitor(cfg *config.Config) (*geofence.Monitor, error) {
+	if cfg.Zones == "" {
+		return geofence.NewMonitor(nil), nil
+	}
+
+	zones, err := geofence.Load(cfg.Zones)
+	if err != nil {
+		return nil, fmt.Errorf("error loading zones: %w", err)
+	}
+
+	return geofence.NewMonitor(zones), nil
+}
+
+// checkZones publishes an event for every aircraft that has entered, left or stayed in a zone since the last update
+func checkZones(monitor *geofence.Monitor, station adsb.Station, bus *events.Bus, myADSBData adsb.Data) {
+	zoneEvents, err := monitor.Update(time.Now(), station, myADSBData)
+	if err != nil {
+		fmt.Printf("error checking zones: %s\n", err)
+	}
+
+	for _, event := range zoneEvents {
+		bus.Publish(event.Event())
+	}
+}
+
+// checkEmergencies publishes an event for every emergency that has started or ended since the last update. A started
+// emergency is alerted at its priority in priorities and stays on the display until it's acknowledged or over.
+func checkEmergencies(emergencies *layout.Emergencies, alerts *layout.Alerts, priorities map[string]layout.Priority,
+	bus *events.Bus, myADSBData adsb.Data) {
+	now := time.Now()
+
+	for _, change := range emergencies.Update(now, myADSBData) {
+		name := strings.TrimSpace(change.Aircraft.CallSign)
+		if name == "" {
+			name = change.Aircraft.Hex
+		}
+
+		event := events.Event{
+			Time:  now,
+			Type:  emergencyEventType,
+			Key:   emergencyEventType + ":" + change.Aircraft.Hex,
+			Hex:   change.Aircraft.Hex,
+			Title: fmt.Sprintf("%s %s", name, change.Status.Short),
+			Message: fmt.Sprintf("%s (%s) declared %s (%s)", name, change.Aircraft.Hex, change.Status.Description,
+				change.Status.Code),
+		}
+
+		if !change.Started {
+			event.Type = emergencyClearedEventType
+			event.Title = fmt.Sprintf("%s %s over", name, change.Status.Short)
+			event.Message = fmt.Sprintf("%s (%s) %s (%s) ended after %s", name, change.Aircraft.Hex,
+				strings.ToLower(change.Status.Description), change.Status.Code, change.Duration.Round(time.Second))
+
+			alerts.Remove(event.Key)
+
+			event.Key = emergencyClearedEventType + ":" + change.Aircraft.Hex
+		} else {
+			alerts.Add(now, layout.Alert{
+				Key:      event.Key,
+				Priority: priorities[emergencyEventType],
+				Title:    event.Title,
+				Message:  event.Message,
+				Page:     emergencies.Page(change.Aircraft.Hex),
+				Sticky:   true,
+			})
+		}
+
+		bus.Publish(event)
+	}
+}
+
+// checkWatchlist publishes an event for every watched aircraft that has appeared or left since the last update
+func checkWatchlist(watched *watchlist.Watchlist, station adsb.Station, bus *events.Bus, myADSBData adsb.Data) {
+	for _, event := range watched.Update(time.Now(), station, myADSBData) {
+		bus.Publish(event)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/events"
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+func TestCheckEmergencies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		priorities map[string]string
+		// interrupts is whether the emergency takes over from a high priority alert that's just been shown
+		interrupts bool
+	}{
+		{"critical by default", nil, true},
+		{"configured", map[string]string{emergencyEventType: "low"}, false},
+		{"configured critical", map[string]string{emergencyEventType: "critical"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			priorities, err := alertPriorities(&config.Config{Alerts: config.AlertConfig{Priorities: tt.priorities}})
+			if err != nil {
+				t.Fatalf("error reading priorities: %s", err)
+			}
+
+			alerts := layout.NewAlerts(layout.AlertOptions{Duration: time.Minute, MinDuration: time.Minute})
+			now := time.Now()
+
+			alerts.Add(now, layout.Alert{Key: "feeder", Priority: layout.PriorityHigh, Title: "feeder down"})
+
+			shown, ok := alerts.Current(now)
+			if !ok {
+				t.Fatalf("got no alert shown, want the feeder alert")
+			}
+
+			plane := adsb.Aircraft{Hex: "a1b2c3", Squawk: "7700"}
+			emergencies := adsb.Data{Planes: []adsb.Aircraft{plane}}
+			checkEmergencies(&layout.Emergencies{}, alerts, priorities, &events.Bus{}, emergencies)
+
+			// every alert page has the same name, so they're told apart by what they draw
+			current, _ := alerts.Current(now)
+			if interrupted := !bytes.Equal(drawTitle(current), drawTitle(shown)); interrupted != tt.interrupts {
+				t.Errorf("got interrupted %t, want %t", interrupted, tt.interrupts)
+			}
+		})
+	}
+}
+
+// drawTitle draws page and returns the pixels of its top half, where the title is, leaving out the count of alerts
+// waiting in the corner
+func drawTitle(page layout.Page) []byte {
+	dst := image.NewGray(image.Rect(0, 0, oled.Width, oled.Height))
+	page.Draw(dst, &layout.Data{Now: time.Now()})
+
+	return dst.Pix[:oled.Height/2*dst.Stride]
+}
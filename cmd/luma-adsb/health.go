@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/events"
+)
+
+// cpuCooledMarginC is how far below hotCPUTempC the CPU has to cool before it's no longer overheating, so a
+// temperature hovering around the limit doesn't raise an event every minute
+const cpuCooledMarginC = 5
+
+// healthMonitor raises events when feeders go down or come back, when the CPU overheats or cools down and when an
+// update becomes available
+type healthMonitor struct {
+	mu sync.Mutex
+
+	bus             *events.Bus
+	feedersDown     map[string]bool
+	hot             bool
+	updateAvailable bool
+}
+
+// checkFeeders publishes an event for every enabled feeder that has gone down or come back up
+func (h *healthMonitor) checkFeeders(feederStatus map[string]adsb.FeederInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.feedersDown == nil {
+		h.feedersDown = make(map[string]bool)
+	}
+
+	now := time.Now()
+
+	for name, info := range feederStatus {
+		down := feederDown(info)
+		if down == h.feedersDown[name] {
+			continue
+		}
+
+		h.feedersDown[name] = down
+
+		event := events.Event{
+			Time:    now,
+			Type:    feederUpEventType,
+			Key:     feederUpEventType + ":" + name,
+			Title:   name + " up",
+			Message: fmt.Sprintf("feeder %s is connected again", name),
+		}
+
+		if down {
+			event.Type = feederDownEventType
+			event.Key = feederDownEventType + ":" + name
+			event.Title = name + " down"
+			event.Message = fmt.Sprintf("feeder %s is down, beast %s, mlat %s", name, info.BeastStatus,
+				info.MLATStatus)
+		}
+
+		h.bus.Publish(event)
+	}
+}
+
+// checkCPUTemp publishes an event when the CPU starts overheating and when it has cooled down
+func (h *healthMonitor) checkCPUTemp(cpuTempC int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event := events.Event{Time: time.Now()}
+
+	switch {
+	case !h.hot && cpuTempC >= hotCPUTempC:
+		h.hot = true
+		event.Type = cpuHotEventType
+		event.Title = fmt.Sprintf("CPU %dC", cpuTempC)
+		event.Message = fmt.Sprintf("CPU is overheating at %dC", cpuTempC)
+	case h.hot && cpuTempC < hotCPUTempC-cpuCooledMarginC:
+		h.hot = false
+		event.Type = cpuCooledEventType
+		event.Title = fmt.Sprintf("CPU %dC", cpuTempC)
+		event.Message = fmt.Sprintf("CPU has cooled down to %dC", cpuTempC)
+	default:
+		return
+	}
+
+	event.Key = event.Type
+
+	h.bus.Publish(event)
+}
+
+// checkUpdate publishes an event when an adsb.im update becomes available
+func (h *healthMonitor) checkUpdate(updateAvailable bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if updateAvailable == h.updateAvailable {
+		return
+	}
+
+	h.updateAvailable = updateAvailable
+
+	if !updateAvailable {
+		return
+	}
+
+	h.bus.Publish(events.Event{
+		Time:    time.Now(),
+		Type:    updateAvailableEventType,
+		Key:     updateAvailableEventType,
+		Title:   "Update available",
+		Message: "an adsb.im update is available",
+	})
+}
+
+// feederDown reports whether an enabled feeder has a connection that isn't good, the same as countFeeders counts bad
+func feederDown(info adsb.FeederInfo) bool {
+	if !info.Enabled {
+		return false
+	}
+
+	beastBad := info.BeastStatus != "good" && info.BeastStatus != "unknown"
+	mlatBad := info.MLATStatus != "good" && info.MLATStatus != "disabled"
+
+	return beastBad || mlatBad
+}
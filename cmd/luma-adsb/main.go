@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,12 +12,13 @@ import (
 	"time"
 
 	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/aircraftdb"
+	"github.com/swills/luma-adsb/internal/config"
 	"github.com/swills/luma-adsb/internal/oled"
-	goi2coled "github.com/waxdred/go-i2c-oled"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
 )
 
+var errBadFontEntry = errors.New("font entry should be name=path")
+
 func main() {
 	initError, host, myLatFloat, myLonFloat, myAltFloat := initEnv()
 
@@ -24,9 +26,24 @@ func main() {
 		os.Exit(1)
 	}
 
-	sigChan := make(chan os.Signal, 1)
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("%s\n", err)
 
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGABRT, syscall.SIGBUS)
+		os.Exit(1)
+	}
+
+	svc, err := newServices(cfg)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+
+		os.Exit(1)
+	}
+
+	station := newStation(myLatFloat, myLonFloat, myAltFloat, cfg)
+	ctx := context.Background()
+
+	sigChan, ackChan := notifySignals()
 
 	oledData := oled.InitDisplay()
 
@@ -40,51 +57,57 @@ func main() {
 
 	var cpuTempC int
 
-	ctx := context.Background()
+	displayData := displayDataBuilder(&myADSBData, &feederStatus, &updateStatus, &cpuTempC, station, svc.config,
+		svc.display)
+	snapshot := dashboardSnapshot(displayData, oledData)
+
+	onADSBData := func(newADSBData adsb.Data) {
+		svc.onADSBData(ctx, station, newADSBData)
+		svc.display.pages.Sample(displayData)
+	}
 
 	displayUpdateInterval := 125 * time.Millisecond // faster causes issues
 	aircraftDataInterval := 500 * time.Millisecond
 	feederStatusInterval := 30 * time.Second
 	updateStatusInterval := 5 * time.Minute
 	updateCPUTempInterval := 1 * time.Minute
+	aircraftDBInterval := cfg.AircraftDBReload.Duration
 
 	displayTicker := time.NewTicker(displayUpdateInterval)
 	aircraftDataTicker := time.NewTicker(aircraftDataInterval)
 	feederStatusTicker := time.NewTicker(feederStatusInterval)
 	updateStatusTicker := time.NewTicker(updateStatusInterval)
 	updateCPUTempTicker := time.NewTicker(updateCPUTempInterval)
+	aircraftDBTicker := time.NewTicker(aircraftDBInterval)
 
-	go func() {
-		<-sigChan
-		displayTicker.Stop()
-		aircraftDataTicker.Stop()
-		cleanup(oledData)
-		os.Exit(0)
-	}()
-	go updateFeederStatus(ctx, &feederStatus, host, feederStatusInterval/2)
-	go updateUpdateStatus(ctx, &updateStatus, host, updateStatusInterval/2)
-	go updateCPUTemp(ctx, &cpuTempC, host, updateCPUTempInterval)
+	updateFeeders := func() {
+		updateFeederStatus(ctx, &feederStatus, host, feederStatusInterval/2, svc.onMicroConfig, svc.health.checkFeeders)
+	}
+
+	// this comes before anything fetches so the requests can be timed
+	svc.startServers(ctx, cfg, host, oledData, snapshot)
+
+	go stopOnSignal(sigChan, oledData, displayTicker, aircraftDataTicker)
+	go updateFeeders()
+	go updateUpdateStatus(ctx, &updateStatus, host, updateStatusInterval/2, svc.health.checkUpdate)
+	go updateCPUTemp(ctx, &cpuTempC, host, updateCPUTempInterval, svc.health.checkCPUTemp)
 
 	for {
 		select {
 		case <-aircraftDataTicker.C:
-			go getAndUpdateADSBData(ctx, &myADSBData, host, aircraftDataInterval/2)
+			go getAndUpdateADSBData(ctx, &myADSBData, host, aircraftDataInterval/2, svc.aircraftDB, onADSBData)
 		case <-displayTicker.C:
-			go buildDisplayInfoAndUpdateDisplay(
-				&myADSBData,
-				&feederStatus,
-				&updateStatus,
-				&cpuTempC,
-				myLatFloat,
-				myLonFloat,
-				myAltFloat,
-				oledData)
+			go updateDisplay(displayData, svc.display, oledData)
 		case <-feederStatusTicker.C:
-			go updateFeederStatus(ctx, &feederStatus, host, feederStatusInterval/2)
+			go updateFeeders()
 		case <-updateStatusTicker.C:
-			go updateUpdateStatus(ctx, &updateStatus, host, updateStatusInterval/2)
+			go updateUpdateStatus(ctx, &updateStatus, host, updateStatusInterval/2, svc.health.checkUpdate)
 		case <-updateCPUTempTicker.C:
-			go updateCPUTemp(ctx, &cpuTempC, host, updateCPUTempInterval)
+			go updateCPUTemp(ctx, &cpuTempC, host, updateCPUTempInterval, svc.health.checkCPUTemp)
+		case <-aircraftDBTicker.C:
+			go reloadAircraftDB(svc.aircraftDB)
+		case <-ackChan:
+			fmt.Printf("acknowledged %d alerts\n", svc.display.alerts.Acknowledge(time.Now()))
 		}
 	}
 }
@@ -152,17 +175,104 @@ func initEnv() (bool, string, float64, float64, float64) {
 	return initError, host, myLatFloat, myLonFloat, myAltFloat
 }
 
-func getAndUpdateADSBData(ctx context.Context, data *adsb.Data, host string, timeout time.Duration) {
+// notifySignals returns a channel of the signals that stop luma-adsb and one of SIGUSR1, which acknowledges the alert
+// on the display and any emergencies
+func notifySignals() (chan os.Signal, chan os.Signal) {
+	sigChan := make(chan os.Signal, 1)
+
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGABRT, syscall.SIGBUS)
+
+	ackChan := make(chan os.Signal, 1)
+
+	signal.Notify(ackChan, syscall.SIGUSR1)
+
+	return sigChan, ackChan
+}
+
+// loadConfig loads the fonts and the config file named in the environment
+func loadConfig() (*config.Config, error) {
+	err := loadFonts(os.Getenv("LUMAADSB_FONTS"))
+	if err != nil {
+		return nil, fmt.Errorf("error loading fonts: %w", err)
+	}
+
+	cfg, err := config.Load(os.Getenv("LUMAADSB_CONFIG"))
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// loadFonts registers the fonts listed in fontList, a comma separated list of name=path entries. TTF and OTF paths may
+// be followed by @size to give the pixel size they are rasterised at.
+func loadFonts(fontList string) error {
+	for entry := range strings.SplitSeq(fontList, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, path, found := strings.Cut(entry, "=")
+		if !found {
+			return fmt.Errorf("%w: %q", errBadFontEntry, entry)
+		}
+
+		var size float64
+
+		path, sizeStr, hasSize := strings.Cut(path, "@")
+		if hasSize {
+			var err error
+
+			size, err = strconv.ParseFloat(sizeStr, 64)
+			if err != nil {
+				return fmt.Errorf("error parsing font size in %q: %w", entry, err)
+			}
+		}
+
+		face, err := oled.LoadFont(path, size)
+		if err != nil {
+			return fmt.Errorf("error loading font %q: %w", name, err)
+		}
+
+		oled.RegisterFont(name, face)
+	}
+
+	return nil
+}
+
+// getAndUpdateADSBData fetches the aircraft, fills them in from the aircraft database, storing them in data and passing
+// them to onUpdate
+func getAndUpdateADSBData(ctx context.Context, data *adsb.Data, host string, timeout time.Duration,
+	aircraftDB *aircraftdb.Database, onUpdate func(adsb.Data)) {
 	newADSBData, err := adsb.GetADSBData(ctx, host, timeout)
 	if err != nil {
 		fmt.Printf("error getting adsb data: %s\n", err)
 	} else {
+		aircraftDB.Enrich(newADSBData)
+
 		*data = *newADSBData
+
+		onUpdate(*newADSBData)
+	}
+}
+
+// reloadAircraftDB loads the aircraft database again if the file has changed
+func reloadAircraftDB(aircraftDB *aircraftdb.Database) {
+	reloaded, err := aircraftDB.ReloadIfChanged()
+	if err != nil {
+		fmt.Printf("error reloading aircraft database: %s\n", err)
+
+		return
+	}
+
+	if reloaded {
+		fmt.Printf("reloaded aircraft database, %d aircraft\n", aircraftDB.Len())
 	}
 }
 
 func updateFeederStatus(ctx context.Context, feederStatus *map[string]adsb.FeederInfo, host string,
-	timeout time.Duration) {
+	timeout time.Duration, onConfig func(*adsb.MicroConfig), onUpdate func(map[string]adsb.FeederInfo)) {
 	config, err := adsb.GetMicroConfig(ctx, host, timeout)
 	if err != nil {
 		fmt.Printf("error getting micro config: %s\n", err)
@@ -170,138 +280,58 @@ func updateFeederStatus(ctx context.Context, feederStatus *map[string]adsb.Feede
 		return
 	}
 
+	onConfig(config)
+
 	newFeederStatus, err := adsb.GetAllFeederStatus(ctx, host, timeout, config)
 	if err != nil {
 		fmt.Printf("error getting feeder status info: %s\n", err)
 	} else {
 		*feederStatus = *newFeederStatus
+
+		onUpdate(*newFeederStatus)
 	}
 }
 
-func updateUpdateStatus(ctx context.Context, updateStatus *bool, host string, timeout time.Duration) {
+func updateUpdateStatus(ctx context.Context, updateStatus *bool, host string, timeout time.Duration,
+	onUpdate func(bool)) {
 	updateAvailable, err := adsb.GetUpdateAvailable(ctx, host, timeout)
 	if err != nil {
 		fmt.Printf("error getting update status: %s\n", err)
 	} else {
 		*updateStatus = updateAvailable
+
+		onUpdate(updateAvailable)
 	}
 }
 
-func updateCPUTemp(ctx context.Context, cpuTempC *int, host string, timeout time.Duration) {
+func updateCPUTemp(ctx context.Context, cpuTempC *int, host string, timeout time.Duration, onUpdate func(int)) {
 	newCPUTempC, err := adsb.GetCPUTempC(ctx, host, timeout)
 	if err != nil {
 		fmt.Printf("error getting CPU Temp: %s\n", err)
 	} else {
 		*cpuTempC = newCPUTempC
-	}
-}
 
-var messagePrinter = message.NewPrinter(language.English)
-
-func buildDisplayInfoAndUpdateDisplay(
-	myADSBData *adsb.Data,
-	feederStatus *map[string]adsb.FeederInfo,
-	updateStatus *bool,
-	cpuTemp *int,
-	myLatFloat float64,
-	myLonFloat float64,
-	myAltFloat float64,
-	oledData *goi2coled.I2c,
-) {
-	var totalPlanes int
-
-	var numPlanesWithPos int
-
-	totalPlanes = len(myADSBData.Planes)
-	for _, v := range myADSBData.Planes {
-		if v.Latitude != 0 || v.Longitude != 0 || v.Last.Latitude != 0 || v.Last.Longitude != 0 {
-			numPlanesWithPos++
-		}
+		onUpdate(newCPUTempC)
 	}
-
-	planesWithoutPos := totalPlanes - numPlanesWithPos
-
-	var updateString string
-
-	if *updateStatus {
-		updateString = "U"
-	} else {
-		updateString = " "
-	}
-
-	dispLines := []string{
-		fmt.Sprintf("%s%s    %2d %2d", time.Now().Format("15:04:05"), updateString, numPlanesWithPos, planesWithoutPos),
-	}
-
-	if len(myADSBData.Planes) > 0 {
-		dispLines = addClosest(myADSBData, feederStatus, cpuTemp, myLatFloat, myLonFloat, myAltFloat,
-			dispLines)
-	}
-
-	oled.UpdateDisplayLines(dispLines, oledData)
 }
 
-func addClosest(
-	myADSBData *adsb.Data,
-	feederStatus *map[string]adsb.FeederInfo,
-	cpuTemp *int,
-	myLatFloat float64,
-	myLonFloat float64,
-	myAltFloat float64,
-	dispLines []string,
-) []string {
-	closestPlane, dist := adsb.FindClosest(*myADSBData, myLatFloat, myLonFloat, myAltFloat)
-
-	closest := strings.TrimSpace(closestPlane.CallSign)
-	//nolint:nestif
-	if closestPlane.Hex != "" {
-		if closest == "" {
-			closest = "none"
-		}
-
-		dispLines = append(dispLines, messagePrinter.Sprintf("%s (%s)", closest, closestPlane.Hex))
-
-		if closestPlane.Category != "" {
-			dispLines = append(dispLines, messagePrinter.Sprintf("%4.1fmi (%s)    %dC", dist, closestPlane.Category, *cpuTemp))
-		} else {
-			dispLines = append(dispLines, messagePrinter.Sprintf("%4.1fmi         %dC", dist, *cpuTemp))
-		}
-
-		var goodCount int
-
-		var badCount int
-
-		if _, ok := closestPlane.Altitude.(float64); ok {
-			for _, value := range *feederStatus {
-				if value.Enabled {
-					if value.BeastStatus == "good" {
-						goodCount++
-					} else if value.BeastStatus != "unknown" {
-						badCount++
-					}
-
-					if value.MLATStatus == "good" {
-						goodCount++
-					} else if value.MLATStatus != "disabled" {
-						badCount++
-					}
-				}
-			}
+// stopOnSignal clears the display and exits once a signal arrives on sigChan, stopping tickers first so nothing draws
+// over the cleared display
+func stopOnSignal(sigChan chan os.Signal, oledData *oled.Display, tickers ...*time.Ticker) {
+	<-sigChan
 
-			dispLines = append(dispLines, messagePrinter.Sprintf("%6.0fft     %2d %2d",
-				closestPlane.Altitude, goodCount, badCount))
-		}
+	for _, ticker := range tickers {
+		ticker.Stop()
 	}
 
-	return dispLines
+	cleanup(oledData)
+	os.Exit(0)
 }
 
-func cleanup(oledData *goi2coled.I2c) {
+func cleanup(oledData *oled.Display) {
 	fmt.Printf("Clearing screen\n")
 	oled.ClearDisplay(oledData)
 	time.Sleep(time.Millisecond * 500) // wait for other go routines to finish
 
-	_, _ = oledData.DisplayOff()
-
-	_ = oledData.Close()
+	oledData.Close()
 }
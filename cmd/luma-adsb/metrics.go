@@ -0,0 +1,209 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/metrics"
+	"github.com/swills/luma-adsb/internal/oled"
+	"github.com/swills/luma-adsb/internal/web"
+)
+
+// requestBuckets and frameBuckets are the histogram bucket bounds in seconds for requests and display frames
+var (
+	requestBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	frameBuckets   = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5}
+)
+
+// newMetrics creates the metrics served on /metrics, timing the requests made with clients and the frames drawn on the
+// display
+func newMetrics(oledData *oled.Display, snapshot func() web.Snapshot, clients ...*http.Client) *metrics.Registry {
+	requestDuration := metrics.NewHistogramVec("luma_adsb_request_duration_seconds",
+		"How long requests to adsb.im and the route API took, by path", "path", requestBuckets)
+	requestErrors := metrics.NewCounterVec("luma_adsb_request_errors_total",
+		"Requests to adsb.im and the route API that failed or got an error status, by path", "path")
+
+	for _, client := range clients {
+		client.Transport = &instrumentedTransport{
+			next:     transportOrDefault(client.Transport),
+			duration: requestDuration,
+			errors:   requestErrors,
+		}
+	}
+
+	frameDuration := metrics.NewHistogramVec("luma_adsb_display_frame_seconds",
+		"How long frames took to draw and send to the display", "", frameBuckets)
+
+	oledData.ObserveFrames(func(took time.Duration) {
+		frameDuration.Observe("", took.Seconds())
+	})
+
+	registry := &metrics.Registry{}
+
+	registry.Register(func() []metrics.Family {
+		return []metrics.Family{requestDuration.Collect(), requestErrors.Collect(), frameDuration.Collect()}
+	})
+	registry.Register(func() []metrics.Family {
+		return snapshotMetrics(snapshot())
+	})
+
+	return registry
+}
+
+// transportOrDefault is transport, or the default transport if it's nil
+func transportOrDefault(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		return http.DefaultTransport
+	}
+
+	return transport
+}
+
+// instrumentedTransport times each request and counts those that fail
+type instrumentedTransport struct {
+	next     http.RoundTripper
+	duration *metrics.HistogramVec
+	errors   *metrics.CounterVec
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	res, err := t.next.RoundTrip(req)
+
+	t.duration.Observe(req.URL.Path, time.Since(start).Seconds())
+
+	if err != nil {
+		t.errors.Inc(req.URL.Path)
+
+		// the client wraps transport errors with the request itself
+		return nil, err //nolint:wrapcheck
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		t.errors.Inc(req.URL.Path)
+	}
+
+	return res, nil
+}
+
+// snapshotMetrics are the gauges worked out from what the display shows and the display's health
+func snapshotMetrics(snapshot web.Snapshot) []metrics.Family {
+	data := snapshot.Data
+
+	families := []metrics.Family{
+		gauge("luma_adsb_aircraft", "Aircraft passing the configured filter", float64(data.Total)),
+		gauge("luma_adsb_aircraft_with_position", "Aircraft with a recent position", float64(data.WithPosition)),
+		gauge("luma_adsb_aircraft_without_position", "Aircraft without a recent position",
+			float64(data.WithoutPosition)),
+		gauge("luma_adsb_cpu_temperature_celsius", "CPU temperature", float64(data.CPUTempC)),
+		gauge("luma_adsb_update_available", "1 if an adsb.im update is available",
+			metrics.Bool(data.UpdateAvailable)),
+		gauge("luma_adsb_feeder_connections_good", "Beast and MLAT connections of enabled feeders that are good",
+			float64(data.FeedersGood)),
+		gauge("luma_adsb_feeder_connections_bad", "Beast and MLAT connections of enabled feeders that are down",
+			float64(data.FeedersBad)),
+	}
+
+	closest := metrics.Family{
+		Name: "luma_adsb_closest_distance_miles",
+		Help: "Slant range of the closest aircraft, missing if there isn't one",
+		Type: metrics.Gauge,
+	}
+
+	if data.Closest != nil {
+		closest.Samples = []metrics.Sample{{Value: data.Closest.SlantRange}}
+	}
+
+	families = append(families, closest)
+	families = append(families, feederMetrics(snapshot)...)
+
+	return append(families, displayMetrics(snapshot.Display)...)
+}
+
+// feederMetrics give each feeder's connections as 1 if good and 0 if not, along with what their status is
+func feederMetrics(snapshot web.Snapshot) []metrics.Family {
+	enabled := metrics.Family{
+		Name: "luma_adsb_feeder_enabled",
+		Help: "1 if the feeder is enabled",
+		Type: metrics.Gauge,
+	}
+	good := metrics.Family{
+		Name: "luma_adsb_feeder_connection_good",
+		Help: "1 if the feeder's beast or MLAT connection is good",
+		Type: metrics.Gauge,
+	}
+	status := metrics.Family{
+		Name: "luma_adsb_feeder_connection_status",
+		Help: "The status of the feeder's beast or MLAT connection, always 1",
+		Type: metrics.Gauge,
+	}
+
+	feeders := snapshot.Data.Feeders
+
+	for _, name := range slices.Sorted(maps.Keys(feeders)) {
+		info := feeders[name]
+
+		enabled.Samples = append(enabled.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "feeder", Value: name}},
+			Value:  metrics.Bool(info.Enabled),
+		})
+
+		connections := []struct{ name, status string }{{"beast", info.BeastStatus}, {"mlat", info.MLATStatus}}
+
+		for _, connection := range connections {
+			if connection.status == "" {
+				continue
+			}
+
+			good.Samples = append(good.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "feeder", Value: name}, {Name: "connection", Value: connection.name}},
+				Value:  metrics.Bool(connection.status == "good"),
+			})
+			status.Samples = append(status.Samples, metrics.Sample{
+				Labels: []metrics.Label{
+					{Name: "feeder", Value: name},
+					{Name: "connection", Value: connection.name},
+					{Name: "status", Value: connection.status},
+				},
+				Value: 1,
+			})
+		}
+	}
+
+	return []metrics.Family{enabled, good, status}
+}
+
+// displayMetrics are the display's I2C health
+func displayMetrics(health oled.Health) []metrics.Family {
+	state := metrics.Family{
+		Name: "luma_adsb_display_state",
+		Help: "1 for the display's current state",
+		Type: metrics.Gauge,
+	}
+
+	for _, healthState := range []oled.HealthState{
+		oled.HealthOK, oled.HealthDegraded, oled.HealthRecovering, oled.HealthHeadless,
+	} {
+		state.Samples = append(state.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "state", Value: string(healthState)}},
+			Value:  metrics.Bool(health.State == healthState),
+		})
+	}
+
+	return []metrics.Family{
+		state,
+		counter("luma_adsb_display_i2c_errors_total", "Failed writes to the display", float64(health.TotalErrors)),
+		counter("luma_adsb_display_reinits_total", "Times the display was re-initialised", float64(health.Reinits)),
+	}
+}
+
+func gauge(name, help string, value float64) metrics.Family {
+	return metrics.Family{Name: name, Help: help, Type: metrics.Gauge, Samples: []metrics.Sample{{Value: value}}}
+}
+
+func counter(name, help string, value float64) metrics.Family {
+	return metrics.Family{Name: name, Help: help, Type: metrics.Counter, Samples: []metrics.Sample{{Value: value}}}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/mqtt"
+	"github.com/swills/luma-adsb/internal/web"
+)
+
+const (
+	mqttOnline  = "online"
+	mqttOffline = "offline"
+
+	mqttInitialBackoff = 5 * time.Second
+	mqttMaxBackoff     = 5 * time.Minute
+)
+
+// unsafeIDChars are the characters Home Assistant doesn't allow in discovery node and object IDs
+var unsafeIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// mqttPublisher publishes the state to an MQTT broker along with Home Assistant discovery configs for it. Messages are
+// retained so Home Assistant gets the state as soon as it subscribes.
+type mqttPublisher struct {
+	cfg      config.MQTTConfig
+	clientID string
+	topic    string
+	snapshot func() web.Snapshot
+	// commands runs the commands published to the command topic, nil to not take commands
+	commands func(line string) (string, error)
+
+	// published is the payload last published to each topic on this connection, only changes are published again
+	published map[string]string
+	// feeders are the feeders with discovery configs published, kept across connections so the configs of feeders
+	// that go away while disconnected are still removed
+	feeders []string
+}
+
+// messagePublisher is the part of the MQTT client the state is published with
+type messagePublisher interface {
+	Publish(topic string, payload []byte, retain bool) error
+}
+
+// mqttState is published to the state topic
+type mqttState struct {
+	Aircraft        int  `json:"aircraft"`
+	WithPosition    int  `json:"with_position"`
+	WithoutPosition int  `json:"without_position"`
+	FeedersGood     int  `json:"feeders_good"`
+	FeedersBad      int  `json:"feeders_bad"`
+	CPUTempC        int  `json:"cpu_temp_c"`
+	UpdateAvailable bool `json:"update_available"`
+}
+
+// mqttFeeder is one feeder in the feeders topic
+type mqttFeeder struct {
+	Enabled bool   `json:"enabled"`
+	Beast   string `json:"beast"`
+	MLAT    string `json:"mlat"`
+	Good    bool   `json:"good"`
+}
+
+// haEntity is a Home Assistant MQTT discovery config
+type haEntity struct {
+	Name                   string   `json:"name"`
+	UniqueID               string   `json:"unique_id"`
+	StateTopic             string   `json:"state_topic,omitempty"`
+	ValueTemplate          string   `json:"value_template,omitempty"`
+	JSONAttributesTopic    string   `json:"json_attributes_topic,omitempty"`
+	JSONAttributesTemplate string   `json:"json_attributes_template,omitempty"`
+	CommandTopic           string   `json:"command_topic,omitempty"`
+	PayloadPress           string   `json:"payload_press,omitempty"`
+	AvailabilityTopic      string   `json:"availability_topic"`
+	DeviceClass            string   `json:"device_class,omitempty"`
+	StateClass             string   `json:"state_class,omitempty"`
+	UnitOfMeasurement      string   `json:"unit_of_measurement,omitempty"`
+	Icon                   string   `json:"icon,omitempty"`
+	EntityCategory         string   `json:"entity_category,omitempty"`
+	Device                 haDevice `json:"device"`
+}
+
+type haDevice struct {
+	Identifiers []string `json:"identifiers"`
+	Name        string   `json:"name"`
+	Model       string   `json:"model"`
+}
+
+// newMQTTPublisher creates a publisher for the state given by snapshot. The client ID is made from host unless the
+// config sets one.
+func newMQTTPublisher(cfg config.MQTTConfig, host string, snapshot func() web.Snapshot) *mqttPublisher {
+	clientID := cmp.Or(cfg.ClientID, "luma-adsb-"+host)
+
+	return &mqttPublisher{
+		cfg:      cfg,
+		clientID: clientID,
+		topic:    cmp.Or(cfg.Topic, "luma-adsb/"+clientID),
+		snapshot: snapshot,
+	}
+}
+
+// run publishes until ctx is done, connecting again with a backoff whenever the connection is lost
+func (p *mqttPublisher) run(ctx context.Context) {
+	backoff := mqttInitialBackoff
+
+	for {
+		connected, err := p.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		fmt.Printf("error publishing to MQTT broker %s: %s\n", p.cfg.Broker, err)
+
+		if connected {
+			backoff = mqttInitialBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, mqttMaxBackoff)
+	}
+}
+
+// session connects and publishes every interval until the connection is lost or ctx is done, reporting whether it
+// connected at all
+func (p *mqttPublisher) session(ctx context.Context) (bool, error) {
+	opts := mqtt.Options{
+		Address:  p.cfg.Broker,
+		ClientID: p.clientID,
+		Username: p.cfg.Username,
+		Password: p.cfg.Password,
+		Will: &mqtt.Will{
+			Topic:   p.availabilityTopic(),
+			Payload: []byte(mqttOffline),
+			Retain:  true,
+		},
+	}
+
+	if p.cfg.TLS {
+		opts.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if p.commands != nil {
+		opts.OnMessage = p.onMessage
+	}
+
+	client, err := mqtt.Dial(ctx, opts)
+	if err != nil {
+		return false, fmt.Errorf("error connecting: %w", err)
+	}
+
+	if p.commands != nil {
+		err = client.Subscribe(p.commandTopic())
+		if err != nil {
+			_ = client.Close()
+
+			return true, fmt.Errorf("error subscribing: %w", err)
+		}
+	}
+
+	p.published = make(map[string]string)
+
+	ticker := time.NewTicker(p.cfg.Interval.Duration)
+	defer ticker.Stop()
+
+	err = p.publish(client, p.availabilityTopic(), mqttOnline)
+
+	for err == nil {
+		err = p.publishState(client)
+		if err != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = client.Publish(p.availabilityTopic(), []byte(mqttOffline), true)
+			_ = client.Close()
+
+			return true, fmt.Errorf("error publishing: %w", ctx.Err())
+		case <-client.Done():
+			err = client.Err()
+		case <-ticker.C:
+		}
+	}
+
+	_ = client.Close()
+
+	return true, err
+}
+
+func (p *mqttPublisher) availabilityTopic() string {
+	return p.topic + "/availability"
+}
+
+func (p *mqttPublisher) commandTopic() string {
+	return p.topic + "/command"
+}
+
+// onMessage runs a command published to the command topic, which logs what it did
+func (p *mqttPublisher) onMessage(_ string, payload []byte) {
+	_, _ = p.commands(string(payload))
+}
+
+// publishState publishes whatever has changed since it was last published, along with discovery configs for any
+// feeders that have appeared
+func (p *mqttPublisher) publishState(client messagePublisher) error {
+	data := p.snapshot().Data
+
+	err := p.publishDiscovery(client, slices.Sorted(maps.Keys(data.Feeders)))
+	if err != nil {
+		return err
+	}
+
+	err = p.publishJSON(client, p.topic+"/state", mqttState{
+		Aircraft:        data.Total,
+		WithPosition:    data.WithPosition,
+		WithoutPosition: data.WithoutPosition,
+		FeedersGood:     data.FeedersGood,
+		FeedersBad:      data.FeedersBad,
+		CPUTempC:        data.CPUTempC,
+		UpdateAvailable: data.UpdateAvailable,
+	})
+	if err != nil {
+		return err
+	}
+
+	var closest any = struct{}{}
+
+	if summary := web.NewSummary(data); summary.Closest != nil {
+		closest = summary.Closest
+	}
+
+	err = p.publishJSON(client, p.topic+"/closest", closest)
+	if err != nil {
+		return err
+	}
+
+	feeders := make(map[string]mqttFeeder, len(data.Feeders))
+
+	for name, info := range data.Feeders {
+		feeders[name] = mqttFeeder{
+			Enabled: info.Enabled,
+			Beast:   info.BeastStatus,
+			MLAT:    info.MLATStatus,
+			Good:    !feederDown(info),
+		}
+	}
+
+	return p.publishJSON(client, p.topic+"/feeders", feeders)
+}
+
+// publishDiscovery publishes the discovery configs, including one for each feeder, and removes the configs of feeders
+// that have gone
+func (p *mqttPublisher) publishDiscovery(client messagePublisher, feeders []string) error {
+	entities := p.entities()
+
+	for _, feeder := range feeders {
+		entities["binary_sensor/feeder_"+feeder] = p.entity("Feeder "+feeder, "feeder_"+feeder, haEntity{
+			StateTopic:             p.topic + "/feeders",
+			ValueTemplate:          fmt.Sprintf("{{ 'ON' if value_json[%q].good else 'OFF' }}", feeder),
+			JSONAttributesTopic:    p.topic + "/feeders",
+			JSONAttributesTemplate: fmt.Sprintf("{{ value_json[%q] | tojson }}", feeder),
+			DeviceClass:            "connectivity",
+			EntityCategory:         "diagnostic",
+		})
+	}
+
+	if p.commands != nil {
+		maps.Copy(entities, p.buttons())
+	}
+
+	for _, feeder := range p.feeders {
+		if !slices.Contains(feeders, feeder) {
+			// an empty config removes the entity
+			err := p.publish(client, p.discoveryTopic("binary_sensor/feeder_"+feeder), "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(entities)) {
+		err := p.publishJSON(client, p.discoveryTopic(key), entities[key])
+		if err != nil {
+			return err
+		}
+	}
+
+	p.feeders = feeders
+
+	return nil
+}
+
+// entities are the discovery configs that don't depend on the feeders, keyed by component and object ID
+func (p *mqttPublisher) entities() map[string]haEntity {
+	state := p.topic + "/state"
+	closest := p.topic + "/closest"
+
+	return map[string]haEntity{
+		"sensor/aircraft": p.entity("Aircraft", "aircraft", haEntity{
+			StateTopic: state, ValueTemplate: "{{ value_json.aircraft }}", StateClass: "measurement",
+			Icon: "mdi:airplane",
+		}),
+		"sensor/aircraft_with_position": p.entity("Aircraft with position", "aircraft_with_position", haEntity{
+			StateTopic: state, ValueTemplate: "{{ value_json.with_position }}", StateClass: "measurement",
+			Icon: "mdi:airplane-marker",
+		}),
+		"sensor/aircraft_without_position": p.entity("Aircraft without position", "aircraft_without_position", haEntity{
+			StateTopic: state, ValueTemplate: "{{ value_json.without_position }}", StateClass: "measurement",
+			Icon: "mdi:airplane-off",
+		}),
+		"sensor/closest": p.entity("Closest aircraft", "closest", haEntity{
+			StateTopic: closest, ValueTemplate: "{{ value_json.name | default(None) }}",
+			JSONAttributesTopic: closest, Icon: "mdi:airplane-search",
+		}),
+		"sensor/closest_distance": p.entity("Closest aircraft distance", "closest_distance", haEntity{
+			StateTopic: closest, ValueTemplate: "{{ value_json.slant_range | default(None) }}",
+			DeviceClass: "distance", StateClass: "measurement", UnitOfMeasurement: "mi",
+		}),
+		"sensor/cpu_temperature": p.entity("CPU temperature", "cpu_temperature", haEntity{
+			StateTopic: state, ValueTemplate: "{{ value_json.cpu_temp_c }}", DeviceClass: "temperature",
+			StateClass: "measurement", UnitOfMeasurement: "°C", EntityCategory: "diagnostic",
+		}),
+		"sensor/feeders_good": p.entity("Feeder connections good", "feeders_good", haEntity{
+			StateTopic: state, ValueTemplate: "{{ value_json.feeders_good }}", StateClass: "measurement",
+			Icon: "mdi:lan-connect", EntityCategory: "diagnostic",
+		}),
+		"sensor/feeders_bad": p.entity("Feeder connections bad", "feeders_bad", haEntity{
+			StateTopic: state, ValueTemplate: "{{ value_json.feeders_bad }}", StateClass: "measurement",
+			Icon: "mdi:lan-disconnect", EntityCategory: "diagnostic",
+		}),
+		"binary_sensor/update_available": p.entity("Update available", "update_available", haEntity{
+			StateTopic: state, ValueTemplate: "{{ 'ON' if value_json.update_available else 'OFF' }}",
+			DeviceClass: "update", EntityCategory: "diagnostic",
+		}),
+	}
+}
+
+// buttons are the discovery configs of buttons sending commands
+func (p *mqttPublisher) buttons() map[string]haEntity {
+	command := p.commandTopic()
+
+	return map[string]haEntity{
+		"button/next_page": p.entity("Next page", "next_page", haEntity{
+			CommandTopic: command, PayloadPress: "next", Icon: "mdi:page-next",
+		}),
+		"button/previous_page": p.entity("Previous page", "previous_page", haEntity{
+			CommandTopic: command, PayloadPress: "previous", Icon: "mdi:page-previous",
+		}),
+		"button/acknowledge": p.entity("Acknowledge alerts", "acknowledge", haEntity{
+			CommandTopic: command, PayloadPress: "ack", Icon: "mdi:bell-check",
+		}),
+		"button/reload": p.entity("Reload config", "reload", haEntity{
+			CommandTopic: command, PayloadPress: "reload", EntityCategory: "config",
+		}),
+	}
+}
+
+// entity fills in the name, IDs, availability and device of a discovery config
+func (p *mqttPublisher) entity(name, objectID string, entity haEntity) haEntity {
+	entity.Name = name
+	entity.UniqueID = p.nodeID() + "_" + unsafeIDChars.ReplaceAllString(objectID, "_")
+	entity.AvailabilityTopic = p.availabilityTopic()
+	entity.Device = haDevice{
+		Identifiers: []string{p.nodeID()},
+		Name:        p.clientID,
+		Model:       "luma-adsb",
+	}
+
+	return entity
+}
+
+func (p *mqttPublisher) nodeID() string {
+	return unsafeIDChars.ReplaceAllString(p.clientID, "_")
+}
+
+// discoveryTopic is where the config for key, a component and object ID, is published
+func (p *mqttPublisher) discoveryTopic(key string) string {
+	component, objectID, _ := strings.Cut(key, "/")
+
+	return p.cfg.DiscoveryPrefix + "/" + component + "/" + p.nodeID() + "/" +
+		unsafeIDChars.ReplaceAllString(objectID, "_") + "/config"
+}
+
+func (p *mqttPublisher) publishJSON(client messagePublisher, topic string, value any) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", topic, err)
+	}
+
+	return p.publish(client, topic, string(payload))
+}
+
+// publish publishes a retained message if it's different from the last one published to topic
+func (p *mqttPublisher) publish(client messagePublisher, topic string, payload string) error {
+	if last, ok := p.published[topic]; ok && last == payload {
+		return nil
+	}
+
+	err := client.Publish(topic, []byte(payload), true)
+	if err != nil {
+		return fmt.Errorf("error publishing %s: %w", topic, err)
+	}
+
+	p.published[topic] = payload
+
+	return nil
+}
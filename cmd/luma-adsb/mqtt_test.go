@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/swills/luma-adsb/internal/config"
+)
+
+var errNotRetained = errors.New("message isn't retained")
+
+// recorder records the messages published to it
+type recorder struct {
+	messages map[string]string
+	topics   []string
+}
+
+func (r *recorder) Publish(topic string, payload []byte, retain bool) error {
+	if !retain {
+		return errNotRetained
+	}
+
+	r.messages[topic] = string(payload)
+	r.topics = append(r.topics, topic)
+
+	return nil
+}
+
+func newRecorder() *recorder {
+	return &recorder{messages: make(map[string]string)}
+}
+
+func newTestPublisher(commands bool) *mqttPublisher {
+	publisher := newMQTTPublisher(config.MQTTConfig{DiscoveryPrefix: "homeassistant"}, "pi.local", nil)
+	publisher.published = make(map[string]string)
+
+	if commands {
+		publisher.commands = func(string) (string, error) {
+			return "", nil
+		}
+	}
+
+	return publisher
+}
+
+func TestPublishDiscoveryTopics(t *testing.T) {
+	t.Parallel()
+
+	sensors := []string{
+		"homeassistant/binary_sensor/luma-adsb-pi_local/update_available/config",
+		"homeassistant/sensor/luma-adsb-pi_local/aircraft/config",
+		"homeassistant/sensor/luma-adsb-pi_local/aircraft_with_position/config",
+		"homeassistant/sensor/luma-adsb-pi_local/aircraft_without_position/config",
+		"homeassistant/sensor/luma-adsb-pi_local/closest/config",
+		"homeassistant/sensor/luma-adsb-pi_local/closest_distance/config",
+		"homeassistant/sensor/luma-adsb-pi_local/cpu_temperature/config",
+		"homeassistant/sensor/luma-adsb-pi_local/feeders_bad/config",
+		"homeassistant/sensor/luma-adsb-pi_local/feeders_good/config",
+	}
+	buttons := []string{
+		"homeassistant/button/luma-adsb-pi_local/acknowledge/config",
+		"homeassistant/button/luma-adsb-pi_local/next_page/config",
+		"homeassistant/button/luma-adsb-pi_local/previous_page/config",
+		"homeassistant/button/luma-adsb-pi_local/reload/config",
+	}
+
+	tests := []struct {
+		name     string
+		commands bool
+		feeders  []string
+		want     []string
+	}{
+		{
+			name: "sensors",
+			want: sensors,
+		},
+		{
+			name:     "buttons",
+			commands: true,
+			want:     slices.Concat(sensors, buttons),
+		},
+		{
+			name:    "feeders",
+			feeders: []string{"adsb.fi", "fr24"},
+			want: slices.Concat(sensors, []string{
+				"homeassistant/binary_sensor/luma-adsb-pi_local/feeder_adsb_fi/config",
+				"homeassistant/binary_sensor/luma-adsb-pi_local/feeder_fr24/config",
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newRecorder()
+
+			err := newTestPublisher(tt.commands).publishDiscovery(client, tt.feeders)
+			if err != nil {
+				t.Fatalf("error publishing discovery: %s", err)
+			}
+
+			got := slices.Sorted(maps.Keys(client.messages))
+			want := slices.Sorted(slices.Values(tt.want))
+
+			if !slices.Equal(got, want) {
+				t.Errorf("got topics %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPublishDiscoveryPayloads(t *testing.T) {
+	t.Parallel()
+
+	client := newRecorder()
+
+	err := newTestPublisher(true).publishDiscovery(client, []string{"fr24"})
+	if err != nil {
+		t.Fatalf("error publishing discovery: %s", err)
+	}
+
+	tests := []struct {
+		topic string
+		want  haEntity
+	}{
+		{
+			topic: "homeassistant/sensor/luma-adsb-pi_local/aircraft/config",
+			want: haEntity{
+				Name: "Aircraft", UniqueID: "luma-adsb-pi_local_aircraft",
+				StateTopic: "luma-adsb/luma-adsb-pi.local/state", ValueTemplate: "{{ value_json.aircraft }}",
+				StateClass: "measurement", Icon: "mdi:airplane",
+			},
+		},
+		{
+			topic: "homeassistant/binary_sensor/luma-adsb-pi_local/feeder_fr24/config",
+			want: haEntity{
+				Name: "Feeder fr24", UniqueID: "luma-adsb-pi_local_feeder_fr24",
+				StateTopic:             "luma-adsb/luma-adsb-pi.local/feeders",
+				ValueTemplate:          `{{ 'ON' if value_json["fr24"].good else 'OFF' }}`,
+				JSONAttributesTopic:    "luma-adsb/luma-adsb-pi.local/feeders",
+				JSONAttributesTemplate: `{{ value_json["fr24"] | tojson }}`,
+				DeviceClass:            "connectivity", EntityCategory: "diagnostic",
+			},
+		},
+		{
+			topic: "homeassistant/button/luma-adsb-pi_local/next_page/config",
+			want: haEntity{
+				Name: "Next page", UniqueID: "luma-adsb-pi_local_next_page",
+				CommandTopic: "luma-adsb/luma-adsb-pi.local/command", PayloadPress: "next", Icon: "mdi:page-next",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			t.Parallel()
+
+			var got haEntity
+
+			err := json.Unmarshal([]byte(client.messages[tt.topic]), &got)
+			if err != nil {
+				t.Fatalf("error decoding %s: %s", tt.topic, err)
+			}
+
+			tt.want.AvailabilityTopic = "luma-adsb/luma-adsb-pi.local/availability"
+			tt.want.Device = haDevice{
+				Identifiers: []string{"luma-adsb-pi_local"}, Name: "luma-adsb-pi.local", Model: "luma-adsb",
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %s, want %+v", client.messages[tt.topic], tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishDiscoveryRemovesFeeders(t *testing.T) {
+	t.Parallel()
+
+	publisher := newTestPublisher(false)
+
+	err := publisher.publishDiscovery(newRecorder(), []string{"fr24", "piaware"})
+	if err != nil {
+		t.Fatalf("error publishing discovery: %s", err)
+	}
+
+	// removing a feeder is remembered across connections
+	publisher.published = make(map[string]string)
+	client := newRecorder()
+
+	err = publisher.publishDiscovery(client, []string{"piaware"})
+	if err != nil {
+		t.Fatalf("error publishing discovery: %s", err)
+	}
+
+	removed := "homeassistant/binary_sensor/luma-adsb-pi_local/feeder_fr24/config"
+
+	payload, ok := client.messages[removed]
+	if !ok || payload != "" {
+		t.Errorf("got %q published to %s, want an empty config", payload, removed)
+	}
+
+	if client.topics[0] != removed {
+		t.Errorf("got %s published first, want %s", client.topics[0], removed)
+	}
+
+	// nothing that hasn't changed is published again
+	client = newRecorder()
+
+	err = publisher.publishDiscovery(client, []string{"piaware"})
+	if err != nil {
+		t.Fatalf("error publishing discovery: %s", err)
+	}
+
+	if len(client.topics) != 0 {
+		t.Errorf("got %q published again, want nothing", client.topics)
+	}
+}
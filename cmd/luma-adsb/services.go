@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/aircraftdb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/events"
+	"github.com/swills/luma-adsb/internal/geofence"
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/notify"
+	"github.com/swills/luma-adsb/internal/routes"
+	"github.com/swills/luma-adsb/internal/watchlist"
+)
+
+// services are the parts of luma-adsb set up from the config
+type services struct {
+	display    *screens
+	zones      *geofence.Monitor
+	aircraftDB *aircraftdb.Database
+	bus        *events.Bus
+	health     *healthMonitor
+	notifier   *notify.Notifier
+	// priorities are the alert priority of each event type
+	priorities map[string]layout.Priority
+
+	// routeAPI is whether routes are looked up, which is turned on in adsb.im
+	routeAPI atomic.Bool
+	// cfg is the config, which is replaced rather than changed when it's reloaded
+	cfg atomic.Pointer[config.Config]
+}
+
+// newServices loads the pages, zones, watchlist, aircraft database and airlines named in the config
+func newServices(cfg *config.Config) (*services, error) {
+	pages, err := layout.NewPages(cfg.Pages, pageTypes)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pages: %w", err)
+	}
+
+	zoneMonitor, err := newZoneMonitor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	watched, err := watchlist.Load(cfg.Watchlist)
+	if err != nil {
+		return nil, fmt.Errorf("error loading watchlist: %w", err)
+	}
+
+	aircraftDB, err := aircraftdb.Open(cfg.AircraftDB)
+	if err != nil {
+		return nil, fmt.Errorf("error loading aircraft database: %w", err)
+	}
+
+	if cfg.AircraftDB != "" {
+		fmt.Printf("loaded %d aircraft from %s\n", aircraftDB.Len(), cfg.AircraftDB)
+	}
+
+	airlines, err := adsb.LoadAirlines(cfg.Airlines)
+	if err != nil {
+		return nil, fmt.Errorf("error loading airlines: %w", err)
+	}
+
+	priorities, err := alertPriorities(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error in alerts config: %w", err)
+	}
+
+	display := &screens{
+		pages:       pages,
+		alerts:      newAlerts(cfg),
+		emergencies: &layout.Emergencies{},
+		watchlist:   watched,
+		airlines:    airlines,
+		routes:      routes.NewClient(cfg.RouteAPIURL),
+	}
+	bus := newEventBus(display.alerts, priorities)
+
+	notifier, err := notify.New(cfg.Notify, priorities, notifyEventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("error in notify config: %w", err)
+	}
+
+	bus.Subscribe(notifier.Notify)
+
+	svc := &services{
+		display:    display,
+		zones:      zoneMonitor,
+		aircraftDB: aircraftDB,
+		bus:        bus,
+		health:     &healthMonitor{bus: bus},
+		notifier:   notifier,
+		priorities: priorities,
+	}
+
+	svc.cfg.Store(cfg)
+
+	return svc, nil
+}
+
+// config is the config as last loaded
+func (s *services) config() *config.Config {
+	return s.cfg.Load()
+}
+
+// reloadConfig loads the config file again, switching to its pages, watchlist and the settings used in working out
+// each frame. Everything else is only set up when luma-adsb starts.
+func (s *services) reloadConfig() error {
+	cfg, err := config.Load(os.Getenv("LUMAADSB_CONFIG"))
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	pages, err := layout.NewPages(cfg.Pages, pageTypes)
+	if err != nil {
+		return fmt.Errorf("error creating pages: %w", err)
+	}
+
+	watched, err := watchlist.Load(cfg.Watchlist)
+	if err != nil {
+		return fmt.Errorf("error loading watchlist: %w", err)
+	}
+
+	s.display.pages.Replace(pages)
+	s.display.watchlist.Replace(watched)
+	s.cfg.Store(cfg)
+
+	return nil
+}
+
+// onMicroConfig picks up the settings made in adsb.im
+func (s *services) onMicroConfig(micro *adsb.MicroConfig) {
+	s.routeAPI.Store(micro.RouteAPI)
+}
+
+// onADSBData checks each update of the aircraft for events and looks up their routes
+func (s *services) onADSBData(ctx context.Context, station adsb.Station, newADSBData adsb.Data) {
+	checkZones(s.zones, station, s.bus, newADSBData)
+	checkEmergencies(s.display.emergencies, s.display.alerts, s.priorities, s.bus, newADSBData)
+	checkWatchlist(s.display.watchlist, station, s.bus, newADSBData)
+
+	if s.routeAPI.Load() {
+		lookupRoutes(ctx, s.display.routes, station, newADSBData)
+	}
+}
+
+// lookupRoutes looks up the routes of aircraft that haven't been looked up yet
+func lookupRoutes(ctx context.Context, client *routes.Client, station adsb.Station, myADSBData adsb.Data) {
+	err := client.Update(ctx, time.Now(), station, myADSBData.Planes)
+	if err != nil {
+		fmt.Printf("error looking up routes: %s\n", err)
+	}
+}
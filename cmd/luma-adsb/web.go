@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/oled"
+	"github.com/swills/luma-adsb/internal/web"
+)
+
+// dashboardSnapshot returns a function giving what the display works out from the shared state, before any page's
+// filter, for the dashboard, API and metrics
+func dashboardSnapshot(displayData displayDataFunc, oledData *oled.Display) func() web.Snapshot {
+	return func() web.Snapshot {
+		return web.Snapshot{
+			Data:    displayData(adsb.Filter{}),
+			Display: oledData.Health(),
+		}
+	}
+}
+
+// startServers starts serving the dashboard, publishing to MQTT, taking commands and sending notifications if they're
+// configured. The HTTP handler is created before returning so the requests made after can be timed.
+func (s *services) startServers(ctx context.Context, cfg *config.Config, host string, oledData *oled.Display,
+	snapshot func() web.Snapshot,
+) {
+	control := &controller{svc: s, oledData: oledData, token: cfg.Control.Token}
+
+	if cfg.HTTP != "" {
+		server := s.newHTTPHandler(oledData, snapshot)
+
+		if cfg.Control.HTTP {
+			server.Handle("POST /command", control)
+		}
+
+		go serveHTTP(ctx, cfg.HTTP, server)
+	}
+
+	if cfg.MQTT.Broker != "" {
+		publisher := newMQTTPublisher(cfg.MQTT, host, snapshot)
+
+		if cfg.Control.MQTT {
+			publisher.commands = control.run
+		}
+
+		go publisher.run(ctx)
+	}
+
+	if cfg.Control.Socket != "" {
+		go serveControl(ctx, cfg.Control.Socket, control)
+	}
+
+	go s.notifier.Run(ctx)
+}
+
+// newHTTPHandler creates the dashboard, API and metrics, timing the requests made to adsb.im and the route API
+func (s *services) newHTTPHandler(oledData *oled.Display, snapshot func() web.Snapshot) *web.Server {
+	server := web.NewServer(oledData, snapshot)
+
+	server.Handle("GET /metrics", newMetrics(oledData, snapshot, adsb.HTTPClient, s.display.routes.HTTPClient))
+
+	return server
+}
+
+// serveHTTP serves handler on addr
+func serveHTTP(ctx context.Context, addr string, handler http.Handler) {
+	fmt.Printf("serving dashboard on %s\n", addr)
+
+	err := web.ListenAndServe(ctx, addr, handler)
+	if err != nil {
+		fmt.Printf("error serving dashboard: %s\n", err)
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 )
 
+// LastPositionData is the last position of an aircraft that has stopped sending its position, SeenPos is how many
+// seconds ago it was received
 type LastPositionData struct {
 	Latitude  float64 `json:"lat"`
 	Longitude float64 `json:"lon"`
@@ -19,20 +21,39 @@ type LastPositionData struct {
 }
 
 type Aircraft struct {
-	Hex        string           `json:"hex"`
-	MarkerType string           `json:"type"`
-	CallSign   string           `json:"flight"`
-	Latitude   float64          `json:"lat"`
-	Longitude  float64          `json:"lon"`
-	Altitude   json.Token       `json:"alt_baro"`
-	Last       LastPositionData `json:"lastPosition"`
-	Category   string           `json:"category,omitempty"`
+	Hex        string `json:"hex"`
+	MarkerType string `json:"type"`
+	CallSign   string `json:"flight"`
+	// Registration, TypeCode (the ICAO aircraft type designator), Description, Operator and DBFlags come from an
+	// aircraft database, either readsb's or one loaded locally
+	Registration string            `json:"r,omitempty"`
+	TypeCode     string            `json:"t,omitempty"`
+	Description  string            `json:"desc,omitempty"`
+	Operator     string            `json:"ownOp,omitempty"`
+	DBFlags      int               `json:"dbFlags,omitempty"`
+	Latitude     float64           `json:"lat"`
+	Longitude    float64           `json:"lon"`
+	Altitude     json.Token        `json:"alt_baro"`
+	SeenPos      *float64          `json:"seen_pos,omitempty"`
+	Last         *LastPositionData `json:"lastPosition,omitempty"`
+	Category     string            `json:"category,omitempty"`
+	Track        *float64          `json:"track,omitempty"`
+	GroundSpeed  *float64          `json:"gs,omitempty"`
+	BaroRate     *float64          `json:"baro_rate,omitempty"`
+	GeomRate     *float64          `json:"geom_rate,omitempty"`
+	Seen         float64           `json:"seen"`
+	Squawk       string            `json:"squawk,omitempty"`
+	Emergency    string            `json:"emergency,omitempty"`
 }
 
 type Data struct {
 	Planes []Aircraft `json:"aircraft"`
 }
 
+// HTTPClient makes the requests to adsb.im, it's kept apart from http.DefaultClient so it can be instrumented without
+// affecting anything else
+var HTTPClient = &http.Client{}
+
 // GetADSBData fetches aircraft.json and computes a messages/sec value using MessageRateTracker.
 func GetADSBData(ctx context.Context, host string, timeout time.Duration) (*Data, error) {
 	var err error
@@ -55,7 +76,7 @@ func GetADSBData(ctx context.Context, host string, timeout time.Duration) (*Data
 		return &Data{}, fmt.Errorf("error creating http req: %w", err)
 	}
 
-	res, err = http.DefaultClient.Do(req)
+	res, err = HTTPClient.Do(req)
 	if err != nil {
 		return &Data{}, fmt.Errorf("error making http request: %w", err)
 	}
@@ -0,0 +1,231 @@
+package adsb
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var ErrBadAirline = errors.New("bad airline")
+
+// Airline is an operator with an ICAO three letter designator
+type Airline struct {
+	ICAO string
+	// IATA is the two character airline code used in flight numbers, empty if it doesn't have one
+	IATA string
+	Name string
+	// Telephony is the callsign used on the radio, like "SPEEDBIRD" for British Airways
+	Telephony string
+}
+
+// Flight is a callsign decoded into the airline and its flight number
+type Flight struct {
+	Airline Airline
+	// Number is the flight number without leading zeros, which can end in letters
+	Number string
+}
+
+// Airlines looks up airlines by their ICAO designator
+type Airlines struct {
+	byICAO map[string]Airline
+}
+
+// icaoDesignatorLength is the length of an ICAO airline designator
+const icaoDesignatorLength = 3
+
+// airlineCallSign is an ICAO designator followed by a flight number of up to four characters that starts with a digit
+var airlineCallSign = regexp.MustCompile(`^([A-Z]{3})([0-9][0-9A-Z]{0,3})$`)
+
+// defaultAirlines are the airlines known without loading a table
+var defaultAirlines = []Airline{
+	{"AAL", "AA", "American Airlines", "AMERICAN"},
+	{"ACA", "AC", "Air Canada", "AIR CANADA"},
+	{"AFR", "AF", "Air France", "AIRFRANS"},
+	{"AIC", "AI", "Air India", "AIRINDIA"},
+	{"AMX", "AM", "Aeroméxico", "AEROMEXICO"},
+	{"ANA", "NH", "All Nippon Airways", "ALL NIPPON"},
+	{"ANZ", "NZ", "Air New Zealand", "NEW ZEALAND"},
+	{"ASA", "AS", "Alaska Airlines", "ALASKA"},
+	{"AUA", "OS", "Austrian", "AUSTRIAN"},
+	{"AVA", "AV", "Avianca", "AVIANCA"},
+	{"BAW", "BA", "British Airways", "SPEEDBIRD"},
+	{"BEL", "SN", "Brussels Airlines", "BEE-LINE"},
+	{"CAL", "CI", "China Airlines", "DYNASTY"},
+	{"CCA", "CA", "Air China", "AIR CHINA"},
+	{"CES", "MU", "China Eastern", "CHINA EASTERN"},
+	{"CFG", "DE", "Condor", "CONDOR"},
+	{"CKS", "K4", "Kalitta Air", "CONNIE"},
+	{"CLX", "CV", "Cargolux", "CARGOLUX"},
+	{"CMP", "CM", "Copa Airlines", "COPA"},
+	{"CPA", "CX", "Cathay Pacific", "CATHAY"},
+	{"CSN", "CZ", "China Southern", "CHINA SOUTHERN"},
+	{"DAL", "DL", "Delta", "DELTA"},
+	{"DLH", "LH", "Lufthansa", "LUFTHANSA"},
+	{"EDV", "9E", "Endeavor Air", "ENDEAVOR"},
+	{"EIN", "EI", "Aer Lingus", "SHAMROCK"},
+	{"EJA", "", "NetJets", "EXECJET"},
+	{"ELY", "LY", "El Al", "EL AL"},
+	{"ENY", "MQ", "Envoy Air", "ENVOY"},
+	{"ETD", "EY", "Etihad", "ETIHAD"},
+	{"ETH", "ET", "Ethiopian Airlines", "ETHIOPIAN"},
+	{"EVA", "BR", "EVA Air", "EVA"},
+	{"EWG", "EW", "Eurowings", "EUROWINGS"},
+	{"EZY", "U2", "easyJet", "EASY"},
+	{"FDX", "FX", "FedEx", "FEDEX"},
+	{"FFT", "F9", "Frontier", "FRONTIER FLIGHT"},
+	{"FIN", "AY", "Finnair", "FINNAIR"},
+	{"GTI", "5Y", "Atlas Air", "GIANT"},
+	{"HAL", "HA", "Hawaiian Airlines", "HAWAIIAN"},
+	{"IBE", "IB", "Iberia", "IBERIA"},
+	{"ICE", "FI", "Icelandair", "ICEAIR"},
+	{"ITY", "AZ", "ITA Airways", "ITARROW"},
+	{"JAL", "JL", "Japan Airlines", "JAPANAIR"},
+	{"JBU", "B6", "JetBlue", "JETBLUE"},
+	{"JIA", "OH", "PSA Airlines", "BLUE STREAK"},
+	{"JZA", "QK", "Jazz", "JAZZ"},
+	{"KAL", "KE", "Korean Air", "KOREANAIR"},
+	{"KLM", "KL", "KLM", "KLM"},
+	{"LAN", "LA", "LATAM", "LAN"},
+	{"LOT", "LO", "LOT Polish Airlines", "POLLOT"},
+	{"MXY", "MX", "Breeze Airways", "MOXY"},
+	{"NKS", "NK", "Spirit", "SPIRIT WINGS"},
+	{"NWS", "", "Nordwind", "NORDLAND"},
+	{"PDT", "PT", "Piedmont Airlines", "PIEDMONT"},
+	{"QFA", "QF", "Qantas", "QANTAS"},
+	{"QTR", "QR", "Qatar Airways", "QATARI"},
+	{"QXE", "QX", "Horizon Air", "HORIZON"},
+	{"RPA", "YX", "Republic Airways", "BRICKYARD"},
+	{"RYR", "FR", "Ryanair", "RYANAIR"},
+	{"SAS", "SK", "SAS", "SCANDINAVIAN"},
+	{"SCX", "SY", "Sun Country", "SUN COUNTRY"},
+	{"SIA", "SQ", "Singapore Airlines", "SINGAPORE"},
+	{"SKW", "OO", "SkyWest", "SKYWEST"},
+	{"SVA", "SV", "Saudia", "SAUDIA"},
+	{"SWA", "WN", "Southwest", "SOUTHWEST"},
+	{"SWR", "LX", "Swiss", "SWISS"},
+	{"TAP", "TP", "TAP Air Portugal", "AIR PORTUGAL"},
+	{"THY", "TK", "Turkish Airlines", "TURKISH"},
+	{"UAE", "EK", "Emirates", "EMIRATES"},
+	{"UAL", "UA", "United", "UNITED"},
+	{"UPS", "5X", "UPS", "UPS"},
+	{"VIR", "VS", "Virgin Atlantic", "VIRGIN"},
+	{"VJT", "", "VistaJet", "VISTA MALTA"},
+	{"VOI", "Y4", "Volaris", "VOLARIS"},
+	{"WJA", "WS", "WestJet", "WESTJET"},
+	{"WZZ", "W6", "Wizz Air", "WIZZ AIR"},
+}
+
+// NewAirlines creates a table of the given airlines along with the built-in ones, the given ones replacing any built-in
+// airline with the same designator
+func NewAirlines(airlines []Airline) *Airlines {
+	table := &Airlines{byICAO: make(map[string]Airline, len(defaultAirlines)+len(airlines))}
+
+	for _, airline := range append(append([]Airline(nil), defaultAirlines...), airlines...) {
+		airline.ICAO = strings.ToUpper(airline.ICAO)
+		table.byICAO[airline.ICAO] = airline
+	}
+
+	return table
+}
+
+// LoadAirlines reads a CSV file of airlines, one per line as ICAO designator, name, telephony callsign and IATA code,
+// and adds them to the built-in ones. An empty path gives just the built-in airlines.
+func LoadAirlines(path string) (*Airlines, error) {
+	if path == "" {
+		return NewAirlines(nil), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening airlines: %w", err)
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	var airlines []Airline
+
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading airlines %s: %w", path, err)
+		}
+
+		field := func(index int) string {
+			if index >= len(fields) {
+				return ""
+			}
+
+			return strings.TrimSpace(fields[index])
+		}
+
+		if len(field(0)) != icaoDesignatorLength {
+			line, _ := reader.FieldPos(0)
+
+			return nil, fmt.Errorf("%w on line %d of %s: designator should be three letters", ErrBadAirline, line, path)
+		}
+
+		airlines = append(airlines, Airline{ICAO: field(0), Name: field(1), Telephony: field(2), IATA: field(3)})
+	}
+
+	return NewAirlines(airlines), nil
+}
+
+// Lookup finds an airline by its ICAO designator
+func (a *Airlines) Lookup(icao string) (Airline, bool) {
+	airline, ok := a.byICAO[strings.ToUpper(icao)]
+
+	return airline, ok
+}
+
+// Decode splits a callsign like SWA1234 into its airline and flight number. ok is false for callsigns of unknown
+// airlines and those that aren't an airline's, like registrations.
+func (a *Airlines) Decode(callSign string) (Flight, bool) {
+	match := airlineCallSign.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(callSign)))
+	if match == nil {
+		return Flight{}, false
+	}
+
+	airline, ok := a.Lookup(match[1])
+	if !ok {
+		return Flight{}, false
+	}
+
+	number := strings.TrimLeft(match[2], "0")
+	if number == "" || number[0] < '0' || number[0] > '9' {
+		number = "0" + number
+	}
+
+	return Flight{Airline: airline, Number: number}, true
+}
+
+// IATA is the flight number as printed on tickets, like WN1234, or empty if the airline has no IATA code
+func (f Flight) IATA() string {
+	if f.Airline.IATA == "" {
+		return ""
+	}
+
+	return f.Airline.IATA + f.Number
+}
+
+// String describes the flight like "Southwest 1234 (WN1234)"
+func (f Flight) String() string {
+	name := f.Airline.Name + " " + f.Number
+
+	if iata := f.IATA(); iata != "" {
+		return name + " (" + iata + ")"
+	}
+
+	return name
+}
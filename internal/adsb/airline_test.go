@@ -0,0 +1,160 @@
+package adsb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAirlinesDecode(t *testing.T) {
+	t.Parallel()
+
+	airlines := NewAirlines([]Airline{
+		{ICAO: "xyz", Name: "Local Air", Telephony: "LOCAL"},
+		// given airlines replace built-in ones
+		{ICAO: "BAW", IATA: "BA", Name: "British", Telephony: "SPEEDBIRD"},
+	})
+
+	tests := []struct {
+		callSign string
+		wantOK   bool
+		name     string
+		number   string
+		iata     string
+		str      string
+	}{
+		{"SWA1234", true, "Southwest", "1234", "WN1234", "Southwest 1234 (WN1234)"},
+		{"swa1234 ", true, "Southwest", "1234", "WN1234", "Southwest 1234 (WN1234)"},
+		{"UAL0042", true, "United", "42", "UA42", "United 42 (UA42)"},
+		{"EZY12AB", true, "easyJet", "12AB", "U212AB", "easyJet 12AB (U212AB)"},
+		{"ITY611", true, "ITA Airways", "611", "AZ611", "ITA Airways 611 (AZ611)"},
+		// leading zeros are dropped but the number still starts with a digit
+		{"DAL0", true, "Delta", "0", "DL0", "Delta 0 (DL0)"},
+		{"BAW0A", true, "British", "0A", "BA0A", "British 0A (BA0A)"},
+		{"EJA555", true, "NetJets", "555", "", "NetJets 555"},
+		{"XYZ1", true, "Local Air", "1", "", "Local Air 1"},
+		{"N12345", false, "", "", "", ""},
+		{"GABCD", false, "", "", "", ""},
+		{"SWA", false, "", "", "", ""},
+		{"SWA12345", false, "", "", "", ""},
+		{"SWAA123", false, "", "", "", ""},
+		{"QQQ123", false, "", "", "", ""},
+		// Alitalia and Vistara are gone
+		{"AZA123", false, "", "", "", ""},
+		{"VTI123", false, "", "", "", ""},
+		{"", false, "", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.callSign, func(t *testing.T) {
+			t.Parallel()
+
+			flight, ok := airlines.Decode(tt.callSign)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %t, want %t", ok, tt.wantOK)
+			}
+
+			if flight.Airline.Name != tt.name || flight.Number != tt.number {
+				t.Errorf("got %q flight %q, want %q flight %q", flight.Airline.Name, flight.Number, tt.name, tt.number)
+			}
+
+			if ok && (flight.IATA() != tt.iata || flight.String() != tt.str) {
+				t.Errorf("got %q and %q, want %q and %q", flight.IATA(), flight.String(), tt.iata, tt.str)
+			}
+		})
+	}
+}
+
+// TestDefaultAirlines checks that the built-in designators and IATA codes are well formed and not repeated
+func TestDefaultAirlines(t *testing.T) {
+	t.Parallel()
+
+	var designators, iataCodes []string
+
+	for _, airline := range defaultAirlines {
+		if !airlineCallSign.MatchString(airline.ICAO+"1") || airline.Name == "" || airline.Telephony == "" {
+			t.Errorf("bad airline %+v", airline)
+		}
+
+		if airline.IATA != "" && len(airline.IATA) != 2 {
+			t.Errorf("%s: bad IATA code %q", airline.ICAO, airline.IATA)
+		}
+
+		designators = append(designators, airline.ICAO)
+
+		if airline.IATA != "" {
+			iataCodes = append(iataCodes, airline.IATA)
+		}
+	}
+
+	if !slices.IsSorted(designators) {
+		t.Errorf("designators aren't in order")
+	}
+
+	for _, codes := range [][]string{designators, iataCodes} {
+		slices.Sort(codes)
+
+		if len(slices.Compact(codes)) != len(codes) {
+			t.Errorf("got repeated codes in %q", codes)
+		}
+	}
+}
+
+func TestLoadAirlines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr error
+		want    Airline
+	}{
+		{
+			name: "added",
+			csv:  "# ICAO, name, telephony, IATA\nxyz, Local Air, LOCAL, LA\n",
+			want: Airline{ICAO: "XYZ", IATA: "LA", Name: "Local Air", Telephony: "LOCAL"},
+		},
+		{
+			name: "without IATA",
+			csv:  "XYZ,Local Air,LOCAL\n",
+			want: Airline{ICAO: "XYZ", Name: "Local Air", Telephony: "LOCAL"},
+		},
+		{
+			name:    "bad designator",
+			csv:     "XYZ,Local Air\nXY,Short\n",
+			wantErr: ErrBadAirline,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "airlines.csv")
+
+			err := os.WriteFile(path, []byte(tt.csv), 0o600)
+			if err != nil {
+				t.Fatalf("error writing airlines: %s", err)
+			}
+
+			airlines, err := LoadAirlines(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got, ok := airlines.Lookup("xyz"); !ok || got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+
+			if _, ok := airlines.Lookup("SWA"); !ok {
+				t.Errorf("got no built-in airlines")
+			}
+		})
+	}
+}
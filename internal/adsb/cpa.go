@@ -0,0 +1,149 @@
+package adsb
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	milesPerNauticalMile = 1.15078
+	secondsPerHour       = 3600
+	secondsPerMinute     = 60
+
+	// OverheadMiles is how close to the station an aircraft has to pass to count as overhead
+	OverheadMiles = 1.0
+)
+
+// Approach is the predicted closest point of approach of an aircraft to the station, assuming it keeps its current
+// ground speed, track and vertical rate
+type Approach struct {
+	// Time until the closest approach, 0 if the aircraft is already moving away
+	Time time.Duration
+	// Distance is the slant range in miles at the closest approach
+	Distance float64
+	// HorizontalDistance is the distance along the ground in miles at the closest approach
+	HorizontalDistance float64
+	// Approaching is true while the aircraft is getting closer
+	Approaching bool
+}
+
+// Overhead reports whether the aircraft is predicted to pass over the station
+func (a Approach) Overhead() bool {
+	return a.Approaching && a.HorizontalDistance <= OverheadMiles
+}
+
+// ClosestApproach predicts the aircraft's closest approach to the station within horizon. ok is false if the aircraft
+// has no ground speed or track, or isn't sending its position any more.
+func (s Station) ClosestApproach(aircraft Aircraft, horizon time.Duration) (Approach, bool, error) {
+	located, hasPosition := s.PositionOf(aircraft)
+	if !hasPosition || located.Last || aircraft.GroundSpeed == nil || aircraft.Track == nil {
+		return Approach{}, false, nil
+	}
+
+	relative, err := s.RelativeTo(aircraft)
+	if err != nil {
+		return Approach{}, false, err
+	}
+
+	// work in a flat plane around the station in miles, which is close enough at ADS-B ranges
+	bearingRad := relative.Bearing * math.Pi / 180
+	trackRad := *aircraft.Track * math.Pi / 180
+	speed := *aircraft.GroundSpeed * milesPerNauticalMile / secondsPerHour
+
+	position := [3]float64{relative.Distance * math.Sin(bearingRad), relative.Distance * math.Cos(bearingRad), 0}
+	velocity := [3]float64{speed * math.Sin(trackRad), speed * math.Cos(trackRad), 0}
+
+	altFeet, hasAlt := aircraft.Altitude.(float64)
+	if hasAlt {
+		position[2] = (altFeet - s.Altitude) / feetPerMile
+
+		if rate := verticalRate(aircraft); rate != nil {
+			velocity[2] = *rate / secondsPerMinute / feetPerMile
+		}
+	}
+
+	approach := Approach{
+		Approaching: dot(position, velocity) < 0,
+	}
+
+	var seconds float64
+
+	if speedSquared := dot(velocity, velocity); speedSquared > 0 {
+		seconds = min(max(-dot(position, velocity)/speedSquared, 0), horizon.Seconds())
+	}
+
+	for i := range position {
+		position[i] += velocity[i] * seconds
+	}
+
+	approach.Time = time.Duration(seconds * float64(time.Second))
+	approach.Distance = math.Sqrt(dot(position, position))
+	approach.HorizontalDistance = math.Hypot(position[0], position[1])
+
+	return approach, true, nil
+}
+
+// FindClosestApproach returns the aircraft predicted to pass closest to the station within horizon, along with its
+// approach. Aircraft without a speed and track are ranked by where they are now, and one that can't be located is
+// logged and left out rather than failing the rest.
+func FindClosestApproach(myADSBData Data, station Station, horizon time.Duration) (Aircraft, Approach) {
+	var closestPlane Aircraft
+
+	var closestApproach Approach
+
+	closestDist := math.MaxFloat64
+
+	for _, flight := range myADSBData.Planes {
+		if !station.HasPosition(flight) {
+			continue
+		}
+
+		approach, err := approachOrCurrent(station, flight, horizon)
+		if err != nil {
+			fmt.Printf("error predicting approach of %s: %s\n", flight.Hex, err)
+
+			continue
+		}
+
+		if approach.Distance < closestDist {
+			closestDist = approach.Distance
+			closestPlane = flight
+			closestApproach = approach
+		}
+	}
+
+	return closestPlane, closestApproach
+}
+
+// approachOrCurrent predicts the aircraft's closest approach, treating it as stationary if it can't be predicted
+func approachOrCurrent(station Station, aircraft Aircraft, horizon time.Duration) (Approach, error) {
+	approach, ok, err := station.ClosestApproach(aircraft, horizon)
+	if err != nil {
+		return Approach{}, err
+	}
+
+	if ok {
+		return approach, nil
+	}
+
+	relative, err := station.RelativeTo(aircraft)
+	if err != nil {
+		return Approach{}, err
+	}
+
+	return Approach{Distance: relative.SlantRange, HorizontalDistance: relative.Distance}, nil
+}
+
+// verticalRate prefers the barometric rate, falling back to the geometric rate, in feet per minute
+func verticalRate(aircraft Aircraft) *float64 {
+	if aircraft.BaroRate != nil {
+		return aircraft.BaroRate
+	}
+
+	return aircraft.GeomRate
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
@@ -0,0 +1,135 @@
+package adsb
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// moving is an aircraft at latitude, longitude and altitude in feet with a ground speed in knots and a track
+func moving(hex string, latitude, longitude, altitude, speed, track float64) Aircraft {
+	aircraft := at(hex, latitude, longitude)
+	aircraft.Altitude = altitude
+	aircraft.GroundSpeed = &speed
+	aircraft.Track = &track
+
+	return aircraft
+}
+
+func TestClosestApproach(t *testing.T) {
+	t.Parallel()
+
+	station := Station{Latitude: 0, Longitude: 0}
+	// north is 0.1 degrees of latitude north of the station, 6.87 miles
+	const north = 0.1
+	// 360 knots covers the 6.87 miles in about a minute
+	const knots = 360.0
+
+	climbing := moving("climbing", north, 0, 0, knots, 180)
+	rate := 5280.0
+	climbing.BaroRate = &rate
+
+	lost := moving("lost", north, 0, 0, knots, 180)
+	lost.SeenPos = nil
+	lost.Last = &LastPositionData{Latitude: north, Longitude: 0, SeenPos: 1}
+
+	tests := []struct {
+		name        string
+		aircraft    Aircraft
+		horizon     time.Duration
+		wantOK      bool
+		time        time.Duration
+		horizontal  float64
+		distance    float64
+		approaching bool
+		overhead    bool
+	}{
+		{
+			name: "overhead", aircraft: moving("a", north, 0, 0, knots, 180), horizon: time.Hour,
+			wantOK: true, time: 59700 * time.Millisecond, approaching: true, overhead: true,
+		},
+		{
+			name: "overhead at altitude", aircraft: moving("a", north, 0, 10560, knots, 180), horizon: time.Hour,
+			wantOK: true, time: 59700 * time.Millisecond, distance: 2, approaching: true, overhead: true,
+		},
+		{
+			name: "passing to the east", aircraft: moving("a", north, 0.03, 0, knots, 180), horizon: time.Hour,
+			wantOK: true, time: 59700 * time.Millisecond, horizontal: 2.07, distance: 2.07, approaching: true,
+		},
+		{
+			name: "beyond the horizon", aircraft: moving("a", north, 0, 0, knots, 180), horizon: 30 * time.Second,
+			wantOK: true, time: 30 * time.Second, horizontal: 3.42, distance: 3.42, approaching: true,
+		},
+		{
+			name: "moving away", aircraft: moving("a", north, 0, 0, knots, 0), horizon: time.Hour,
+			wantOK: true, horizontal: 6.87, distance: 6.87,
+		},
+		{
+			// climbing a mile a minute it's nearest just before it passes over, almost a mile up
+			name: "climbing", aircraft: climbing, horizon: time.Hour,
+			wantOK: true, time: 58500 * time.Millisecond, horizontal: 0.14, distance: 0.99, approaching: true,
+			overhead: true,
+		},
+		{name: "no track", aircraft: at("a", north, 0), horizon: time.Hour},
+		{name: "position lost", aircraft: lost, horizon: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			approach, ok, err := station.ClosestApproach(tt.aircraft, tt.horizon)
+			if err != nil {
+				t.Fatalf("error predicting approach: %s", err)
+			}
+
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %t, want %t", ok, tt.wantOK)
+			}
+
+			if (approach.Time - tt.time).Abs() > time.Second {
+				t.Errorf("got time %s, want %s", approach.Time, tt.time)
+			}
+
+			if math.Abs(approach.HorizontalDistance-tt.horizontal) > 0.02 {
+				t.Errorf("got horizontal distance %.2f, want %.2f", approach.HorizontalDistance, tt.horizontal)
+			}
+
+			if math.Abs(approach.Distance-tt.distance) > 0.02 {
+				t.Errorf("got distance %.2f, want %.2f", approach.Distance, tt.distance)
+			}
+
+			if approach.Approaching != tt.approaching || approach.Overhead() != tt.overhead {
+				t.Errorf("got approaching %t and overhead %t, want %t and %t", approach.Approaching,
+					approach.Overhead(), tt.approaching, tt.overhead)
+			}
+		})
+	}
+}
+
+func TestFindClosestApproach(t *testing.T) {
+	t.Parallel()
+
+	station := Station{Latitude: 0, Longitude: 0}
+	data := Data{Planes: []Aircraft{
+		// nearest now but heading away
+		moving("leaving", 0.05, 0, 0, 360, 0),
+		// further away but about to pass overhead
+		moving("arriving", 0.1, 0, 0, 360, 180),
+		// without a track it's ranked by where it is now
+		at("parked", 0.2, 0),
+		{Hex: "nopos"},
+		// Vincenty's formula doesn't converge for points on opposite sides of the earth
+		at("antipode", 0, 180),
+	}}
+
+	aircraft, approach := FindClosestApproach(data, station, time.Hour)
+	if aircraft.Hex != "arriving" || !approach.Overhead() {
+		t.Errorf("got %s overhead %t, want arriving overhead", aircraft.Hex, approach.Overhead())
+	}
+
+	aircraft, _ = FindClosestApproach(data, station, 10*time.Second)
+	if aircraft.Hex != "leaving" {
+		t.Errorf("got %s with a short horizon, want leaving", aircraft.Hex)
+	}
+}
@@ -0,0 +1,40 @@
+package adsb
+
+// EmergencyStatus describes why an aircraft is in an emergency
+type EmergencyStatus struct {
+	// Code is the emergency squawk, like "7700", or the emergency status the aircraft is sending, like "minfuel"
+	Code string
+	// Short is a word or two that fits across the display
+	Short string
+	// Description is the meaning of the code
+	Description string
+}
+
+// emergencySquawks are the transponder codes reserved for emergencies
+var emergencySquawks = map[string]EmergencyStatus{
+	"7500": {Code: "7500", Short: "HIJACK", Description: "Unlawful interference"},
+	"7600": {Code: "7600", Short: "RADIO FAIL", Description: "Radio failure"},
+	"7700": {Code: "7700", Short: "EMERGENCY", Description: "General emergency"},
+}
+
+// emergencyStates are the emergency values readsb reports from the aircraft's emergency/priority status
+var emergencyStates = map[string]EmergencyStatus{
+	"general":   {Code: "general", Short: "EMERGENCY", Description: "General emergency"},
+	"lifeguard": {Code: "lifeguard", Short: "MEDICAL", Description: "Lifeguard/medical emergency"},
+	"minfuel":   {Code: "minfuel", Short: "MIN FUEL", Description: "Minimum fuel"},
+	"nordo":     {Code: "nordo", Short: "NO RADIO", Description: "No communications"},
+	"unlawful":  {Code: "unlawful", Short: "HIJACK", Description: "Unlawful interference"},
+	"downed":    {Code: "downed", Short: "DOWNED", Description: "Downed aircraft"},
+}
+
+// InEmergency reports whether the aircraft is in an emergency, going by the emergency status it's sending and
+// falling back to its squawk
+func (a Aircraft) InEmergency() (EmergencyStatus, bool) {
+	if status, ok := emergencyStates[a.Emergency]; ok {
+		return status, true
+	}
+
+	status, ok := emergencySquawks[a.Squawk]
+
+	return status, ok
+}
@@ -110,7 +110,7 @@ func GetFeederStatus(ctx context.Context, host string, timeout time.Duration, fe
 		return FeederStatus{}, fmt.Errorf("error creating http req: %w", err)
 	}
 
-	res, err = http.DefaultClient.Do(req)
+	res, err = HTTPClient.Do(req)
 	if err != nil {
 		return FeederStatus{}, fmt.Errorf("error making http request: %w", err)
 	}
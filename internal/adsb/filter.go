@@ -0,0 +1,165 @@
+package adsb
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var ErrBadFilter = errors.New("bad filter")
+
+// ground filter settings
+const (
+	GroundInclude = ""
+	GroundExclude = "exclude"
+	GroundOnly    = "only"
+)
+
+// groundAltitude is the alt_baro value of an aircraft on the ground
+const groundAltitude = "ground"
+
+// Filter selects aircraft. Empty fields match everything. The include lists match if any pattern matches and the
+// exclude lists remove anything any pattern matches. Patterns are shell style globs, compared without regard to case,
+// so "tisb_*" matches every TIS-B source type.
+type Filter struct {
+	// MinAltitude and MaxAltitude limit the barometric altitude in feet. Aircraft on the ground are at 0 and those
+	// without an altitude are left out when either is set.
+	MinAltitude *float64 `json:"min_altitude,omitempty"`
+	MaxAltitude *float64 `json:"max_altitude,omitempty"`
+	// MaxRange leaves out aircraft with a slant range over this many miles, and aircraft without a recent position
+	MaxRange float64 `json:"max_range,omitempty"`
+	// Categories are ADS-B emitter categories like "A3" or "C1"
+	Categories        []string `json:"categories,omitempty"`
+	ExcludeCategories []string `json:"exclude_categories,omitempty"`
+	// Sources are the types of message the aircraft was last heard by, like "adsb_icao", "mlat" or "mode_s"
+	Sources        []string `json:"sources,omitempty"`
+	ExcludeSources []string `json:"exclude_sources,omitempty"`
+	// Ground is GroundInclude, GroundExclude to leave out aircraft on the ground or GroundOnly for just those
+	Ground           string   `json:"ground,omitempty"`
+	Hex              []string `json:"hex,omitempty"`
+	ExcludeHex       []string `json:"exclude_hex,omitempty"`
+	CallSigns        []string `json:"callsigns,omitempty"`
+	ExcludeCallSigns []string `json:"exclude_callsigns,omitempty"`
+}
+
+// Validate checks the ground setting and patterns
+func (f Filter) Validate() error {
+	if f.Ground != GroundInclude && f.Ground != GroundExclude && f.Ground != GroundOnly {
+		return fmt.Errorf("%w: ground should be %q or %q", ErrBadFilter, GroundExclude, GroundOnly)
+	}
+
+	for _, patterns := range [][]string{
+		f.Categories, f.ExcludeCategories, f.Sources, f.ExcludeSources, f.Hex, f.ExcludeHex, f.CallSigns,
+		f.ExcludeCallSigns,
+	} {
+		for _, pattern := range patterns {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return fmt.Errorf("%w: pattern %q: %w", ErrBadFilter, pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Match reports whether the aircraft passes the filter. The station is only used to check the range.
+func (f Filter) Match(station Station, aircraft Aircraft) (bool, error) {
+	if !f.matchAltitude(aircraft) {
+		return false, nil
+	}
+
+	if !matchLists(aircraft.Category, f.Categories, f.ExcludeCategories) ||
+		!matchLists(aircraft.MarkerType, f.Sources, f.ExcludeSources) ||
+		!matchLists(aircraft.Hex, f.Hex, f.ExcludeHex) ||
+		!matchLists(strings.TrimSpace(aircraft.CallSign), f.CallSigns, f.ExcludeCallSigns) {
+		return false, nil
+	}
+
+	if f.MaxRange > 0 {
+		if !station.HasPosition(aircraft) {
+			return false, nil
+		}
+
+		relative, err := station.RelativeTo(aircraft)
+		if err != nil {
+			return false, err
+		}
+
+		if relative.SlantRange > f.MaxRange {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Apply returns the aircraft that pass the filter. An aircraft whose range can't be worked out, like one with a bad
+// position that Vincenty's formula doesn't converge for, is logged and doesn't pass rather than failing the rest.
+func (f Filter) Apply(station Station, myADSBData Data) Data {
+	planes := make([]Aircraft, 0, len(myADSBData.Planes))
+
+	for _, flight := range myADSBData.Planes {
+		ok, err := f.Match(station, flight)
+		if err != nil {
+			fmt.Printf("error filtering %s: %s\n", flight.Hex, err)
+
+			continue
+		}
+
+		if ok {
+			planes = append(planes, flight)
+		}
+	}
+
+	return Data{Planes: planes}
+}
+
+func (f Filter) matchAltitude(aircraft Aircraft) bool {
+	onGround := aircraft.Altitude == groundAltitude
+
+	switch {
+	case f.Ground == GroundExclude && onGround:
+		return false
+	case f.Ground == GroundOnly && !onGround:
+		return false
+	case f.MinAltitude == nil && f.MaxAltitude == nil:
+		return true
+	}
+
+	var altFeet float64
+
+	if !onGround {
+		var ok bool
+
+		altFeet, ok = aircraft.Altitude.(float64)
+		if !ok {
+			return false
+		}
+	}
+
+	return (f.MinAltitude == nil || altFeet >= *f.MinAltitude) && (f.MaxAltitude == nil || altFeet <= *f.MaxAltitude)
+}
+
+// matchLists reports whether value matches one of include, or include is empty, and matches nothing in exclude
+func matchLists(value string, include []string, exclude []string) bool {
+	if len(include) > 0 && !matchAny(value, include) {
+		return false
+	}
+
+	return !matchAny(value, exclude)
+}
+
+func matchAny(value string, patterns []string) bool {
+	value = strings.ToLower(value)
+
+	for _, pattern := range patterns {
+		matched, err := path.Match(strings.ToLower(pattern), value)
+		if err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
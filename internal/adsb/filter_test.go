@@ -0,0 +1,128 @@
+package adsb
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFilterApply(t *testing.T) {
+	t.Parallel()
+
+	station := Station{Latitude: 0, Longitude: 0}
+	aircraft := func(hex, callSign, category, source string, latitude float64, altitude any) Aircraft {
+		plane := at(hex, latitude, 0)
+		plane.CallSign = callSign
+		plane.Category = category
+		plane.MarkerType = source
+		plane.Altitude = altitude
+
+		return plane
+	}
+
+	// Vincenty's formula doesn't converge for points on opposite sides of the earth, so it can't be ranged
+	antipode := aircraft("antipode", "", "B1", "adsb_icao", 0, 10000.0)
+	antipode.Longitude = 180
+
+	data := Data{Planes: []Aircraft{
+		aircraft("a1b2c3", "BAW123  ", "A3", "adsb_icao", 0.01, 35000.0),
+		aircraft("400123", "EZY45", "A2", "mlat", 0.1, 3000.0),
+		aircraft("406abc", "", "C1", "tisb_icao", 0.02, groundAltitude),
+		aircraft("ae1234", "RCH1", "A5", "mode_s", 1, nil),
+		{Hex: "nopos", Category: "A3", Altitude: 10000.0},
+		antipode,
+	}}
+
+	feet := func(altitude float64) *float64 {
+		return &altitude
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"empty", Filter{}, []string{"a1b2c3", "400123", "406abc", "ae1234", "nopos", "antipode"}},
+		{"min altitude", Filter{MinAltitude: feet(5000)}, []string{"a1b2c3", "nopos", "antipode"}},
+		// aircraft on the ground are at 0, those without an altitude are left out
+		{"max altitude", Filter{MaxAltitude: feet(5000)}, []string{"400123", "406abc"}},
+		{
+			"altitude band", Filter{MinAltitude: feet(1000), MaxAltitude: feet(10000)},
+			[]string{"400123", "nopos", "antipode"},
+		},
+		{
+			"exclude ground", Filter{Ground: GroundExclude},
+			[]string{"a1b2c3", "400123", "ae1234", "nopos", "antipode"},
+		},
+		{"ground only", Filter{Ground: GroundOnly}, []string{"406abc"}},
+		// 0.1 degrees is 6.9 miles away, 1 degree is 69, and nopos and antipode can't be ranged at all
+		{"max range", Filter{MaxRange: 10}, []string{"a1b2c3", "400123", "406abc"}},
+		{"categories", Filter{Categories: []string{"a3", "A5"}}, []string{"a1b2c3", "ae1234", "nopos"}},
+		{"exclude categories", Filter{ExcludeCategories: []string{"A*"}}, []string{"406abc", "antipode"}},
+		{"sources", Filter{Sources: []string{"tisb_*", "MLAT"}}, []string{"400123", "406abc"}},
+		{
+			"exclude sources", Filter{ExcludeSources: []string{"mode_s"}},
+			[]string{"a1b2c3", "400123", "406abc", "nopos", "antipode"},
+		},
+		{"hex", Filter{Hex: []string{"40*"}}, []string{"400123", "406abc"}},
+		{"exclude hex", Filter{Hex: []string{"40*"}, ExcludeHex: []string{"406ABC"}}, []string{"400123"}},
+		// call signs are compared without their padding
+		{"call signs", Filter{CallSigns: []string{"baw123", "EZY?5"}}, []string{"a1b2c3", "400123"}},
+		{"exclude call signs", Filter{ExcludeCallSigns: []string{"RCH*", ""}}, []string{"a1b2c3", "400123"}},
+		{"max range with no others", Filter{MaxRange: 20000}, []string{"a1b2c3", "400123", "406abc", "ae1234"}},
+		{
+			"combined", Filter{Ground: GroundExclude, Categories: []string{"A*"}, MaxRange: 10},
+			[]string{"a1b2c3", "400123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.filter.Validate()
+			if err != nil {
+				t.Fatalf("error validating filter: %s", err)
+			}
+
+			filtered := tt.filter.Apply(station, data)
+
+			var got []string
+
+			for _, plane := range filtered.Planes {
+				got = append(got, plane.Hex)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr error
+	}{
+		{"empty", Filter{}, nil},
+		{"ground", Filter{Ground: GroundOnly}, nil},
+		{"bad ground", Filter{Ground: "sometimes"}, ErrBadFilter},
+		{"bad category", Filter{Categories: []string{"A["}}, ErrBadFilter},
+		{"bad call sign", Filter{ExcludeCallSigns: []string{"\\"}}, ErrBadFilter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.filter.Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,45 +1,37 @@
 package adsb
 
 import (
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/jftuga/geodist"
 )
 
-func FindClosest(myADSBData Data, myLatFloat, myLonFloat, myAltFloat float64) (Aircraft, float64) {
-	myLoc := geodist.Coord{Lat: myLatFloat, Lon: myLonFloat}
-
-	var closestDist = math.MaxFloat64
-
-	var closestPlane Aircraft
-
-	for _, flight := range myADSBData.Planes {
-		if flight.Latitude == 0 || flight.Longitude == 0 {
-			continue
-		}
-
-		planeLoc := geodist.Coord{Lat: flight.Latitude, Lon: flight.Longitude}
-
-		distanceMiles, _, err := geodist.VincentyDistance(myLoc, planeLoc)
-		if err != nil {
-			return closestPlane, 0
-		}
+// Station is the position of the receiver, Altitude is in feet. Aircraft positions older than MaxPositionAge are
+// ignored, 0 uses DefaultMaxPositionAge.
+type Station struct {
+	Latitude       float64
+	Longitude      float64
+	Altitude       float64
+	MaxPositionAge time.Duration
+}
 
-		if planeAlt, ok := flight.Altitude.(float64); ok && flight.Altitude != 0 {
-			distanceMiles = threeDDistance(distanceMiles, myAltFloat/5280.0, planeAlt/5280.0)
-		}
+// FindClosest returns the aircraft with the shortest slant range from the station and that range in miles. Aircraft
+// are located by their current or last position, ignoring any older than DefaultMaxPositionAge.
+func FindClosest(myADSBData Data, myLatFloat, myLonFloat, myAltFloat float64) (Aircraft, float64, error) {
+	station := Station{Latitude: myLatFloat, Longitude: myLonFloat, Altitude: myAltFloat}
 
-		if distanceMiles < closestDist {
-			closestDist = distanceMiles
-			closestPlane = flight
-		}
+	nearest, err := station.Query(myADSBData, Query{SortBy: SortByDistance, Limit: 1})
+	if err != nil {
+		return Aircraft{}, 0, err
 	}
 
-	if closestDist == math.MaxFloat64 {
-		closestDist = 0
+	if len(nearest) == 0 {
+		return Aircraft{}, 0, nil
 	}
 
-	return closestPlane, closestDist
+	return nearest[0].Aircraft, nearest[0].SlantRange, nil
 }
 
 // threeDDistance calculates the distance to the plane in 3d space. all 3 args must be in the
@@ -52,3 +44,116 @@ func threeDDistance(horizontalDistance, myAlt, objectAlt float64) float64 {
 
 	return distThreeD
 }
+
+// Bearing returns the initial great circle bearing in degrees from the first position to the second, 0 being north
+func Bearing(fromLat, fromLon, toLat, toLon float64) float64 {
+	fromLatRad := fromLat * math.Pi / 180
+	toLatRad := toLat * math.Pi / 180
+	deltaLonRad := (toLon - fromLon) * math.Pi / 180
+
+	y := math.Sin(deltaLonRad) * math.Cos(toLatRad)
+	x := math.Cos(fromLatRad)*math.Sin(toLatRad) - math.Sin(fromLatRad)*math.Cos(toLatRad)*math.Cos(deltaLonRad)
+
+	bearing := math.Atan2(y, x) * 180 / math.Pi
+
+	return math.Mod(bearing+360, 360)
+}
+
+// HorizontalDistance returns the distance in miles between two positions along the surface of the earth
+func HorizontalDistance(fromLat, fromLon, toLat, toLon float64) (float64, error) {
+	distanceMiles, _, err := geodist.VincentyDistance(geodist.Coord{Lat: fromLat, Lon: fromLon},
+		geodist.Coord{Lat: toLat, Lon: toLon})
+	if err != nil {
+		return 0, fmt.Errorf("error calculating distance: %w", err)
+	}
+
+	return distanceMiles, nil
+}
+
+const (
+	feetPerMile       = 5280.0
+	earthRadiusMiles  = 3958.8
+	compassPointWidth = 360.0 / 16
+)
+
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// RelativePosition is where an aircraft is as seen from the station
+type RelativePosition struct {
+	// Distance is along the surface of the earth in miles
+	Distance float64
+	// SlantRange is the straight line distance in miles, the same as Distance if the altitude isn't known
+	SlantRange float64
+	// Bearing is the initial bearing from the station in degrees, 0 being north
+	Bearing float64
+	// Compass is Bearing as one of the 16 points of the compass
+	Compass string
+	// Elevation is the angle above the horizon in degrees, allowing for the curvature of the earth
+	Elevation float64
+	// HasElevation is false when the aircraft's altitude isn't known
+	HasElevation bool
+}
+
+// Relative works out where a position is as seen from the station. altFeet is only used if hasAlt is true.
+func (s Station) Relative(lat, lon, altFeet float64, hasAlt bool) (RelativePosition, error) {
+	distance, err := HorizontalDistance(s.Latitude, s.Longitude, lat, lon)
+	if err != nil {
+		return RelativePosition{}, err
+	}
+
+	bearing := Bearing(s.Latitude, s.Longitude, lat, lon)
+
+	relative := RelativePosition{
+		Distance:   distance,
+		SlantRange: distance,
+		Bearing:    bearing,
+		Compass:    CompassPoint(bearing),
+	}
+
+	if hasAlt {
+		relative.SlantRange = threeDDistance(distance, s.Altitude/feetPerMile, altFeet/feetPerMile)
+		relative.Elevation = ElevationAngle(distance, s.Altitude, altFeet)
+		relative.HasElevation = true
+	}
+
+	return relative, nil
+}
+
+// RelativeTo works out where an aircraft is as seen from the station, using its barometric altitude if it has one. It
+// returns ErrNoPosition if the aircraft has no recent position.
+func (s Station) RelativeTo(aircraft Aircraft) (RelativePosition, error) {
+	position, err := s.locate(aircraft)
+	if err != nil {
+		return RelativePosition{}, err
+	}
+
+	altFeet, hasAlt := aircraft.Altitude.(float64)
+
+	return s.Relative(position.Latitude, position.Longitude, altFeet, hasAlt)
+}
+
+// CompassPoint converts a bearing in degrees to one of the 16 points of the compass
+func CompassPoint(bearing float64) string {
+	bearing = math.Mod(math.Mod(bearing, 360)+360, 360)
+
+	return compassPoints[int(math.Round(bearing/compassPointWidth))%len(compassPoints)]
+}
+
+// ElevationAngle returns the angle in degrees above the horizon of an object horizontalMiles away, allowing for the
+// curvature of the earth. Altitudes are in feet.
+func ElevationAngle(horizontalMiles, myAltFeet, objectAltFeet float64) float64 {
+	heightMiles := (objectAltFeet - myAltFeet) / feetPerMile
+
+	if horizontalMiles == 0 {
+		if heightMiles < 0 {
+			return -90
+		}
+
+		return 90
+	}
+
+	// the earth curves away from the horizon by roughly d²/2R
+	return math.Atan(heightMiles/horizontalMiles-horizontalMiles/(2*earthRadiusMiles)) * 180 / math.Pi
+}
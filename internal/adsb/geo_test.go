@@ -0,0 +1,178 @@
+package adsb
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestBearing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fromLon  float64
+		lat, lon float64
+		want     float64
+	}{
+		{"north", 0, 1, 0, 0},
+		{"east", 0, 0, 1, 90},
+		{"south", 0, -1, 0, 180},
+		{"west", 0, 0, -1, 270},
+		// a degree of longitude at a degree north is slightly shorter than a degree of latitude
+		{"northeast", 0, 1, 1, 44.9956},
+		{"southeast", 0, -1, 1, 135.0044},
+		{"southwest", 0, -1, -1, 224.9956},
+		{"northwest", 0, 1, -1, 315.0044},
+		// just west of north wraps around to just under 360 rather than going negative
+		{"just west of north", 0, 1, -0.0001, 359.9943},
+		{"east across the date line", 179, 0, -179, 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Bearing(0, tt.fromLon, tt.lat, tt.lon)
+			if math.Abs(got-tt.want) > 0.0001 || got < 0 || got >= 360 {
+				t.Errorf("got %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompassPoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		bearing float64
+		want    string
+	}{
+		{0, "N"},
+		{360, "N"},
+		{720, "N"},
+		{-10, "N"},
+		{11.24, "N"},
+		{11.25, "NNE"},
+		{45, "NE"},
+		{90, "E"},
+		{135, "SE"},
+		{180, "S"},
+		{202.5, "SSW"},
+		{225, "SW"},
+		{270, "W"},
+		{-90, "W"},
+		{315, "NW"},
+		{337.5, "NNW"},
+		// the boundary between NNW and N
+		{348.74, "NNW"},
+		{348.75, "N"},
+		{359.9, "N"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.bearing), func(t *testing.T) {
+			t.Parallel()
+
+			if got := CompassPoint(tt.bearing); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestElevationAngle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		horizontal       float64
+		myAlt, objectAlt float64
+		want             float64
+	}{
+		{"overhead", 0, 0, 1000, 90},
+		{"straight down", 0, 1000, 0, -90},
+		// a mile away and a mile up is 45 degrees less a little for the curve of the earth
+		{"a mile up and a mile away", 1, 0, feetPerMile, 44.9964},
+		{"from a mile up", 1, feetPerMile, 2 * feetPerMile, 44.9964},
+		{"a mile down and a mile away", 1, feetPerMile, 0, -45.0036},
+		// at the same height the earth curving away puts it below the horizon
+		{"level and far away", 100, 1000, 1000, -0.7236},
+		{"low and far away", 10, 0, 1000, 1.0127},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ElevationAngle(tt.horizontal, tt.myAlt, tt.objectAlt)
+			if math.Abs(got-tt.want) > 0.0001 {
+				t.Errorf("got %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelative(t *testing.T) {
+	t.Parallel()
+
+	station := Station{Latitude: 51.5, Longitude: -0.1, Altitude: 100}
+
+	tests := []struct {
+		name         string
+		lat, lon     float64
+		altFeet      float64
+		hasAlt       bool
+		wantDistance float64
+		wantBearing  float64
+		wantCompass  string
+	}{
+		// a tenth of a degree of latitude is about 6.9 miles
+		{"north without altitude", 51.6, -0.1, 0, false, 6.9133, 0, "N"},
+		{"north at altitude", 51.6, -0.1, 36000, true, 6.9133, 0, "N"},
+		{"south below the station", 51.4, -0.1, 0, true, 6.9132, 180, "S"},
+		{"east", 51.5, 0.1, 10000, true, 8.6297, 89.92, "E"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := station.Relative(tt.lat, tt.lon, tt.altFeet, tt.hasAlt)
+			if err != nil {
+				t.Fatalf("error working out relative position: %s", err)
+			}
+
+			if math.Abs(got.Distance-tt.wantDistance) > 0.0001 {
+				t.Errorf("got distance %.4f, want %.4f", got.Distance, tt.wantDistance)
+			}
+
+			if math.Abs(got.Bearing-tt.wantBearing) > 0.01 || got.Compass != tt.wantCompass {
+				t.Errorf("got bearing %.2f %s, want %.2f %s", got.Bearing, got.Compass, tt.wantBearing, tt.wantCompass)
+			}
+
+			if got.HasElevation != tt.hasAlt {
+				t.Errorf("got has elevation %t, want %t", got.HasElevation, tt.hasAlt)
+			}
+
+			if !tt.hasAlt {
+				// without an altitude the slant range is the distance over the ground
+				if got.SlantRange != got.Distance || got.Elevation != 0 {
+					t.Errorf("got slant range %.2f and elevation %.2f, want %.2f and 0", got.SlantRange,
+						got.Elevation, got.Distance)
+				}
+
+				return
+			}
+
+			height := (tt.altFeet - station.Altitude) / feetPerMile
+			if want := math.Hypot(got.Distance, height); math.Abs(got.SlantRange-want) > 0.0001 {
+				t.Errorf("got slant range %.4f, want %.4f", got.SlantRange, want)
+			}
+
+			if want := ElevationAngle(got.Distance, station.Altitude, tt.altFeet); got.Elevation != want {
+				t.Errorf("got elevation %.2f, want %.2f", got.Elevation, want)
+			}
+		})
+	}
+}
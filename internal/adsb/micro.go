@@ -89,7 +89,7 @@ func GetMicroConfig(ctx context.Context, host string, timeout time.Duration) (*M
 		return nil, fmt.Errorf("error creating http req: %w", err)
 	}
 
-	res, err = http.DefaultClient.Do(req)
+	res, err = HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making http request: %w", err)
 	}
@@ -0,0 +1,91 @@
+package adsb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNoPosition = errors.New("no recent position")
+
+// DefaultMaxPositionAge is how old a position can be before the aircraft is treated as having no position
+const DefaultMaxPositionAge = 2 * time.Minute
+
+// Position is where an aircraft was when it last sent its position
+type Position struct {
+	Latitude  float64
+	Longitude float64
+	// Age is how long ago the position was received
+	Age time.Duration
+	// Last is true when the aircraft has stopped sending its position and this is the last one it sent
+	Last bool
+}
+
+// Position returns the aircraft's position, or the last position it sent if it isn't sending one any more. ok is
+// false if it hasn't sent one or it's older than maxAge, 0 uses DefaultMaxPositionAge. The position is present
+// whenever seen_pos is, so aircraft on the equator or the prime meridian still have one.
+func (a Aircraft) Position(maxAge time.Duration) (Position, bool) {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxPositionAge
+	}
+
+	var position Position
+
+	switch {
+	case a.SeenPos != nil:
+		position = Position{Latitude: a.Latitude, Longitude: a.Longitude, Age: secondsToDuration(*a.SeenPos)}
+	case a.Last != nil:
+		position = Position{
+			Latitude:  a.Last.Latitude,
+			Longitude: a.Last.Longitude,
+			Age:       secondsToDuration(a.Last.SeenPos),
+			Last:      true,
+		}
+	default:
+		return Position{}, false
+	}
+
+	if position.Age > maxAge {
+		return Position{}, false
+	}
+
+	return position, true
+}
+
+// PositionOf returns the aircraft's position if it's recent enough for the station to use
+func (s Station) PositionOf(aircraft Aircraft) (Position, bool) {
+	return aircraft.Position(s.MaxPositionAge)
+}
+
+// HasPosition reports whether the station can locate the aircraft
+func (s Station) HasPosition(aircraft Aircraft) bool {
+	_, ok := s.PositionOf(aircraft)
+
+	return ok
+}
+
+// CountWithPosition counts the aircraft the station can locate
+func (s Station) CountWithPosition(myADSBData Data) int {
+	var count int
+
+	for _, flight := range myADSBData.Planes {
+		if s.HasPosition(flight) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s Station) locate(aircraft Aircraft) (Position, error) {
+	position, ok := s.PositionOf(aircraft)
+	if !ok {
+		return Position{}, fmt.Errorf("%w: %s", ErrNoPosition, aircraft.Hex)
+	}
+
+	return position, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,112 @@
+package adsb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPosition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		json   string
+		maxAge time.Duration
+		want   Position
+		wantOK bool
+	}{
+		{
+			name:   "current",
+			json:   `{"hex":"a","lat":51.5,"lon":-0.1,"seen_pos":2.5}`,
+			want:   Position{Latitude: 51.5, Longitude: -0.1, Age: 2500 * time.Millisecond},
+			wantOK: true,
+		},
+		{
+			name:   "on the equator and prime meridian",
+			json:   `{"hex":"a","lat":0,"lon":0,"seen_pos":1}`,
+			want:   Position{Age: time.Second},
+			wantOK: true,
+		},
+		{
+			name: "never sent",
+			json: `{"hex":"a","lat":51.5,"lon":-0.1}`,
+		},
+		{
+			name:   "last position",
+			json:   `{"hex":"a","lastPosition":{"lat":52,"lon":1,"seen_pos":30}}`,
+			want:   Position{Latitude: 52, Longitude: 1, Age: 30 * time.Second, Last: true},
+			wantOK: true,
+		},
+		{
+			name:   "current preferred to last",
+			json:   `{"hex":"a","lat":51.5,"lon":-0.1,"seen_pos":1,"lastPosition":{"lat":52,"lon":1,"seen_pos":30}}`,
+			want:   Position{Latitude: 51.5, Longitude: -0.1, Age: time.Second},
+			wantOK: true,
+		},
+		{
+			name: "stale with the default cutoff",
+			json: `{"hex":"a","lat":51.5,"lon":-0.1,"seen_pos":121}`,
+		},
+		{
+			name:   "stale last position",
+			json:   `{"hex":"a","lastPosition":{"lat":52,"lon":1,"seen_pos":61}}`,
+			maxAge: time.Minute,
+		},
+		{
+			name:   "at the cutoff",
+			json:   `{"hex":"a","lat":51.5,"lon":-0.1,"seen_pos":60}`,
+			maxAge: time.Minute,
+			want:   Position{Latitude: 51.5, Longitude: -0.1, Age: time.Minute},
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var aircraft Aircraft
+
+			err := json.Unmarshal([]byte(tt.json), &aircraft)
+			if err != nil {
+				t.Fatalf("error decoding aircraft: %s", err)
+			}
+
+			got, ok := aircraft.Position(tt.maxAge)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("got %+v and %t, want %+v and %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCountWithPosition(t *testing.T) {
+	t.Parallel()
+
+	old := 90.0
+	stale := at("stale", 1, 1)
+	stale.SeenPos = &old
+
+	data := Data{Planes: []Aircraft{
+		at("a", 1, 1),
+		{Hex: "last", Last: &LastPositionData{Latitude: 1, Longitude: 1, SeenPos: 10}},
+		stale,
+		{Hex: "nopos"},
+	}}
+
+	if got := (Station{}).CountWithPosition(data); got != 3 {
+		t.Errorf("got %d with the default cutoff, want 3", got)
+	}
+
+	station := Station{MaxPositionAge: time.Minute}
+	if got := station.CountWithPosition(data); got != 2 {
+		t.Errorf("got %d with a minute cutoff, want 2", got)
+	}
+
+	_, err := station.RelativeTo(stale)
+	if !errors.Is(err, ErrNoPosition) {
+		t.Errorf("got error %v locating a stale aircraft, want %v", err, ErrNoPosition)
+	}
+}
@@ -0,0 +1,153 @@
+package adsb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+var ErrUnknownSortKey = errors.New("unknown sort key")
+
+// SortKey is what a query orders aircraft by
+type SortKey string
+
+const (
+	// SortByDistance orders by slant range, nearest first
+	SortByDistance SortKey = "distance"
+	// SortByAltitude orders by barometric altitude, lowest first, aircraft on the ground or without an altitude last
+	SortByAltitude SortKey = "altitude"
+	// SortBySpeed orders by ground speed, fastest first, aircraft without a speed last
+	SortBySpeed SortKey = "speed"
+	// SortByAge orders by time since the last message, most recent first
+	SortByAge SortKey = "age"
+)
+
+// ParseSortKey checks a sort key read from configuration, an empty key sorts by distance
+func ParseSortKey(key string) (SortKey, error) {
+	switch SortKey(key) {
+	case "":
+		return SortByDistance, nil
+	case SortByDistance, SortByAltitude, SortBySpeed, SortByAge:
+		return SortKey(key), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownSortKey, key)
+	}
+}
+
+// AircraftInfo is an aircraft along with where it is as seen from the station
+type AircraftInfo struct {
+	Aircraft
+	RelativePosition
+}
+
+// Query selects and orders aircraft
+type Query struct {
+	SortBy SortKey
+	// Limit is the most aircraft to return, 0 for all of them
+	Limit int
+	// MaxRange leaves out aircraft with a slant range over this many miles, 0 for no limit
+	MaxRange float64
+}
+
+// Query returns the aircraft with a recent position, annotated with where they are relative to the station and selected
+// and ordered by query
+func (s Station) Query(myADSBData Data, query Query) ([]AircraftInfo, error) {
+	annotated := s.Annotate(myADSBData)
+
+	if query.MaxRange > 0 {
+		annotated = WithinRange(annotated, query.MaxRange)
+	}
+
+	err := SortAircraft(annotated, query.SortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Limit > 0 && len(annotated) > query.Limit {
+		annotated = annotated[:query.Limit]
+	}
+
+	return annotated, nil
+}
+
+// Nearest returns the k aircraft with the shortest slant range, nearest first
+func (s Station) Nearest(myADSBData Data, k int) ([]AircraftInfo, error) {
+	return s.Query(myADSBData, Query{SortBy: SortByDistance, Limit: k})
+}
+
+// Annotate works out where each aircraft with a recent position is relative to the station. An aircraft that can't be
+// located, like one with a bad position that Vincenty's formula doesn't converge for, is logged and left out rather
+// than failing the rest.
+func (s Station) Annotate(myADSBData Data) []AircraftInfo {
+	annotated := make([]AircraftInfo, 0, len(myADSBData.Planes))
+
+	for _, flight := range myADSBData.Planes {
+		if !s.HasPosition(flight) {
+			continue
+		}
+
+		relative, err := s.RelativeTo(flight)
+		if err != nil {
+			fmt.Printf("error locating %s: %s\n", flight.Hex, err)
+
+			continue
+		}
+
+		annotated = append(annotated, AircraftInfo{Aircraft: flight, RelativePosition: relative})
+	}
+
+	return annotated
+}
+
+// WithinRange returns the aircraft with a slant range of at most maxRange miles
+func WithinRange(aircraft []AircraftInfo, maxRange float64) []AircraftInfo {
+	return slices.DeleteFunc(aircraft, func(info AircraftInfo) bool {
+		return info.SlantRange > maxRange
+	})
+}
+
+// SortAircraft orders aircraft in place by key, keeping the order of aircraft that compare equal
+func SortAircraft(aircraft []AircraftInfo, key SortKey) error {
+	var value func(info AircraftInfo) float64
+
+	switch key {
+	case SortByDistance, "":
+		value = func(info AircraftInfo) float64 { return info.SlantRange }
+	case SortByAltitude:
+		value = func(info AircraftInfo) float64 {
+			if altitude, ok := info.Altitude.(float64); ok {
+				return altitude
+			}
+
+			return math.Inf(1)
+		}
+	case SortBySpeed:
+		value = func(info AircraftInfo) float64 {
+			if info.GroundSpeed != nil {
+				return -*info.GroundSpeed
+			}
+
+			return math.Inf(1)
+		}
+	case SortByAge:
+		value = func(info AircraftInfo) float64 { return info.Seen }
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSortKey, key)
+	}
+
+	slices.SortStableFunc(aircraft, func(a, b AircraftInfo) int {
+		valueA, valueB := value(a), value(b)
+
+		switch {
+		case valueA < valueB:
+			return -1
+		case valueA > valueB:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return nil
+}
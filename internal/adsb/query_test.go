@@ -0,0 +1,78 @@
+package adsb
+
+import (
+	"slices"
+	"testing"
+)
+
+// at is an aircraft with a position seen a second ago
+func at(hex string, latitude, longitude float64) Aircraft {
+	seen := 1.0
+
+	return Aircraft{Hex: hex, Latitude: latitude, Longitude: longitude, SeenPos: &seen, Altitude: 10000.0}
+}
+
+func hexes(aircraft []AircraftInfo) []string {
+	result := make([]string, 0, len(aircraft))
+
+	for _, info := range aircraft {
+		result = append(result, info.Hex)
+	}
+
+	return result
+}
+
+func TestAnnotate(t *testing.T) {
+	t.Parallel()
+
+	station := Station{Latitude: 0, Longitude: 0}
+	data := Data{Planes: []Aircraft{
+		at("near", 0.1, 0.1),
+		// Vincenty's formula doesn't converge for points on opposite sides of the earth
+		at("antipode", 0, 180),
+		{Hex: "nopos"},
+		at("far", 1, 1),
+	}}
+
+	got := hexes(station.Annotate(data))
+	if want := []string{"near", "far"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	station := Station{Latitude: 51.5, Longitude: -0.1}
+	data := Data{Planes: []Aircraft{
+		at("c", 51.9, -0.1),
+		at("a", 51.6, -0.1),
+		at("b", 51.7, -0.1),
+		at("antipode", -51.5, 179.9),
+	}}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"nearest first", Query{}, []string{"a", "b", "c"}},
+		{"limit", Query{Limit: 2}, []string{"a", "b"}},
+		{"max range", Query{MaxRange: 20}, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			aircraft, err := station.Query(data, tt.query)
+			if err != nil {
+				t.Fatalf("error querying: %s", err)
+			}
+
+			if got := hexes(aircraft); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
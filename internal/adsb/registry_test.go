@@ -0,0 +1,77 @@
+package adsb
+
+import "testing"
+
+func TestLookupRegistry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		hex  string
+		want Registry
+	}{
+		{"a1b2c3", Registry{Country: "United States", CountryCode: "US"}},
+		{"A1B2C3", Registry{Country: "United States", CountryCode: "US"}},
+		{" a1b2c3 ", Registry{Country: "United States", CountryCode: "US"}},
+		// the first and last addresses of a block are in it
+		{"a00000", Registry{Country: "United States", CountryCode: "US"}},
+		{"afffff", Registry{Country: "United States", CountryCode: "US", Military: true}},
+		{"adf7c8", Registry{Country: "United States", CountryCode: "US", Military: true}},
+		{"adf7c7", Registry{Country: "United States", CountryCode: "US"}},
+		{"400123", Registry{Country: "United Kingdom", CountryCode: "GB"}},
+		{"43c123", Registry{Country: "United Kingdom", CountryCode: "GB", Military: true}},
+		{"3c6444", Registry{Country: "Germany", CountryCode: "DE"}},
+		// Hong Kong is inside China's block
+		{"789123", Registry{Country: "Hong Kong", CountryCode: "HK"}},
+		{"780123", Registry{Country: "China", CountryCode: "CN"}},
+		{"7cf900", Registry{Country: "Australia", CountryCode: "AU", Military: true}},
+		{"e94fff", Registry{Country: "Bolivia", CountryCode: "BO"}},
+		// between blocks
+		{"e95000", Registry{}},
+		{"000000", Registry{}},
+		{"~a1b2c3", Registry{NonICAO: true}},
+		{"1000000", Registry{NonICAO: true}},
+		{"", Registry{NonICAO: true}},
+		{"xyz", Registry{NonICAO: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			t.Parallel()
+
+			if got := (Aircraft{Hex: tt.hex}).Registry(); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAddressBlocks checks that the blocks are well formed and only overlap where a block is carved out of another
+// listed after it
+func TestAddressBlocks(t *testing.T) {
+	t.Parallel()
+
+	for i, block := range countryBlocks {
+		if block.start > block.end || block.end > 0xFFFFFF || block.country == "" || len(block.countryCode) != 2 {
+			t.Errorf("%s: bad block %06X-%06X", block.country, block.start, block.end)
+		}
+
+		for _, other := range countryBlocks[:i] {
+			if block.start <= other.end && other.start <= block.end &&
+				(other.start < block.start || other.end > block.end) {
+				t.Errorf("%s overlaps %s without containing it", block.country, other.country)
+			}
+		}
+	}
+
+	countries := make(map[string]bool)
+
+	for _, block := range countryBlocks {
+		countries[block.countryCode] = true
+	}
+
+	for _, block := range militaryBlocks {
+		if block.start > block.end || !countries[block.countryCode] {
+			t.Errorf("bad military block %06X-%06X for %q", block.start, block.end, block.countryCode)
+		}
+	}
+}
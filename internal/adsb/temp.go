@@ -37,7 +37,7 @@ func GetCPUTempC(ctx context.Context, host string, timeout time.Duration) (int,
 		return 0, fmt.Errorf("error creating http req: %w", err)
 	}
 
-	res, err = http.DefaultClient.Do(req)
+	res, err = HTTPClient.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("error making http request: %w", err)
 	}
@@ -41,7 +41,7 @@ func GetUpdateAvailable(ctx context.Context, host string, timeout time.Duration)
 		return false, fmt.Errorf("error creating http req: %w", err)
 	}
 
-	res, err = http.DefaultClient.Do(req)
+	res, err = HTTPClient.Do(req)
 	if err != nil {
 		return false, fmt.Errorf("error making http request: %w", err)
 	}
@@ -0,0 +1,369 @@
+package aircraftdb
+
+import (
+	"bufio"
+	"cmp"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+var ErrNoHexColumn = errors.New("no ICAO hex column")
+
+// Flags are the tar1090-db dbFlags bits
+type Flags uint8
+
+// Military reports whether the aircraft is flagged as military
+func (f Flags) Military() bool {
+	return f&FlagMilitary != 0
+}
+
+const (
+	FlagMilitary Flags = 1 << iota
+	FlagInteresting
+	FlagPIA
+	FlagLADD
+)
+
+// Record is what the database knows about an aircraft
+type Record struct {
+	Registration string
+	TypeCode     string
+	Description  string
+	Operator     string
+	Flags        Flags
+}
+
+// record is a Record with the often repeated strings stored once in the table's string list
+type record struct {
+	registration string
+	typeCode     uint32
+	description  uint32
+	operator     uint32
+	flags        Flags
+}
+
+// table is a loaded database, keyed by the 24 bit ICAO address
+type table struct {
+	records map[uint32]record
+	strings []string
+}
+
+// tableBuilder dedupes strings while a table is loaded
+type tableBuilder struct {
+	table
+
+	index map[string]uint32
+}
+
+// columns are where each field is in a row, -1 if the file doesn't have it
+type columns struct {
+	hex, registration, typeCode, description, operator, flags int
+	// flagBits is true if the flags are a string of 0s and 1s rather than a number
+	flagBits bool
+}
+
+// tar1090Columns are the columns of the headerless, semicolon separated tar1090-db aircraft.csv
+var tar1090Columns = columns{
+	hex: 0, registration: 1, typeCode: 2, flags: 3, description: 4, operator: 6, flagBits: true,
+}
+
+// headerNames are the header names recognised in CSV files with a header, like a BaseStation.sqb export where Type
+// is the model
+var headerNames = map[string]func(*columns, int){
+	"icao":             func(c *columns, i int) { c.hex = i },
+	"icao24":           func(c *columns, i int) { c.hex = i },
+	"hex":              func(c *columns, i int) { c.hex = i },
+	"modes":            func(c *columns, i int) { c.hex = i },
+	"registration":     func(c *columns, i int) { c.registration = i },
+	"reg":              func(c *columns, i int) { c.registration = i },
+	"typecode":         func(c *columns, i int) { c.typeCode = i },
+	"icaotypecode":     func(c *columns, i int) { c.typeCode = i },
+	"type":             func(c *columns, i int) { c.description = i },
+	"description":      func(c *columns, i int) { c.description = i },
+	"desc":             func(c *columns, i int) { c.description = i },
+	"model":            func(c *columns, i int) { c.description = i },
+	"operator":         func(c *columns, i int) { c.operator = i },
+	"owner":            func(c *columns, i int) { c.operator = i },
+	"ownop":            func(c *columns, i int) { c.operator = i },
+	"registeredowners": func(c *columns, i int) { c.operator = i },
+	"flags":            func(c *columns, i int) { c.flags = i },
+	"dbflags":          func(c *columns, i int) { c.flags = i },
+}
+
+// Database looks aircraft up in a CSV file, reloading it when it changes
+type Database struct {
+	mu sync.RWMutex
+
+	path    string
+	table   *table
+	modTime time.Time
+}
+
+// Open loads the database at path. Files ending in .gz are decompressed. A file whose first line has a hex, icao,
+// icao24 or modes column is read as CSV with a header, otherwise it's read as the tar1090-db aircraft.csv. An empty
+// path gives an empty database.
+func Open(path string) (*Database, error) {
+	db := &Database{path: path, table: &table{strings: []string{""}}}
+
+	if path == "" {
+		return db, nil
+	}
+
+	_, err := db.ReloadIfChanged()
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// ReloadIfChanged loads the file again if it has been modified since it was last loaded, the database in use is kept
+// if loading fails
+func (d *Database) ReloadIfChanged() (bool, error) {
+	if d.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return false, fmt.Errorf("error checking aircraft database: %w", err)
+	}
+
+	d.mu.RLock()
+	unchanged := info.ModTime().Equal(d.modTime)
+	d.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	loaded, err := load(d.path)
+	if err != nil {
+		return false, err
+	}
+
+	d.mu.Lock()
+	d.table = loaded
+	d.modTime = info.ModTime()
+	d.mu.Unlock()
+
+	return true, nil
+}
+
+// Len returns the number of aircraft in the database
+func (d *Database) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return len(d.table.records)
+}
+
+// Lookup finds an aircraft by its ICAO hex address
+func (d *Database) Lookup(hex string) (Record, bool) {
+	key, ok := parseHex(hex)
+	if !ok {
+		return Record{}, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	found, ok := d.table.records[key]
+	if !ok {
+		return Record{}, false
+	}
+
+	return Record{
+		Registration: found.registration,
+		TypeCode:     d.table.strings[found.typeCode],
+		Description:  d.table.strings[found.description],
+		Operator:     d.table.strings[found.operator],
+		Flags:        found.flags,
+	}, true
+}
+
+// Enrich fills in the registration, type, description, operator and flags of each aircraft that readsb didn't send
+func (d *Database) Enrich(myADSBData *adsb.Data) {
+	for i := range myADSBData.Planes {
+		flight := &myADSBData.Planes[i]
+
+		found, ok := d.Lookup(flight.Hex)
+		if !ok {
+			continue
+		}
+
+		flight.Registration = cmp.Or(flight.Registration, found.Registration)
+		flight.TypeCode = cmp.Or(flight.TypeCode, found.TypeCode)
+		flight.Description = cmp.Or(flight.Description, found.Description)
+		flight.Operator = cmp.Or(flight.Operator, found.Operator)
+		flight.DBFlags = cmp.Or(flight.DBFlags, int(found.Flags))
+	}
+}
+
+func load(path string) (*table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening aircraft database: %w", err)
+	}
+
+	defer file.Close()
+
+	var reader io.Reader = file
+
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing aircraft database: %w", err)
+		}
+
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	loaded, err := parse(bufio.NewReader(reader))
+	if err != nil {
+		return nil, fmt.Errorf("error loading aircraft database %s: %w", path, err)
+	}
+
+	return loaded, nil
+}
+
+func parse(reader *bufio.Reader) (*table, error) {
+	// a comma in the first line means a CSV file, otherwise it's semicolon separated like tar1090-db
+	firstLine, err := reader.Peek(reader.Size())
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error reading: %w", err)
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	csvReader.ReuseRecord = true
+
+	if !strings.Contains(strings.SplitN(string(firstLine), "\n", 2)[0], ",") {
+		csvReader.Comma = ';'
+	}
+
+	builder := &tableBuilder{
+		table: table{records: make(map[uint32]record), strings: []string{""}},
+		index: map[string]uint32{"": 0},
+	}
+
+	cols := tar1090Columns
+
+	for row := 0; ; row++ {
+		fields, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading: %w", err)
+		}
+
+		if row == 0 {
+			if header, ok := parseHeader(fields); ok {
+				cols = header
+
+				continue
+			}
+		}
+
+		builder.add(cols, fields)
+	}
+
+	return &builder.table, nil
+}
+
+// parseHeader works out the columns from a header row, ok is false if the row isn't a header
+func parseHeader(fields []string) (columns, bool) {
+	cols := columns{hex: -1, registration: -1, typeCode: -1, description: -1, operator: -1, flags: -1}
+
+	for i, field := range fields {
+		if set, ok := headerNames[strings.ToLower(strings.TrimSpace(field))]; ok {
+			set(&cols, i)
+		}
+	}
+
+	return cols, cols.hex >= 0
+}
+
+func (b *tableBuilder) add(cols columns, fields []string) {
+	field := func(index int) string {
+		if index < 0 || index >= len(fields) {
+			return ""
+		}
+
+		return strings.TrimSpace(fields[index])
+	}
+
+	key, ok := parseHex(field(cols.hex))
+	if !ok {
+		return
+	}
+
+	b.records[key] = record{
+		registration: field(cols.registration),
+		typeCode:     b.intern(field(cols.typeCode)),
+		description:  b.intern(field(cols.description)),
+		operator:     b.intern(field(cols.operator)),
+		flags:        parseFlags(field(cols.flags), cols.flagBits),
+	}
+}
+
+func (b *tableBuilder) intern(value string) uint32 {
+	if index, ok := b.index[value]; ok {
+		return index
+	}
+
+	index := uint32(len(b.strings)) //nolint:gosec
+	b.strings = append(b.strings, value)
+	b.index[value] = index
+
+	return index
+}
+
+// parseFlags reads flags, with bits a string of 0s and 1s with the military flag first as in tar1090-db, otherwise a
+// number
+func parseFlags(value string, bits bool) Flags {
+	if !bits {
+		flags, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return 0
+		}
+
+		return Flags(flags)
+	}
+
+	var flags Flags
+
+	for i, flag := range value {
+		if flag == '1' && i < 8 {
+			flags |= 1 << i
+		}
+	}
+
+	return flags
+}
+
+// parseHex converts an ICAO hex address to a number, ok is false for anything else like the ~ prefixed addresses of
+// TIS-B targets without one
+func parseHex(hex string) (uint32, bool) {
+	value, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 24)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint32(value), true
+}
@@ -0,0 +1,255 @@
+package aircraftdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+// tar1090 is in the layout of the tar1090-db aircraft.csv
+const tar1090 = `A1B2C3;N12345;B738;0000;BOEING 737-800;;Southwest Airlines
+AE1234;;C17;1000;BOEING C-17 Globemaster III;;United States Air Force
+400ABC;G-ABCD;A320;0010;AIRBUS A-320;;Somebody's "Airline"
+~123456;;;;TIS-B without an address;;
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+		want map[string]Record
+	}{
+		{
+			name: "tar1090-db",
+			data: tar1090,
+			want: map[string]Record{
+				"a1b2c3": {"N12345", "B738", "BOEING 737-800", "Southwest Airlines", 0},
+				"AE1234": {"", "C17", "BOEING C-17 Globemaster III", "United States Air Force", FlagMilitary},
+				"400abc": {"G-ABCD", "A320", "AIRBUS A-320", `Somebody's "Airline"`, FlagPIA},
+			},
+		},
+		{
+			name: "header",
+			data: "ModeS,Registration,ICAOTypeCode,Type,RegisteredOwners,Flags\n" +
+				"A1B2C3, N12345 ,B738,Boeing 737-8H4,\"Southwest Airlines, Inc\",\n" +
+				"ae1234,,C17,C-17A,USAF,1\n" +
+				"400abc,G-ABCD,A320,A320-214,Somebody,10\n",
+			want: map[string]Record{
+				"a1b2c3": {"N12345", "B738", "Boeing 737-8H4", "Southwest Airlines, Inc", 0},
+				"ae1234": {"", "C17", "C-17A", "USAF", FlagMilitary},
+				// flags in a file with a header are a number, not bits as in tar1090-db
+				"400abc": {"G-ABCD", "A320", "A320-214", "Somebody", FlagInteresting | FlagLADD},
+			},
+		},
+		{
+			name: "header in another order",
+			data: "reg,desc,icao24,ownop\nN12345,Boeing 737-8H4,a1b2c3,Southwest\n",
+			want: map[string]Record{
+				"a1b2c3": {Registration: "N12345", Description: "Boeing 737-8H4", Operator: "Southwest"},
+			},
+		},
+		{
+			name: "short rows",
+			data: "A1B2C3;N12345\nAE1234\n",
+			want: map[string]Record{
+				"a1b2c3": {Registration: "N12345"},
+				"ae1234": {},
+			},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: map[string]Record{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			loaded, err := parse(bufio.NewReader(strings.NewReader(tt.data)))
+			if err != nil {
+				t.Fatalf("error parsing: %s", err)
+			}
+
+			db := &Database{table: loaded}
+
+			if db.Len() != len(tt.want) {
+				t.Errorf("got %d aircraft, want %d", db.Len(), len(tt.want))
+			}
+
+			for hex, want := range tt.want {
+				got, ok := db.Lookup(hex)
+				if !ok || got != want {
+					t.Errorf("%s: got %+v and %t, want %+v", hex, got, ok, want)
+				}
+			}
+
+			if _, ok := db.Lookup("~123456"); ok {
+				t.Errorf("got a record for an address that isn't hex")
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value string
+		bits  bool
+		want  Flags
+	}{
+		{"empty bits", "", true, 0},
+		{"no bits", "0000", true, 0},
+		{"military bit", "1000", true, FlagMilitary},
+		{"two bits", "0101", true, FlagInteresting | FlagLADD},
+		{"every bit", "1111", true, FlagMilitary | FlagInteresting | FlagPIA | FlagLADD},
+		{"short bits", "1", true, FlagMilitary},
+		{"empty number", "", false, 0},
+		{"number", "6", false, FlagInteresting | FlagPIA},
+		{"number that looks like bits", "10", false, FlagInteresting | FlagLADD},
+		{"number too big", "1000", false, 0},
+		{"not a number", "bad", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseFlags(tt.value, tt.bits); got != tt.want {
+				t.Errorf("got %04b, want %04b", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "aircraft.csv.gz")
+
+	writeGzip(t, path, tar1090)
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("error opening: %s", err)
+	}
+
+	if db.Len() != 3 {
+		t.Errorf("got %d aircraft, want 3", db.Len())
+	}
+
+	reloaded, err := db.ReloadIfChanged()
+	if err != nil || reloaded {
+		t.Errorf("got reloaded %t and error %v for an unchanged file, want neither", reloaded, err)
+	}
+
+	writeGzip(t, path, "A1B2C3;N99999\n")
+
+	// make sure the modification time changes however coarse the filesystem's clock is
+	err = os.Chtimes(path, time.Time{}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("error touching database: %s", err)
+	}
+
+	reloaded, err = db.ReloadIfChanged()
+	if err != nil || !reloaded {
+		t.Errorf("got reloaded %t and error %v for a changed file, want a reload", reloaded, err)
+	}
+
+	if found, _ := db.Lookup("a1b2c3"); found.Registration != "N99999" || db.Len() != 1 {
+		t.Errorf("got %+v of %d aircraft after reloading, want N99999 alone", found, db.Len())
+	}
+
+	err = os.WriteFile(path, []byte("not gzip"), 0o600)
+	if err != nil {
+		t.Fatalf("error writing database: %s", err)
+	}
+
+	err = os.Chtimes(path, time.Time{}, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("error touching database: %s", err)
+	}
+
+	_, err = db.ReloadIfChanged()
+	if err == nil {
+		t.Errorf("got no error reloading a corrupt file")
+	}
+
+	if db.Len() != 1 {
+		t.Errorf("got %d aircraft after a failed reload, want the 1 loaded before", db.Len())
+	}
+}
+
+func writeGzip(t *testing.T, path string, data string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("error creating %s: %s", path, err)
+	}
+
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+
+	_, err = writer.Write([]byte(data))
+	if err != nil {
+		t.Fatalf("error writing %s: %s", path, err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("error writing %s: %s", path, err)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	t.Parallel()
+
+	loaded, err := parse(bufio.NewReader(strings.NewReader(tar1090)))
+	if err != nil {
+		t.Fatalf("error parsing: %s", err)
+	}
+
+	db := &Database{table: loaded}
+	data := adsb.Data{Planes: []adsb.Aircraft{
+		{Hex: "a1b2c3"},
+		// what readsb sends is kept
+		{Hex: "ae1234", Registration: "05-5140", TypeCode: "C17"},
+		{Hex: "ffffff"},
+	}}
+
+	db.Enrich(&data)
+
+	want := []adsb.Aircraft{
+		{
+			Hex: "a1b2c3", Registration: "N12345", TypeCode: "B738", Description: "BOEING 737-800",
+			Operator: "Southwest Airlines",
+		},
+		{
+			Hex: "ae1234", Registration: "05-5140", TypeCode: "C17", Description: "BOEING C-17 Globemaster III",
+			Operator: "United States Air Force", DBFlags: int(FlagMilitary),
+		},
+		{Hex: "ffffff"},
+	}
+
+	for i, got := range data.Planes {
+		if got.Registration != want[i].Registration || got.TypeCode != want[i].TypeCode ||
+			got.Description != want[i].Description || got.Operator != want[i].Operator ||
+			got.DBFlags != want[i].DBFlags {
+			t.Errorf("%s: got %+v, want %+v", got.Hex, got, want[i])
+		}
+	}
+}
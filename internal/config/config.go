@@ -0,0 +1,387 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+var (
+	ErrBadDuration = errors.New("duration should be a string like \"10s\" or a number of seconds")
+	ErrBadValue    = errors.New("bad value")
+)
+
+// Config is the optional configuration file named by LUMAADSB_CONFIG
+type Config struct {
+	Pages []PageConfig `json:"pages,omitempty"`
+
+	// Closest chooses how the closest aircraft is picked, "distance" for the one nearest now or "approach" for the one
+	// predicted to pass nearest within ApproachHorizon
+	Closest         string   `json:"closest,omitempty"`
+	ApproachHorizon Duration `json:"approach_horizon,omitzero"`
+
+	// PositionMaxAge is how old a position, including the last position of an aircraft that has stopped sending one,
+	// can be before the aircraft is counted as having no position
+	PositionMaxAge Duration `json:"position_max_age,omitzero"`
+
+	// Filter selects the aircraft that are counted and shown on every page
+	Filter adsb.Filter `json:"filter,omitzero"`
+
+	// Zones is the path of a GeoJSON file of zones to watch aircraft entering and leaving
+	Zones string `json:"zones,omitempty"`
+	// Watchlist is the path of a JSON file of aircraft to watch for
+	Watchlist string `json:"watchlist,omitempty"`
+
+	// AircraftDB is the path of a CSV aircraft database used to fill in the registration and type of aircraft, it's
+	// checked for changes every AircraftDBReload
+	AircraftDB       string   `json:"aircraft_db,omitempty"`
+	AircraftDBReload Duration `json:"aircraft_db_reload,omitzero"`
+
+	// Airlines is the path of a CSV file of airlines to add to the built-in ones
+	Airlines string `json:"airlines,omitempty"`
+	// AirlineNames shows the closest aircraft's airline and flight number in place of its callsign
+	AirlineNames bool `json:"airline_names,omitempty"`
+	// RouteAPIURL is where routes are looked up when the route API is turned on in adsb.im
+	RouteAPIURL string `json:"route_api_url,omitempty"`
+
+	// NoticeDuration is how long events like an aircraft entering a zone are shown on the display
+	NoticeDuration Duration `json:"notice_duration,omitzero"`
+	// Alerts controls when events interrupt the pages
+	Alerts AlertConfig `json:"alerts,omitzero"`
+
+	// HTTP is the address the dashboard is served on, like ":8081", empty to not serve it
+	HTTP string `json:"http,omitempty"`
+	// MQTT publishes the state to an MQTT broker for Home Assistant
+	MQTT MQTTConfig `json:"mqtt,omitzero"`
+	// Control says where commands for the display are accepted
+	Control ControlConfig `json:"control,omitzero"`
+	// Notify sends events as push notifications
+	Notify NotifyConfig `json:"notify,omitzero"`
+}
+
+// ControlConfig says where commands like changing the page or acknowledging alerts are accepted
+type ControlConfig struct {
+	// Socket is the path of a Unix socket taking a command on each line, empty for no socket
+	Socket string `json:"socket,omitempty"`
+	// HTTP accepts commands posted to /command on the dashboard's address by anyone sending Token
+	HTTP  bool   `json:"http,omitempty"`
+	Token string `json:"token,omitempty"`
+	// MQTT accepts commands published to the command topic under the MQTT topic
+	MQTT bool `json:"mqtt,omitempty"`
+}
+
+// MQTTConfig says where the state is published
+type MQTTConfig struct {
+	// Broker is the broker's host and port, like "homeassistant.local:1883", empty to not publish
+	Broker   string `json:"broker,omitempty"`
+	TLS      bool   `json:"tls,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// ClientID is the client identifier given to the broker and identifies the device in Home Assistant, it's made
+	// from LUMAADSB_HOST by default
+	ClientID string `json:"client_id,omitempty"`
+	// Topic is what the topics published start with, "luma-adsb/" and the client identifier by default
+	Topic string `json:"topic,omitempty"`
+	// DiscoveryPrefix is where Home Assistant looks for discovery configs
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+	// Interval is how often the state is checked for changes to publish
+	Interval Duration `json:"interval,omitzero"`
+}
+
+// AlertConfig controls when events are shown in place of the pages
+type AlertConfig struct {
+	// MinDuration is how long an alert is shown before a higher priority one can interrupt it
+	MinDuration Duration `json:"min_duration,omitzero"`
+	// Cooldown is how long after an alert is shown before the same thing can raise another
+	Cooldown Duration `json:"cooldown,omitzero"`
+	// RateLimit is the most alerts shown in a minute, critical alerts aside
+	RateLimit int `json:"rate_limit,omitempty"`
+	// Priorities gives the priority of each event type, "low", "normal", "high" or "critical"
+	Priorities map[string]string `json:"priorities,omitempty"`
+}
+
+// NotifyConfig says where events are sent as notifications
+type NotifyConfig struct {
+	Targets []NotifyTarget `json:"targets,omitempty"`
+	// Dedupe is how long after an event is sent before another about the same thing is sent
+	Dedupe Duration `json:"dedupe,omitzero"`
+	// Retries is how many more times a notification is tried when the target can't be reached or has an error, nil
+	// when it isn't set so 0 can turn retrying off
+	Retries *int `json:"retries,omitempty"`
+	// Timeout is how long a target has to answer
+	Timeout Duration `json:"timeout,omitzero"`
+}
+
+// NotifyTarget is somewhere notifications are sent
+type NotifyTarget struct {
+	Name string `json:"name,omitempty"`
+	// Type is "webhook", "ntfy", "gotify" or "slack"
+	Type string `json:"type"`
+	// URL is where the webhook, ntfy topic or Slack webhook is, or the address of the Gotify server
+	URL string `json:"url"`
+	// Token is the ntfy access token or Gotify application token
+	Token string `json:"token,omitempty"`
+	// Headers are added to each request
+	Headers map[string]string `json:"headers,omitempty"`
+	// Events are the event types sent, the watchlist, emergency, feeder, CPU and update events by default
+	Events []string `json:"events,omitempty"`
+	// Title, Message and Body are Go templates evaluated against the event. Body replaces the JSON a webhook sends.
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+	Body    string `json:"body,omitempty"`
+	// QuietHours holds back notifications at night
+	QuietHours QuietHours `json:"quiet_hours,omitzero"`
+}
+
+// QuietHours is a time of day when only the most important notifications are sent
+type QuietHours struct {
+	// Start and End are local times like "22:00", quiet hours can run past midnight
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// MinPriority is the lowest priority still sent during quiet hours, critical by default
+	MinPriority string `json:"min_priority,omitempty"`
+}
+
+const (
+	ClosestByDistance = "distance"
+	ClosestByApproach = "approach"
+
+	defaultApproachHorizon = 10 * time.Minute
+	defaultNoticeDuration  = 5 * time.Second
+	defaultAircraftDBCheck = 10 * time.Minute
+	defaultAlertMinimum    = 2 * time.Second
+	defaultAlertCooldown   = time.Minute
+	defaultAlertRateLimit  = 10
+	defaultDiscoveryPrefix = "homeassistant"
+	defaultMQTTInterval    = 10 * time.Second
+	defaultNotifyDedupe    = 10 * time.Minute
+	defaultNotifyRetries   = 3
+	defaultNotifyTimeout   = 10 * time.Second
+)
+
+// PageConfig describes one page of the display. Pages are shown in turn, each for Duration.
+type PageConfig struct {
+	Name     string         `json:"name,omitempty"`
+	Type     string         `json:"type,omitempty"`
+	Duration Duration       `json:"duration,omitzero"`
+	Widgets  []WidgetConfig `json:"widgets,omitempty"`
+
+	// Range is the radius of a radar page in miles, 0 scales to fit the aircraft. On a nearest page it leaves out
+	// aircraft further away, 0 for no limit.
+	Range float64 `json:"range,omitempty"`
+	// Rings is the number of range rings on a radar page
+	Rings int `json:"rings,omitempty"`
+
+	// Count is the number of aircraft listed on a nearest page, 0 for as many as fit
+	Count int `json:"count,omitempty"`
+	// Sort orders a nearest page by "distance", "altitude", "speed" or "age"
+	Sort string `json:"sort,omitempty"`
+	// Font is the font of the rows of a nearest page
+	Font string `json:"font,omitempty"`
+
+	// Overflow is what lines too wide for the display do, "ellipsis", "scroll" or "shrink". ScrollSpeed is in pixels
+	// a second and ScrollPause is how long scrolling lines wait at each end, widgets use them unless they set their
+	// own.
+	Overflow    string   `json:"overflow,omitempty"`
+	ScrollSpeed float64  `json:"scroll_speed,omitempty"`
+	ScrollPause Duration `json:"scroll_pause,omitzero"`
+
+	// Filter further selects the aircraft shown on this page
+	Filter adsb.Filter `json:"filter,omitzero"`
+}
+
+// WidgetConfig places a single widget on a page. X, Y, Width and Height are in pixels. Text, Value and If are Go
+// templates evaluated against the current display data.
+type WidgetConfig struct {
+	Type     string   `json:"type"`
+	X        int      `json:"x"`
+	Y        int      `json:"y"`
+	Width    int      `json:"width,omitempty"`
+	Height   int      `json:"height,omitempty"`
+	Align    string   `json:"align,omitempty"`
+	Font     string   `json:"font,omitempty"`
+	Text     string   `json:"text,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	If       string   `json:"if,omitempty"`
+	Min      float64  `json:"min,omitempty"`
+	Max      float64  `json:"max,omitempty"`
+	Icon     string   `json:"icon,omitempty"`
+	Interval Duration `json:"interval,omitzero"`
+
+	Overflow    string   `json:"overflow,omitempty"`
+	ScrollSpeed float64  `json:"scroll_speed,omitempty"`
+	ScrollPause Duration `json:"scroll_pause,omitzero"`
+}
+
+// Duration is a time.Duration read from JSON as either a duration string or a number of seconds
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var durationStr string
+
+		err := json.Unmarshal(data, &durationStr)
+		if err != nil {
+			return fmt.Errorf("error unmarshalling duration: %w", err)
+		}
+
+		d.Duration, err = time.ParseDuration(durationStr)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrBadDuration, err)
+		}
+
+		return nil
+	}
+
+	seconds, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBadDuration, data)
+	}
+
+	d.Duration = time.Duration(seconds * float64(time.Second))
+
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(d.String())
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling duration: %w", err)
+	}
+
+	return data, nil
+}
+
+// Load reads the configuration file at path. An empty path gives an empty configuration.
+func Load(path string) (*Config, error) {
+	var cfg Config
+
+	if path == "" {
+		cfg.setDefaults()
+
+		return &cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %w", path, err)
+	}
+
+	cfg.setDefaults()
+
+	err = cfg.validate()
+	if err != nil {
+		return nil, fmt.Errorf("error in config %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) setDefaults() {
+	if c.Closest == "" {
+		c.Closest = ClosestByDistance
+	}
+
+	if c.ApproachHorizon.Duration <= 0 {
+		c.ApproachHorizon.Duration = defaultApproachHorizon
+	}
+
+	if c.NoticeDuration.Duration <= 0 {
+		c.NoticeDuration.Duration = defaultNoticeDuration
+	}
+
+	if c.AircraftDBReload.Duration <= 0 {
+		c.AircraftDBReload.Duration = defaultAircraftDBCheck
+	}
+
+	if c.Alerts.MinDuration.Duration <= 0 {
+		c.Alerts.MinDuration.Duration = defaultAlertMinimum
+	}
+
+	if c.Alerts.Cooldown.Duration <= 0 {
+		c.Alerts.Cooldown.Duration = defaultAlertCooldown
+	}
+
+	if c.Alerts.RateLimit <= 0 {
+		c.Alerts.RateLimit = defaultAlertRateLimit
+	}
+
+	if c.MQTT.DiscoveryPrefix == "" {
+		c.MQTT.DiscoveryPrefix = defaultDiscoveryPrefix
+	}
+
+	if c.MQTT.Interval.Duration <= 0 {
+		c.MQTT.Interval.Duration = defaultMQTTInterval
+	}
+
+	c.Notify.setDefaults()
+}
+
+func (n *NotifyConfig) setDefaults() {
+	if n.Dedupe.Duration <= 0 {
+		n.Dedupe.Duration = defaultNotifyDedupe
+	}
+
+	if n.Retries == nil {
+		retries := defaultNotifyRetries
+		n.Retries = &retries
+	}
+
+	if n.Timeout.Duration <= 0 {
+		n.Timeout.Duration = defaultNotifyTimeout
+	}
+}
+
+func (c *Config) validate() error {
+	if c.Closest != ClosestByDistance && c.Closest != ClosestByApproach {
+		return fmt.Errorf("%w: closest should be %q or %q", ErrBadValue, ClosestByDistance, ClosestByApproach)
+	}
+
+	err := c.Filter.Validate()
+	if err != nil {
+		return fmt.Errorf("error in filter: %w", err)
+	}
+
+	for i, page := range c.Pages {
+		err = page.Filter.Validate()
+		if err != nil {
+			return fmt.Errorf("error in filter of page %d: %w", i+1, err)
+		}
+	}
+
+	// the dashboard has no login so without a token any page open in a browser that can reach it could send commands
+	if c.Control.HTTP && c.Control.Token == "" {
+		return fmt.Errorf("%w: control http needs a token", ErrBadValue)
+	}
+
+	// commands can only be taken over HTTP or MQTT when there's a server or broker to take them from
+	if c.Control.HTTP && c.HTTP == "" {
+		return fmt.Errorf("%w: control http needs http to be set", ErrBadValue)
+	}
+
+	if c.Control.MQTT && c.MQTT.Broker == "" {
+		return fmt.Errorf("%w: control mqtt needs an mqtt broker", ErrBadValue)
+	}
+
+	if *c.Notify.Retries < 0 {
+		return fmt.Errorf("%w: notify retries shouldn't be negative", ErrBadValue)
+	}
+
+	return nil
+}
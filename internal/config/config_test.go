@@ -0,0 +1,199 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+func TestDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		data    string
+		want    time.Duration
+		wantErr error
+	}{
+		{`"10s"`, 10 * time.Second, nil},
+		{`"1m30s"`, 90 * time.Second, nil},
+		{`45`, 45 * time.Second, nil},
+		{`0.5`, 500 * time.Millisecond, nil},
+		{`"ten seconds"`, 0, ErrBadDuration},
+		{`true`, 0, ErrBadDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			t.Parallel()
+
+			var got Duration
+
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if got.Duration != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %s and error %v, want %s and %v", got.Duration, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	data, err := json.Marshal(Duration{Duration: 90 * time.Second})
+	if err != nil || string(data) != `"1m30s"` {
+		t.Errorf("got %s and error %v, want \"1m30s\"", data, err)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Parallel()
+
+	empty, err := Load("")
+	if err != nil {
+		t.Fatalf("error loading no config: %s", err)
+	}
+
+	path := writeConfig(t, "{}")
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("error loading empty config: %s", err)
+	}
+
+	for _, cfg := range []*Config{empty, loaded} {
+		if cfg.Closest != ClosestByDistance || cfg.ApproachHorizon.Duration != defaultApproachHorizon ||
+			cfg.NoticeDuration.Duration != defaultNoticeDuration ||
+			cfg.AircraftDBReload.Duration != defaultAircraftDBCheck {
+			t.Errorf("got %+v, want the defaults", cfg)
+		}
+
+		if cfg.Alerts.MinDuration.Duration != defaultAlertMinimum ||
+			cfg.Alerts.Cooldown.Duration != defaultAlertCooldown || cfg.Alerts.RateLimit != defaultAlertRateLimit {
+			t.Errorf("got alerts %+v, want the defaults", cfg.Alerts)
+		}
+
+		if cfg.MQTT.DiscoveryPrefix != defaultDiscoveryPrefix || cfg.MQTT.Interval.Duration != defaultMQTTInterval {
+			t.Errorf("got mqtt %+v, want the defaults", cfg.MQTT)
+		}
+
+		if cfg.Notify.Dedupe.Duration != defaultNotifyDedupe || cfg.Notify.Retries == nil ||
+			*cfg.Notify.Retries != defaultNotifyRetries || cfg.Notify.Timeout.Duration != defaultNotifyTimeout {
+			t.Errorf("got notify %+v, want the defaults", cfg.Notify)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		check   func(cfg *Config) bool
+		wantErr error
+	}{
+		{
+			name: "settings kept",
+			data: `{"closest": "approach", "approach_horizon": "5m", "notice_duration": 8,
+				"alerts": {"rate_limit": 3}, "notify": {"retries": 0}}`,
+			check: func(cfg *Config) bool {
+				return cfg.Closest == ClosestByApproach && cfg.ApproachHorizon.Duration == 5*time.Minute &&
+					cfg.NoticeDuration.Duration == 8*time.Second && cfg.Alerts.RateLimit == 3 &&
+					*cfg.Notify.Retries == 0
+			},
+		},
+		{
+			name: "pages",
+			data: `{"pages": [{"name": "radar", "type": "radar", "duration": "20s", "filter": {"max_range": 25}}]}`,
+			check: func(cfg *Config) bool {
+				return len(cfg.Pages) == 1 && cfg.Pages[0].Duration.Duration == 20*time.Second &&
+					cfg.Pages[0].Filter.MaxRange == 25
+			},
+		},
+		{
+			name: "control",
+			data: `{"http": ":8081", "mqtt": {"broker": "localhost:1883"},
+				"control": {"http": true, "token": "secret", "mqtt": true}}`,
+			check: func(cfg *Config) bool {
+				return cfg.Control.HTTP && cfg.Control.MQTT
+			},
+		},
+		{name: "bad closest", data: `{"closest": "nearest"}`, wantErr: ErrBadValue},
+		{name: "negative retries", data: `{"notify": {"retries": -1}}`, wantErr: ErrBadValue},
+		{name: "bad duration", data: `{"approach_horizon": "soon"}`, wantErr: ErrBadDuration},
+		{name: "bad filter", data: `{"filter": {"ground": "sometimes"}}`, wantErr: adsb.ErrBadFilter},
+		{name: "bad page filter", data: `{"pages": [{"filter": {"hex": ["["]}}]}`, wantErr: adsb.ErrBadFilter},
+		{
+			name:    "control http without a token",
+			data:    `{"http": ":8081", "control": {"http": true}}`,
+			wantErr: ErrBadValue,
+		},
+		{
+			name:    "control http without http",
+			data:    `{"control": {"http": true, "token": "secret"}}`,
+			wantErr: ErrBadValue,
+		},
+		{name: "control mqtt without a broker", data: `{"control": {"mqtt": true}}`, wantErr: ErrBadValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := Load(writeConfig(t, tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.check != nil && !tt.check(cfg) {
+				t.Errorf("got %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		// a misspelt setting is an error rather than being ignored
+		{"unknown field", `{"closets": "approach"}`, "unknown field"},
+		{"unknown nested field", `{"pages": [{"widgets": [{"type": "text", "txt": "hi"}]}]}`, "unknown field"},
+		{"not json", `closest = "approach"`, "error parsing config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Load(writeConfig(t, tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one saying %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got error %v for a missing file, want %v", err, os.ErrNotExist)
+	}
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	err := os.WriteFile(path, []byte(data), 0o600)
+	if err != nil {
+		t.Fatalf("error writing config: %s", err)
+	}
+
+	return path
+}
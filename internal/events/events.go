@@ -0,0 +1,47 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is something that happened that's worth telling someone about, like an aircraft entering a zone
+type Event struct {
+	Time time.Time `json:"time"`
+	// Type identifies the kind of event, like "zone_enter"
+	Type string `json:"type"`
+	// Key identifies what the event is about so repeats can be recognised, like the type, zone and aircraft
+	Key string `json:"key"`
+	// Hex is the ICAO address of the aircraft the event is about, if any
+	Hex string `json:"hex,omitempty"`
+	// Title is short enough to fit on a line of the display
+	Title string `json:"title"`
+	// Message is the full description
+	Message string `json:"message"`
+}
+
+// Bus hands each published event to every subscriber, in the order they subscribed
+type Bus struct {
+	mu sync.RWMutex
+
+	subscribers []func(Event)
+}
+
+// Subscribe calls fn with every event published from now on. fn is called on the publishing goroutine so it should
+// return quickly.
+func (b *Bus) Subscribe(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Publish hands event to every subscriber
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, fn := range b.subscribers {
+		fn(event)
+	}
+}
@@ -0,0 +1,169 @@
+package geofence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/events"
+)
+
+// EventType is what an aircraft did in a zone
+type EventType string
+
+const (
+	Enter EventType = "zone_enter"
+	Exit  EventType = "zone_exit"
+	Dwell EventType = "zone_dwell"
+)
+
+// Event is an aircraft entering, leaving or staying in a zone
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Zone     string
+	Aircraft adsb.Aircraft
+	// Inside is how long the aircraft has been in the zone, for exit and dwell events
+	Inside time.Duration
+}
+
+// Monitor follows which aircraft are in which zones between updates
+type Monitor struct {
+	mu sync.Mutex
+
+	zones  []Zone
+	inside map[presenceKey]*presence
+}
+
+type presenceKey struct {
+	zone int
+	hex  string
+}
+
+type presence struct {
+	aircraft  adsb.Aircraft
+	enteredAt time.Time
+	dwelled   bool
+}
+
+func NewMonitor(zones []Zone) *Monitor {
+	return &Monitor{
+		zones:  zones,
+		inside: make(map[presenceKey]*presence),
+	}
+}
+
+// Update checks where every aircraft is at now and returns the events since the last update. Aircraft without a
+// position recent enough for station are treated as having left any zone they were in. Aircraft that can't be
+// checked keep their previous state and the errors are returned along with the events.
+func (m *Monitor) Update(now time.Time, station adsb.Station, myADSBData adsb.Data) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found []Event
+
+	var errs []error
+
+	seen := make(map[presenceKey]bool)
+
+	for _, flight := range myADSBData.Planes {
+		position, ok := station.PositionOf(flight)
+		if !ok {
+			continue
+		}
+
+		for zoneIndex, zone := range m.zones {
+			key := presenceKey{zone: zoneIndex, hex: flight.Hex}
+
+			inside, err := zone.containsAircraft(position, flight)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error checking %s in %s: %w", flight.Hex, zone.Name, err))
+				seen[key] = m.inside[key] != nil
+
+				continue
+			}
+
+			if !inside {
+				continue
+			}
+
+			seen[key] = true
+
+			found = append(found, m.stillInside(now, key, zone, flight)...)
+		}
+	}
+
+	for key, current := range m.inside {
+		if seen[key] {
+			continue
+		}
+
+		delete(m.inside, key)
+
+		found = append(found, Event{
+			Type:     Exit,
+			Time:     now,
+			Zone:     m.zones[key.zone].Name,
+			Aircraft: current.aircraft,
+			Inside:   now.Sub(current.enteredAt),
+		})
+	}
+
+	return found, errors.Join(errs...)
+}
+
+// stillInside records that the aircraft is in the zone, returning an enter event if it's just arrived or a dwell
+// event if it's now been there long enough
+func (m *Monitor) stillInside(now time.Time, key presenceKey, zone Zone, flight adsb.Aircraft) []Event {
+	current, ok := m.inside[key]
+	if !ok {
+		m.inside[key] = &presence{aircraft: flight, enteredAt: now}
+
+		return []Event{{Type: Enter, Time: now, Zone: zone.Name, Aircraft: flight}}
+	}
+
+	current.aircraft = flight
+
+	if zone.Dwell <= 0 || current.dwelled || now.Sub(current.enteredAt) < zone.Dwell {
+		return nil
+	}
+
+	current.dwelled = true
+
+	return []Event{{Type: Dwell, Time: now, Zone: zone.Name, Aircraft: flight, Inside: now.Sub(current.enteredAt)}}
+}
+
+// Event converts a zone event to one that can be published
+func (e Event) Event() events.Event {
+	name := strings.TrimSpace(e.Aircraft.CallSign)
+	if name == "" {
+		name = e.Aircraft.Hex
+	}
+
+	var title, message string
+
+	switch e.Type {
+	case Enter:
+		title = fmt.Sprintf("%s in %s", name, e.Zone)
+		message = fmt.Sprintf("%s (%s) entered %s", name, e.Aircraft.Hex, e.Zone)
+	case Exit:
+		title = fmt.Sprintf("%s left %s", name, e.Zone)
+		message = fmt.Sprintf("%s (%s) left %s after %s", name, e.Aircraft.Hex, e.Zone, e.Inside.Round(time.Second))
+	case Dwell:
+		title = fmt.Sprintf("%s still in %s", name, e.Zone)
+		message = fmt.Sprintf("%s (%s) has been in %s for %s", name, e.Aircraft.Hex, e.Zone,
+			e.Inside.Round(time.Second))
+	}
+
+	return events.Event{
+		Time:    e.Time,
+		Type:    string(e.Type),
+		Key:     fmt.Sprintf("%s:%s:%s", e.Type, e.Zone, e.Aircraft.Hex),
+		Hex:     e.Aircraft.Hex,
+		Title:   title,
+		Message: message,
+	}
+}
@@ -0,0 +1,64 @@
+package geofence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+func TestMonitorUpdate(t *testing.T) {
+	t.Parallel()
+
+	square := Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}
+	low := 5000.0
+	monitor := NewMonitor([]Zone{
+		{Name: "square", Polygons: []Polygon{square}, Dwell: time.Minute},
+		{Name: "low", Polygons: []Polygon{square}, Altitude: adsb.Filter{MaxAltitude: &low}},
+	})
+
+	aircraft := func(latitude float64, altitude float64) adsb.Aircraft {
+		seen := 1.0
+
+		return adsb.Aircraft{Hex: "a1", Latitude: latitude, Longitude: 0.5, SeenPos: &seen, Altitude: altitude}
+	}
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name   string
+		after  time.Duration
+		planes []adsb.Aircraft
+		want   []EventType
+		zones  []string
+	}{
+		{"outside", 0, []adsb.Aircraft{aircraft(2, 3000)}, nil, nil},
+		{"enters both", 10 * time.Second, []adsb.Aircraft{aircraft(0.5, 3000)}, []EventType{Enter, Enter},
+			[]string{"square", "low"}},
+		{"still inside", 30 * time.Second, []adsb.Aircraft{aircraft(0.5, 3000)}, nil, nil},
+		{"climbs out of the band", 50 * time.Second, []adsb.Aircraft{aircraft(0.5, 8000)}, []EventType{Exit},
+			[]string{"low"}},
+		{"dwells", 70 * time.Second, []adsb.Aircraft{aircraft(0.5, 8000)}, []EventType{Dwell}, []string{"square"}},
+		{"only dwells once", 90 * time.Second, []adsb.Aircraft{aircraft(0.5, 8000)}, nil, nil},
+		// an aircraft that's gone from the data has left
+		{"disappears", 100 * time.Second, nil, []EventType{Exit}, []string{"square"}},
+	}
+
+	for _, step := range steps {
+		found, err := monitor.Update(start.Add(step.after), adsb.Station{}, adsb.Data{Planes: step.planes})
+		if err != nil {
+			t.Fatalf("%s: error updating: %s", step.name, err)
+		}
+
+		if len(found) != len(step.want) {
+			t.Fatalf("%s: got %d events, want %d", step.name, len(found), len(step.want))
+		}
+
+		for i, event := range found {
+			if event.Type != step.want[i] || event.Zone != step.zones[i] {
+				t.Errorf("%s: got %s in %s, want %s in %s", step.name, event.Type, event.Zone, step.want[i],
+					step.zones[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+)
+
+var ErrBadGeoJSON = errors.New("bad GeoJSON zone")
+
+// Polygon is a list of rings of longitude, latitude pairs. The first ring is the outline and any others are holes.
+type Polygon [][][2]float64
+
+// Zone is an area aircraft are watched entering and leaving, either polygons or a circle around a point
+type Zone struct {
+	Name     string
+	Polygons []Polygon
+	// Center and Radius, in miles, describe a circular zone
+	Center *[2]float64
+	Radius float64
+	// Altitude limits the zone to a band of barometric altitudes
+	Altitude adsb.Filter
+	// Dwell is how long an aircraft has to stay in the zone before a dwell event, 0 for no dwell events
+	Dwell time.Duration
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	Geometry   geometry       `json:"geometry"`
+	Properties zoneProperties `json:"properties"`
+}
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// zoneProperties are the properties of a zone feature. Radius is in miles and the altitudes are in feet.
+type zoneProperties struct {
+	Name        string          `json:"name"`
+	Radius      float64         `json:"radius"`
+	MinAltitude *float64        `json:"min_altitude"`
+	MaxAltitude *float64        `json:"max_altitude"`
+	Dwell       config.Duration `json:"dwell"`
+}
+
+// Load reads zones from a GeoJSON file holding a FeatureCollection or a single Feature. Polygon and MultiPolygon
+// features are zones as they are, Point features need a radius property giving the size of the circle in miles.
+func Load(path string) ([]Zone, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading zones: %w", err)
+	}
+
+	var collection featureCollection
+
+	err = json.Unmarshal(data, &collection)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing zones %s: %w", path, err)
+	}
+
+	features := collection.Features
+
+	if collection.Type == "Feature" {
+		var single feature
+
+		err = json.Unmarshal(data, &single)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing zones %s: %w", path, err)
+		}
+
+		features = []feature{single}
+	}
+
+	zones := make([]Zone, 0, len(features))
+
+	for i, zoneFeature := range features {
+		zone, err := zoneFeature.toZone()
+		if err != nil {
+			return nil, fmt.Errorf("error in zone %d of %s: %w", i+1, path, err)
+		}
+
+		if zone.Name == "" {
+			zone.Name = fmt.Sprintf("zone %d", i+1)
+		}
+
+		zones = append(zones, zone)
+	}
+
+	return zones, nil
+}
+
+func (f feature) toZone() (Zone, error) {
+	zone := Zone{
+		Name: f.Properties.Name,
+		Altitude: adsb.Filter{
+			MinAltitude: f.Properties.MinAltitude,
+			MaxAltitude: f.Properties.MaxAltitude,
+		},
+		Dwell: f.Properties.Dwell.Duration,
+	}
+
+	var err error
+
+	switch f.Geometry.Type {
+	case "Polygon":
+		var polygon Polygon
+
+		err = json.Unmarshal(f.Geometry.Coordinates, &polygon)
+		zone.Polygons = []Polygon{polygon}
+	case "MultiPolygon":
+		err = json.Unmarshal(f.Geometry.Coordinates, &zone.Polygons)
+	case "Point":
+		var center [2]float64
+
+		if f.Properties.Radius <= 0 {
+			return Zone{}, fmt.Errorf("%w: a point needs a radius", ErrBadGeoJSON)
+		}
+
+		err = json.Unmarshal(f.Geometry.Coordinates, &center)
+		zone.Center = &center
+		zone.Radius = f.Properties.Radius
+	default:
+		return Zone{}, fmt.Errorf("%w: unsupported geometry %q", ErrBadGeoJSON, f.Geometry.Type)
+	}
+
+	if err != nil {
+		return Zone{}, fmt.Errorf("%w: %w", ErrBadGeoJSON, err)
+	}
+
+	return zone, nil
+}
+
+// Contains reports whether position is inside the zone, ignoring altitude
+func (z Zone) Contains(position adsb.Position) (bool, error) {
+	if z.Center != nil {
+		distance, err := adsb.HorizontalDistance(z.Center[1], z.Center[0], position.Latitude, position.Longitude)
+		if err != nil {
+			return false, err
+		}
+
+		return distance <= z.Radius, nil
+	}
+
+	point := [2]float64{position.Longitude, position.Latitude}
+
+	for _, polygon := range z.Polygons {
+		if polygon.contains(point) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// containsAircraft reports whether the aircraft is inside the zone and within its altitude band
+func (z Zone) containsAircraft(position adsb.Position, aircraft adsb.Aircraft) (bool, error) {
+	inBand, err := z.Altitude.Match(adsb.Station{}, aircraft)
+	if err != nil || !inBand {
+		return false, err
+	}
+
+	return z.Contains(position)
+}
+
+func (p Polygon) contains(point [2]float64) bool {
+	if len(p) == 0 || !ringContains(p[0], point) {
+		return false
+	}
+
+	for _, hole := range p[1:] {
+		if ringContains(hole, point) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ringContains counts how many edges of the ring a ray from point crosses, treating longitude and latitude as flat
+// coordinates which is close enough for zones of a few miles
+func ringContains(ring [][2]float64, point [2]float64) bool {
+	var inside bool
+
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		from, to := ring[j], ring[i]
+
+		if (to[1] > point[1]) != (from[1] > point[1]) &&
+			point[0] < (from[0]-to[0])*(point[1]-to[1])/(from[1]-to[1])+to[0] {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
@@ -0,0 +1,172 @@
+package geofence
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+func TestRingContains(t *testing.T) {
+	t.Parallel()
+
+	square := [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	// an L with its notch in the top right
+	ell := [][2]float64{{0, 0}, {10, 0}, {10, 5}, {5, 5}, {5, 10}, {0, 10}}
+	// the same L drawn the other way round
+	reversed := [][2]float64{{0, 10}, {5, 10}, {5, 5}, {10, 5}, {10, 0}, {0, 0}}
+
+	tests := []struct {
+		name  string
+		ring  [][2]float64
+		point [2]float64
+		want  bool
+	}{
+		{"inside square", square, [2]float64{5, 5}, true},
+		{"left of square", square, [2]float64{-1, 5}, false},
+		{"right of square", square, [2]float64{11, 5}, false},
+		{"above square", square, [2]float64{5, 11}, false},
+		{"level with a vertex", square, [2]float64{5, 0.5}, true},
+		{"inside the L", ell, [2]float64{2, 8}, true},
+		{"in the notch", ell, [2]float64{8, 8}, false},
+		{"inside the reversed L", reversed, [2]float64{8, 2}, true},
+		{"in the reversed notch", reversed, [2]float64{8, 8}, false},
+		{"empty ring", nil, [2]float64{0, 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ringContains(tt.ring, tt.point); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZoneContains(t *testing.T) {
+	t.Parallel()
+
+	outline := [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	hole := [][2]float64{{0.4, 0.4}, {0.6, 0.4}, {0.6, 0.6}, {0.4, 0.6}, {0.4, 0.4}}
+	elsewhere := [][2]float64{{5, 5}, {6, 5}, {6, 6}, {5, 6}, {5, 5}}
+
+	withHole := Zone{Polygons: []Polygon{{outline, hole}, {elsewhere}}}
+	// 0.1 degrees of latitude is 6.9 miles
+	circle := Zone{Center: &[2]float64{0, 0}, Radius: 7}
+
+	tests := []struct {
+		name      string
+		zone      Zone
+		latitude  float64
+		longitude float64
+		want      bool
+	}{
+		{"in the outline", withHole, 0.2, 0.2, true},
+		{"in the hole", withHole, 0.5, 0.5, false},
+		{"in the second polygon", withHole, 5.5, 5.5, true},
+		{"outside", withHole, 2, 2, false},
+		{"in the circle", circle, 0.1, 0, true},
+		{"outside the circle", circle, 0, 0.11, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.zone.Contains(adsb.Position{Latitude: tt.latitude, Longitude: tt.longitude})
+			if err != nil {
+				t.Fatalf("error checking zone: %s", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		geoJSON  string
+		want     []string
+		wantErr  error
+		wantZone func(zones []Zone) bool
+	}{
+		{
+			name: "collection",
+			geoJSON: `{"type":"FeatureCollection","features":[
+				{"type":"Feature","properties":{"name":"field","max_altitude":3000,"dwell":"5m"},
+					"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}},
+				{"type":"Feature","properties":{"radius":2},"geometry":{"type":"Point","coordinates":[-0.1,51.5]}}]}`,
+			want: []string{"field", "zone 2"},
+			wantZone: func(zones []Zone) bool {
+				return *zones[0].Altitude.MaxAltitude == 3000 && zones[0].Dwell == 5*time.Minute &&
+					zones[1].Center[1] == 51.5 && zones[1].Radius == 2
+			},
+		},
+		{
+			name: "single feature",
+			geoJSON: `{"type":"Feature","properties":{"name":"islands"},"geometry":{"type":"MultiPolygon",
+				"coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,5]]]]}}`,
+			want: []string{"islands"},
+			wantZone: func(zones []Zone) bool {
+				return len(zones[0].Polygons) == 2
+			},
+		},
+		{
+			name:    "point without radius",
+			geoJSON: `{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[0,0]}}`,
+			wantErr: ErrBadGeoJSON,
+		},
+		{
+			name:    "line",
+			geoJSON: `{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}}`,
+			wantErr: ErrBadGeoJSON,
+		},
+		{
+			name:    "bad coordinates",
+			geoJSON: `{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[0,0]}}`,
+			wantErr: ErrBadGeoJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "zones.geojson")
+
+			err := os.WriteFile(path, []byte(tt.geoJSON), 0o600)
+			if err != nil {
+				t.Fatalf("error writing zones: %s", err)
+			}
+
+			zones, err := Load(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if len(zones) != len(tt.want) {
+				t.Fatalf("got %d zones, want %d", len(zones), len(tt.want))
+			}
+
+			for i, zone := range zones {
+				if zone.Name != tt.want[i] {
+					t.Errorf("zone %d: got name %q, want %q", i+1, zone.Name, tt.want[i])
+				}
+			}
+
+			if tt.wantZone != nil && !tt.wantZone(zones) {
+				t.Errorf("got zones %+v", zones)
+			}
+		})
+	}
+}
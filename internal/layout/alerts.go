@@ -0,0 +1,355 @@
+package layout
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+var ErrUnknownPriority = errors.New("unknown priority")
+
+// Priority orders alerts, higher priority alerts are shown first and interrupt lower priority ones
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	// PriorityCritical alerts interrupt straight away and aren't subject to cooldowns or rate limits
+	PriorityCritical
+)
+
+var priorityNames = []string{"low", "normal", "high", "critical"}
+
+func (p Priority) String() string {
+	if p < PriorityLow || p > PriorityCritical {
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+
+	return priorityNames[p]
+}
+
+// ParsePriority reads a priority name from configuration
+func ParsePriority(name string) (Priority, error) {
+	index := slices.Index(priorityNames, strings.ToLower(name))
+	if index < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownPriority, name)
+	}
+
+	return Priority(index), nil
+}
+
+// noticeFont is used for the title of an alert, the message is in the default font
+const noticeFont = "8x16bold"
+
+// Alert is shown in place of the pages
+type Alert struct {
+	// Key identifies what the alert is about. Alerts with the same key stack into one and the key is given a cooldown
+	// once the alert has been shown.
+	Key      string
+	Priority Priority
+	Title    string
+	Message  string
+	// Page draws the alert, if it's nil the title and message are shown
+	Page Page
+	// Sticky alerts stay on the display until they're acknowledged or removed
+	Sticky bool
+	// Duration is how long the alert is shown, the configured duration if it's 0
+	Duration time.Duration
+}
+
+// AlertOptions control how long alerts are shown and how often
+type AlertOptions struct {
+	// Duration is how long an alert is shown
+	Duration time.Duration
+	// MinDuration is how long an alert is shown before a higher priority alert can interrupt it
+	MinDuration time.Duration
+	// Cooldown is how long after an alert is shown before another with the same key is accepted
+	Cooldown time.Duration
+	// RateLimit is the most alerts accepted in RateWindow, 0 for no limit
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// Alerts queues alerts and decides which, if any, is shown in place of the pages
+type Alerts struct {
+	mu sync.Mutex
+
+	options  AlertOptions
+	current  *queuedAlert
+	queue    []*queuedAlert
+	cooldown map[string]time.Time
+	accepted []time.Time
+}
+
+type queuedAlert struct {
+	Alert
+
+	count int
+	// shown is how long the alert has been on the display, not counting the time shownAt was set
+	shown   time.Duration
+	shownAt time.Time
+}
+
+func NewAlerts(options AlertOptions) *Alerts {
+	return &Alerts{
+		options:  options,
+		cooldown: make(map[string]time.Time),
+	}
+}
+
+// Add queues alert at now. An alert with the same key as one already waiting or shown is stacked onto it. It returns
+// false if the alert was dropped because its key is cooling down or too many alerts have been added recently.
+func (a *Alerts) Add(now time.Time, alert Alert) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if existing := a.find(alert.Key); existing != nil {
+		existing.count++
+		existing.Title, existing.Message, existing.Page = alert.Title, alert.Message, alert.Page
+		existing.Priority = max(existing.Priority, alert.Priority)
+		existing.Sticky = existing.Sticky || alert.Sticky
+
+		return true
+	}
+
+	if alert.Priority < PriorityCritical {
+		if now.Before(a.cooldown[alert.Key]) {
+			return false
+		}
+
+		if !a.allow(now) {
+			return false
+		}
+	}
+
+	a.queue = append(a.queue, &queuedAlert{Alert: alert, count: 1})
+
+	return true
+}
+
+// allow applies the rate limit, recording the alert if it's allowed
+func (a *Alerts) allow(now time.Time) bool {
+	if a.options.RateLimit <= 0 {
+		return true
+	}
+
+	a.accepted = slices.DeleteFunc(a.accepted, func(at time.Time) bool {
+		return now.Sub(at) >= a.options.RateWindow
+	})
+
+	if len(a.accepted) >= a.options.RateLimit {
+		return false
+	}
+
+	a.accepted = append(a.accepted, now)
+
+	return true
+}
+
+func (a *Alerts) find(key string) *queuedAlert {
+	if a.current != nil && a.current.Key == key {
+		return a.current
+	}
+
+	for _, queued := range a.queue {
+		if queued.Key == key {
+			return queued
+		}
+	}
+
+	return nil
+}
+
+// Remove takes the alert with key off the display or out of the queue, for when whatever it's about is over
+func (a *Alerts) Remove(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.current != nil && a.current.Key == key {
+		a.current = nil
+	}
+
+	a.queue = slices.DeleteFunc(a.queue, func(queued *queuedAlert) bool {
+		return queued.Key == key
+	})
+}
+
+// Acknowledge dismisses the alert on the display and every sticky alert waiting to be shown, returning how many were
+// dismissed
+func (a *Alerts) Acknowledge(now time.Time) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var count int
+
+	if a.current != nil {
+		a.finish(now, a.current)
+		a.current = nil
+		count++
+	}
+
+	a.queue = slices.DeleteFunc(a.queue, func(queued *queuedAlert) bool {
+		if queued.Sticky {
+			a.finish(now, queued)
+			count++
+		}
+
+		return queued.Sticky
+	})
+
+	return count
+}
+
+// Current returns the page to show in place of the pages at now, if there's an alert to show
+func (a *Alerts) Current(now time.Time) (Page, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.current != nil {
+		a.current.shown += now.Sub(a.current.shownAt)
+		a.current.shownAt = now
+
+		if !a.current.Sticky && a.current.shown >= cmp.Or(a.current.Duration, a.options.Duration) {
+			a.finish(now, a.current)
+			a.current = nil
+		}
+	}
+
+	next := a.next()
+
+	switch {
+	case next < 0:
+	case a.current == nil:
+		a.show(now, next)
+	case a.queue[next].Priority > a.current.Priority &&
+		(a.current.shown >= a.options.MinDuration || a.queue[next].Priority == PriorityCritical):
+		interrupted := a.current
+
+		// the interrupted alert goes back to the front of the queue to finish being shown
+		a.show(now, next)
+		a.queue = slices.Insert(a.queue, 0, interrupted)
+	}
+
+	if a.current == nil {
+		return nil, false
+	}
+
+	return &alertPage{alert: a.current.Alert, count: a.current.count, waiting: len(a.queue)}, true
+}
+
+// next returns the index of the highest priority alert in the queue, the oldest of those with the same priority
+func (a *Alerts) next() int {
+	best := -1
+
+	for i, queued := range a.queue {
+		if best < 0 || queued.Priority > a.queue[best].Priority {
+			best = i
+		}
+	}
+
+	return best
+}
+
+func (a *Alerts) show(now time.Time, index int) {
+	a.current = a.queue[index]
+	a.current.shownAt = now
+	a.queue = slices.Delete(a.queue, index, index+1)
+}
+
+func (a *Alerts) finish(now time.Time, alert *queuedAlert) {
+	a.cooldown[alert.Key] = now.Add(a.options.Cooldown)
+
+	for key, until := range a.cooldown {
+		if now.After(until) {
+			delete(a.cooldown, key)
+		}
+	}
+}
+
+// alertPage draws an alert along with how many times it stacked and how many others are waiting
+type alertPage struct {
+	alert   Alert
+	count   int
+	waiting int
+}
+
+func (p *alertPage) Name() string {
+	return "alert"
+}
+
+func (p *alertPage) Duration() time.Duration {
+	return 0
+}
+
+func (p *alertPage) Draw(dst draw.Image, data *Data) {
+	if p.alert.Page != nil {
+		p.alert.Page.Draw(dst, data)
+	} else {
+		title := p.alert.Title
+		if p.count > 1 {
+			title = messagePrinter.Sprintf("%s x%d", title, p.count)
+		}
+
+		lines := []oled.Line{{Text: title, Font: noticeFont, Align: oled.AlignCenter}}
+
+		for _, line := range wrapText(oled.DefaultFont, p.alert.Message, dst.Bounds().Dx()) {
+			lines = append(lines, oled.Line{Text: line})
+		}
+
+		oled.DrawLines(dst, lines)
+	}
+
+	if p.waiting > 0 {
+		drawBadge(dst, messagePrinter.Sprintf("+%d", p.waiting))
+	}
+}
+
+// drawBadge draws text in a box in the bottom right corner, clearing whatever is underneath
+func drawBadge(dst draw.Image, text string) {
+	face := oled.Face(oled.DefaultFont)
+	bounds := dst.Bounds()
+	rect := image.Rect(bounds.Max.X-oled.MeasureString(face, text)-2, bounds.Max.Y-face.Metrics().Height.Ceil()-1,
+		bounds.Max.X, bounds.Max.Y)
+
+	draw.Draw(dst, rect, image.Black, image.Point{}, draw.Src)
+	oled.DrawRect(dst, rect)
+	oled.DrawText(dst, face, text, rect.Inset(1), oled.AlignCenter)
+}
+
+// wrapText splits text into lines that fit in width pixels, breaking between words
+func wrapText(fontName string, text string, width int) []string {
+	face := oled.Face(fontName)
+
+	var lines []string
+
+	var current string
+
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+
+		if current != "" && oled.MeasureString(face, candidate) > width {
+			lines = append(lines, current)
+			candidate = word
+		}
+
+		current = candidate
+	}
+
+	if current != "" {
+		lines = append(lines, current)
+	}
+
+	return lines
+}
@@ -0,0 +1,198 @@
+package layout
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var alertStart = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// showing returns the key of the alert shown after seconds, how many times it's stacked and how many are waiting
+func showing(t *testing.T, alerts *Alerts, seconds int) (string, int, int) {
+	t.Helper()
+
+	page, ok := alerts.Current(alertStart.Add(time.Duration(seconds) * time.Second))
+	if !ok {
+		return "", 0, 0
+	}
+
+	shown, ok := page.(*alertPage)
+	if !ok {
+		t.Fatalf("got page %T, want an alert", page)
+	}
+
+	return shown.alert.Key, shown.count, shown.waiting
+}
+
+func addAlert(alerts *Alerts, seconds int, key string, priority Priority) bool {
+	return alerts.Add(alertStart.Add(time.Duration(seconds)*time.Second), Alert{Key: key, Priority: priority})
+}
+
+func TestAlertsCooldown(t *testing.T) {
+	t.Parallel()
+
+	alerts := NewAlerts(AlertOptions{Duration: 10 * time.Second, Cooldown: time.Minute})
+
+	steps := []struct {
+		name      string
+		seconds   int
+		key       string
+		priority  Priority
+		wantAdded bool
+		wantShown string
+	}{
+		{"first", 0, "a", PriorityNormal, true, "a"},
+		{"stacks while shown", 5, "a", PriorityNormal, true, "a"},
+		// shown for its 10 seconds and then cooling down for a minute
+		{"cooling down", 15, "a", PriorityNormal, false, ""},
+		{"other keys aren't", 20, "b", PriorityNormal, true, "b"},
+		{"critical ignores the cooldown", 40, "a", PriorityCritical, true, "a"},
+		{"cooled down", 120, "b", PriorityNormal, true, "b"},
+	}
+
+	for _, step := range steps {
+		// the display checks for an alert to show before anything new is added
+		showing(t, alerts, step.seconds)
+
+		if got := addAlert(alerts, step.seconds, step.key, step.priority); got != step.wantAdded {
+			t.Errorf("%s: got added %t, want %t", step.name, got, step.wantAdded)
+		}
+
+		if got, _, _ := showing(t, alerts, step.seconds); got != step.wantShown {
+			t.Errorf("%s: got %q shown, want %q", step.name, got, step.wantShown)
+		}
+	}
+}
+
+func TestAlertsRateLimit(t *testing.T) {
+	t.Parallel()
+
+	alerts := NewAlerts(AlertOptions{Duration: time.Second, RateLimit: 2, RateWindow: time.Minute})
+
+	steps := []struct {
+		seconds   int
+		key       string
+		priority  Priority
+		wantAdded bool
+	}{
+		{0, "a", PriorityNormal, true},
+		{1, "b", PriorityNormal, true},
+		// stacking onto a waiting alert doesn't count
+		{2, "b", PriorityNormal, true},
+		{3, "c", PriorityHigh, false},
+		{4, "d", PriorityCritical, true},
+		{59, "e", PriorityNormal, false},
+		// a was accepted a minute ago so there's room again
+		{60, "e", PriorityNormal, true},
+		{60, "f", PriorityNormal, false},
+	}
+
+	for _, step := range steps {
+		if got := addAlert(alerts, step.seconds, step.key, step.priority); got != step.wantAdded {
+			t.Errorf("%s at %ds: got added %t, want %t", step.key, step.seconds, got, step.wantAdded)
+		}
+	}
+}
+
+func TestAlertsPriority(t *testing.T) {
+	t.Parallel()
+
+	alerts := NewAlerts(AlertOptions{Duration: 10 * time.Second, MinDuration: 3 * time.Second})
+
+	addAlert(alerts, 0, "low", PriorityLow)
+	addAlert(alerts, 0, "normal", PriorityNormal)
+
+	steps := []struct {
+		name        string
+		seconds     int
+		add         string
+		priority    Priority
+		wantShown   string
+		wantWaiting int
+	}{
+		{"highest first", 0, "", 0, "normal", 1},
+		{"not interrupted too soon", 1, "high", PriorityHigh, "normal", 2},
+		{"interrupted", 3, "", 0, "high", 2},
+		{"critical interrupts straight away", 4, "critical", PriorityCritical, "critical", 3},
+		{"interrupted alerts go back", 14, "", 0, "high", 2},
+		// high was shown for 1 second before it was interrupted
+		{"and finish being shown", 22, "", 0, "high", 2},
+		{"then the rest", 24, "", 0, "normal", 1},
+		{"equal priority doesn't interrupt", 25, "normal2", PriorityNormal, "normal", 2},
+		{"oldest first", 35, "", 0, "normal2", 1},
+		{"last", 45, "", 0, "low", 0},
+		{"empty", 55, "", 0, "", 0},
+	}
+
+	for _, step := range steps {
+		if step.add != "" {
+			addAlert(alerts, step.seconds, step.add, step.priority)
+		}
+
+		got, _, waiting := showing(t, alerts, step.seconds)
+		if got != step.wantShown || waiting != step.wantWaiting {
+			t.Errorf("%s: got %q shown with %d waiting, want %q with %d", step.name, got, waiting, step.wantShown,
+				step.wantWaiting)
+		}
+	}
+}
+
+func TestAlertsStickyAndAcknowledge(t *testing.T) {
+	t.Parallel()
+
+	alerts := NewAlerts(AlertOptions{Duration: 10 * time.Second, Cooldown: time.Minute})
+	now := alertStart
+
+	alerts.Add(now, Alert{Key: "sticky", Priority: PriorityHigh, Sticky: true})
+	alerts.Add(now, Alert{Key: "sticky", Priority: PriorityHigh, Sticky: true})
+	alerts.Add(now, Alert{Key: "other", Sticky: true})
+	alerts.Add(now, Alert{Key: "plain"})
+
+	if got, count, _ := showing(t, alerts, 60); got != "sticky" || count != 2 {
+		t.Errorf("got %q stacked %d times, want sticky stacked twice", got, count)
+	}
+
+	if got := alerts.Acknowledge(alertStart.Add(time.Minute)); got != 2 {
+		t.Errorf("got %d acknowledged, want 2", got)
+	}
+
+	if got, _, waiting := showing(t, alerts, 60); got != "plain" || waiting != 0 {
+		t.Errorf("got %q with %d waiting, want plain alone", got, waiting)
+	}
+
+	alerts.Remove("plain")
+
+	if got, _, _ := showing(t, alerts, 61); got != "" {
+		t.Errorf("got %q after removing it, want nothing", got)
+	}
+
+	if alerts.Add(alertStart.Add(90*time.Second), Alert{Key: "sticky"}) {
+		t.Errorf("got an acknowledged alert added again while cooling down")
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		want    Priority
+		wantErr error
+	}{
+		{"low", PriorityLow, nil},
+		{"Critical", PriorityCritical, nil},
+		{"urgent", 0, ErrUnknownPriority},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePriority(tt.name)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %s and error %v, want %s and %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package layout
+
+import (
+	"image/draw"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+// flashInterval is how long the emergency screen stays the same way round before being inverted
+const flashInterval = 500 * time.Millisecond
+
+// Emergencies follows the aircraft in an emergency
+type Emergencies struct {
+	mu sync.Mutex
+
+	// active are the emergencies going on, by hex
+	active map[string]*emergency
+}
+
+type emergency struct {
+	aircraft adsb.Aircraft
+	status   adsb.EmergencyStatus
+	since    time.Time
+}
+
+// EmergencyChange is an aircraft's emergency starting or ending
+type EmergencyChange struct {
+	Aircraft adsb.Aircraft
+	Status   adsb.EmergencyStatus
+	Started  bool
+	// Duration is how long the emergency lasted, for emergencies that have ended
+	Duration time.Duration
+}
+
+// Update checks every aircraft for emergencies at now, returning those that have started or ended. An aircraft's
+// emergency lasts until it stops sending one, readsb switching between its emergency status and squawk, or the
+// emergency changing, say from 7600 to 7700, updates the emergency shown rather than starting another.
+func (e *Emergencies) Update(now time.Time, myADSBData adsb.Data) []EmergencyChange {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var changes []EmergencyChange
+
+	current := make(map[string]bool)
+
+	for _, flight := range myADSBData.Planes {
+		status, ok := flight.InEmergency()
+		if !ok {
+			continue
+		}
+
+		current[flight.Hex] = true
+
+		if existing, ok := e.active[flight.Hex]; ok {
+			existing.aircraft = flight
+			existing.status = status
+
+			continue
+		}
+
+		if e.active == nil {
+			e.active = make(map[string]*emergency)
+		}
+
+		e.active[flight.Hex] = &emergency{aircraft: flight, status: status, since: now}
+		changes = append(changes, EmergencyChange{Aircraft: flight, Status: status, Started: true})
+	}
+
+	for _, hex := range slices.Sorted(maps.Keys(e.active)) {
+		if current[hex] {
+			continue
+		}
+
+		existing := e.active[hex]
+		changes = append(changes, EmergencyChange{
+			Aircraft: existing.aircraft,
+			Status:   existing.status,
+			Duration: now.Sub(existing.since),
+		})
+
+		delete(e.active, hex)
+	}
+
+	return changes
+}
+
+// Page returns a page showing the aircraft's emergency, kept up to date as the aircraft moves
+func (e *Emergencies) Page(hex string) Page {
+	return &emergencyPage{emergencies: e, hex: hex}
+}
+
+// latest returns the last seen state of the aircraft in an emergency
+func (e *Emergencies) latest(hex string) (*emergency, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	existing, ok := e.active[hex]
+	if !ok {
+		return nil, false
+	}
+
+	copied := *existing
+
+	return &copied, true
+}
+
+// emergencyPage flashes the details of an aircraft in an emergency
+type emergencyPage struct {
+	emergencies *Emergencies
+	hex         string
+}
+
+func (p *emergencyPage) Name() string {
+	return "emergency"
+}
+
+func (p *emergencyPage) Duration() time.Duration {
+	return flashInterval
+}
+
+func (p *emergencyPage) Draw(dst draw.Image, data *Data) {
+	current, ok := p.emergencies.latest(p.hex)
+	if !ok {
+		return
+	}
+
+	lines := []oled.Line{
+		{Text: oled.Icon("warning") + current.status.Short, Font: noticeFont, Align: oled.AlignCenter},
+		{Text: aircraftName(current.aircraft), Right: current.aircraft.Squawk},
+		{Text: current.status.Description},
+	}
+
+	relative, err := data.Station.RelativeTo(current.aircraft)
+	if err == nil {
+		lines = append(lines, oled.Line{Text: messagePrinter.Sprintf("%.1fmi %s%s", relative.SlantRange,
+			oled.Icon(oled.ArrowIconName(relative.Bearing)), relative.Compass)})
+	}
+
+	oled.DrawLines(dst, lines)
+
+	if data.Now.UnixMilli()/flashInterval.Milliseconds()%2 == 1 {
+		oled.Invert(dst)
+	}
+}
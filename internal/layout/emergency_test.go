@@ -0,0 +1,67 @@
+package layout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+func TestEmergenciesUpdate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	aircraft := func(hex, squawk, emergency string) adsb.Aircraft {
+		return adsb.Aircraft{Hex: hex, Squawk: squawk, Emergency: emergency}
+	}
+
+	// change is what's expected to have started or ended
+	type change struct {
+		hex     string
+		code    string
+		started bool
+	}
+
+	steps := []struct {
+		name       string
+		planes     []adsb.Aircraft
+		want       []change
+		wantStatus string
+	}{
+		{"none", []adsb.Aircraft{aircraft("a1", "1200", "")}, nil, ""},
+		{"squawk", []adsb.Aircraft{aircraft("a1", "7700", "")}, []change{{"a1", "7700", true}}, "7700"},
+		{"status flickers on", []adsb.Aircraft{aircraft("a1", "7700", "general")}, nil, "general"},
+		{"status flickers off", []adsb.Aircraft{aircraft("a1", "7700", "none")}, nil, "7700"},
+		{"squawk changes", []adsb.Aircraft{aircraft("a1", "7600", "")}, nil, "7600"},
+		{
+			"another", []adsb.Aircraft{aircraft("a1", "7600", ""), aircraft("b2", "1200", "minfuel")},
+			[]change{{"b2", "minfuel", true}}, "7600",
+		},
+		{
+			"both end", []adsb.Aircraft{aircraft("a1", "1200", "")},
+			[]change{{"a1", "7600", false}, {"b2", "minfuel", false}}, "",
+		},
+	}
+
+	var emergencies Emergencies
+
+	for i, step := range steps {
+		got := emergencies.Update(start.Add(time.Duration(i)*time.Second), adsb.Data{Planes: step.planes})
+
+		if len(got) != len(step.want) {
+			t.Fatalf("%s: got %d changes, want %d", step.name, len(got), len(step.want))
+		}
+
+		for j, want := range step.want {
+			if got[j].Aircraft.Hex != want.hex || got[j].Status.Code != want.code || got[j].Started != want.started {
+				t.Errorf("%s: got %s %s started %t, want %s %s started %t", step.name, got[j].Aircraft.Hex,
+					got[j].Status.Code, got[j].Started, want.hex, want.code, want.started)
+			}
+		}
+
+		current, ok := emergencies.latest("a1")
+		if ok != (step.wantStatus != "") || (ok && current.status.Code != step.wantStatus) {
+			t.Errorf("%s: got active %t, want status %q", step.name, ok, step.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package layout
+
+import (
+	"errors"
+	"fmt"
+	"image/draw"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/routes"
+)
+
+var (
+	ErrUnknownPageType = errors.New("unknown page type")
+	ErrUnknownPage     = errors.New("unknown page")
+)
+
+const (
+	defaultPageType     = "classic"
+	widgetsPageType     = "widgets"
+	defaultPageDuration = 10 * time.Second
+)
+
+// Data is everything a page can show, gathered once per frame
+type Data struct {
+	Now             time.Time
+	Station         adsb.Station
+	UpdateAvailable bool
+	CPUTempC        int
+	Total           int
+	WithPosition    int
+	WithoutPosition int
+	FeedersGood     int
+	FeedersBad      int
+	Feeders         map[string]adsb.FeederInfo
+	Aircraft        []adsb.Aircraft
+	Closest         *Closest
+	// Watched gives the name of the watchlist entry each watched aircraft matches, by hex
+	Watched map[string]string
+}
+
+// Closest is the aircraft nearest the station. Distance is the horizontal distance in miles, the aircraft is chosen
+// by slant range.
+type Closest struct {
+	adsb.Aircraft
+
+	adsb.RelativePosition
+
+	// Name is the callsign, or the registration or "none" if the aircraft isn't sending one. With airline names
+	// turned on it's the airline and flight number when the callsign is an airline's.
+	Name string
+	// Flight is the callsign decoded into the airline and flight number, nil if it isn't a known airline's
+	Flight *adsb.Flight
+	// Route is where the flight is going, nil if it isn't known or doesn't fit where the aircraft is
+	Route *routes.Route
+	// Approach is the predicted closest approach, nil if the aircraft isn't sending its speed and track
+	Approach *adsb.Approach
+}
+
+// Page draws one screen of the display
+type Page interface {
+	Name() string
+	Duration() time.Duration
+	Draw(dst draw.Image, data *Data)
+}
+
+// Sampler is a page keeping a history of the data, like a graph, which samples every update of the aircraft whether or
+// not it's showing
+type Sampler interface {
+	Sample(data *Data)
+}
+
+// PageConstructor builds a page of a type the layout package doesn't know about itself
+type PageConstructor func(cfg config.PageConfig) (Page, error)
+
+// Pages rotates through the configured pages
+type Pages struct {
+	mu sync.Mutex
+
+	pages   []Page
+	filters []adsb.Filter
+	current int
+	shownAt time.Time
+	// pinned keeps the current page on the display until it's unpinned
+	pinned bool
+}
+
+// NewPages builds the pages in cfgs. Pages without a type are widget pages if they have widgets, otherwise the
+// classic page. With no pages configured only the classic page is shown. types gives the constructors for page types
+// defined outside this package, including "classic".
+func NewPages(cfgs []config.PageConfig, types map[string]PageConstructor) (*Pages, error) {
+	if len(cfgs) == 0 {
+		cfgs = []config.PageConfig{{Name: defaultPageType, Type: defaultPageType}}
+	}
+
+	pages := make([]Page, 0, len(cfgs))
+	filters := make([]adsb.Filter, 0, len(cfgs))
+
+	for i, cfg := range cfgs {
+		if cfg.Type == "" {
+			cfg.Type = defaultPageType
+			if len(cfg.Widgets) > 0 {
+				cfg.Type = widgetsPageType
+			}
+		}
+
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("%s-%d", cfg.Type, i+1)
+		}
+
+		if cfg.Duration.Duration <= 0 {
+			cfg.Duration.Duration = defaultPageDuration
+		}
+
+		var page Page
+
+		var err error
+
+		switch cfg.Type {
+		case widgetsPageType:
+			page, err = newWidgetPage(cfg)
+		case radarPageType:
+			page = newRadarPage(cfg)
+		case nearestPageType:
+			page, err = newNearestPage(cfg)
+		default:
+			constructor, ok := types[cfg.Type]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownPageType, cfg.Type)
+			}
+
+			page, err = constructor(cfg)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error creating page %q: %w", cfg.Name, err)
+		}
+
+		pages = append(pages, page)
+		filters = append(filters, cfg.Filter)
+	}
+
+	return &Pages{pages: pages, filters: filters}, nil
+}
+
+// Current returns the page to show at now and the filter selecting the aircraft it shows, moving on to the next page
+// once the current one has been shown for its duration unless it's pinned
+func (p *Pages) Current(now time.Time) (Page, adsb.Filter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.shownAt.IsZero() {
+		p.shownAt = now
+	}
+
+	if !p.pinned && now.Sub(p.shownAt) >= p.pages[p.current].Duration() {
+		p.current = (p.current + 1) % len(p.pages)
+		p.shownAt = now
+	}
+
+	return p.pages[p.current], p.filters[p.current]
+}
+
+// Sample passes each page keeping a history the data it would show, built by build with the page's filter
+func (p *Pages) Sample(build func(filter adsb.Filter) *Data) {
+	p.mu.Lock()
+	pages, filters := p.pages, p.filters
+	p.mu.Unlock()
+
+	for i, page := range pages {
+		if sampler, ok := page.(Sampler); ok {
+			sampler.Sample(build(filters[i]))
+		}
+	}
+}
+
+// Names returns the names of the pages in the order they're shown
+func (p *Pages) Names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.pages))
+
+	for _, page := range p.pages {
+		names = append(names, page.Name())
+	}
+
+	return names
+}
+
+// Next shows the next page from now, returning its name. A pinned page stays pinned, moving the pin along.
+func (p *Pages) Next(now time.Time) string {
+	return p.move(now, 1)
+}
+
+// Previous shows the previous page from now, returning its name. A pinned page stays pinned, moving the pin along.
+func (p *Pages) Previous(now time.Time) string {
+	return p.move(now, -1)
+}
+
+func (p *Pages) move(now time.Time, step int) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current = (p.current + step + len(p.pages)) % len(p.pages)
+	p.shownAt = now
+
+	return p.pages[p.current].Name()
+}
+
+// Pin shows the page named name from now until Unpin is called
+func (p *Pages) Pin(now time.Time, name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	index := p.index(name)
+	if index < 0 {
+		return fmt.Errorf("%w: %q", ErrUnknownPage, name)
+	}
+
+	p.current = index
+	p.shownAt = now
+	p.pinned = true
+
+	return nil
+}
+
+// Unpin goes back to rotating through the pages, the current page being shown for its full duration from now
+func (p *Pages) Unpin(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pinned = false
+	p.shownAt = now
+}
+
+// Replace shows the pages in other, which was just created, in place of these. A pinned page stays pinned if other
+// has a page with the same name, otherwise the rotation starts again from the first page.
+func (p *Pages) Replace(other *Pages) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := p.pages[p.current].Name()
+
+	p.pages, p.filters = other.pages, other.filters
+	p.current = 0
+	p.shownAt = time.Time{}
+
+	if index := p.index(name); p.pinned && index >= 0 {
+		p.current = index
+	} else {
+		p.pinned = false
+	}
+}
+
+func (p *Pages) index(name string) int {
+	for i, page := range p.pages {
+		if page.Name() == name {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,244 @@
+package layout
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+)
+
+// testPage is the name of a page and how many seconds it's shown for
+type testPage struct {
+	name    string
+	seconds int
+}
+
+// newTestPages gives widget pages with the names and durations given
+func newTestPages(t *testing.T, pages ...testPage) *Pages {
+	t.Helper()
+
+	cfgs := make([]config.PageConfig, 0, len(pages))
+
+	for _, page := range pages {
+		cfgs = append(cfgs, config.PageConfig{
+			Name:     page.name,
+			Duration: config.Duration{Duration: time.Duration(page.seconds) * time.Second},
+			Widgets:  []config.WidgetConfig{{Type: "text", Text: page.name}},
+		})
+	}
+
+	created, err := NewPages(cfgs, nil)
+	if err != nil {
+		t.Fatalf("error creating pages: %s", err)
+	}
+
+	return created
+}
+
+func TestNewPages(t *testing.T) {
+	t.Parallel()
+
+	pages, err := NewPages([]config.PageConfig{
+		{Widgets: []config.WidgetConfig{{Type: "text", Text: "hello"}}},
+		{Type: "radar"},
+		{Name: "closest", Type: "nearest"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("error creating pages: %s", err)
+	}
+
+	if got, want := pages.Names(), []string{"widgets-1", "radar-2", "closest"}; !slices.Equal(got, want) {
+		t.Errorf("got names %q, want %q", got, want)
+	}
+
+	page, _ := pages.Current(time.Now())
+	if page.Duration() != defaultPageDuration {
+		t.Errorf("got duration %s, want the default %s", page.Duration(), defaultPageDuration)
+	}
+
+	// the classic page is defined outside this package
+	_, err = NewPages(nil, nil)
+	if !errors.Is(err, ErrUnknownPageType) {
+		t.Errorf("got error %v without the classic page type, want %v", err, ErrUnknownPageType)
+	}
+
+	classic := func(cfg config.PageConfig) (Page, error) {
+		return newWidgetPage(config.PageConfig{Name: cfg.Name, Duration: cfg.Duration})
+	}
+
+	pages, err = NewPages(nil, map[string]PageConstructor{"classic": classic})
+	if err != nil || !slices.Equal(pages.Names(), []string{"classic"}) {
+		t.Errorf("got %v and error %v with no pages, want the classic page alone", pages.Names(), err)
+	}
+
+	_, err = NewPages([]config.PageConfig{{Type: "clock"}}, nil)
+	if !errors.Is(err, ErrUnknownPageType) {
+		t.Errorf("got error %v, want %v", err, ErrUnknownPageType)
+	}
+
+	_, err = NewPages([]config.PageConfig{{Widgets: []config.WidgetConfig{{Type: "dial"}}}}, nil)
+	if !errors.Is(err, ErrUnknownWidgetType) {
+		t.Errorf("got error %v, want %v", err, ErrUnknownWidgetType)
+	}
+}
+
+func TestPagesRotation(t *testing.T) {
+	t.Parallel()
+
+	pages := newTestPages(t, testPage{"first", 10}, testPage{"second", 5}, testPage{"third", 20})
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		after time.Duration
+		want  string
+	}{
+		{0, "first"},
+		{9 * time.Second, "first"},
+		{10 * time.Second, "second"},
+		{14 * time.Second, "second"},
+		{15 * time.Second, "third"},
+		{34 * time.Second, "third"},
+		{35 * time.Second, "first"},
+		// a late frame moves on a single page, which is shown for its duration from then
+		{50 * time.Second, "second"},
+		{54 * time.Second, "second"},
+		{55 * time.Second, "third"},
+	}
+
+	for _, step := range steps {
+		if page, _ := pages.Current(start.Add(step.after)); page.Name() != step.want {
+			t.Errorf("after %s: got %s, want %s", step.after, page.Name(), step.want)
+		}
+	}
+}
+
+func TestPagesCommands(t *testing.T) {
+	t.Parallel()
+
+	pages := newTestPages(t, testPage{"first", 10}, testPage{"second", 10}, testPage{"third", 10})
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	pin := func(name string) func(now time.Time) error {
+		return func(now time.Time) error {
+			return pages.Pin(now, name)
+		}
+	}
+	unpin := func(now time.Time) error {
+		pages.Unpin(now)
+
+		return nil
+	}
+
+	steps := []struct {
+		name    string
+		after   time.Duration
+		command func(now time.Time) error
+		want    string
+	}{
+		{"starts with the first", 0, nil, "first"},
+		{"next", 5 * time.Second, moveTo(pages.Next, "second"), "second"},
+		// moving starts the page's duration again
+		{"shown for its duration", 14 * time.Second, nil, "second"},
+		{"then rotates on", 15 * time.Second, nil, "third"},
+		{"next wraps around", 16 * time.Second, moveTo(pages.Next, "first"), "first"},
+		{"previous wraps around", 17 * time.Second, moveTo(pages.Previous, "third"), "third"},
+		{"previous", 18 * time.Second, moveTo(pages.Previous, "second"), "second"},
+		{"pin", 19 * time.Second, pin("first"), "first"},
+		{"stays pinned", 60 * time.Second, nil, "first"},
+		{"moves the pin", 61 * time.Second, moveTo(pages.Next, "second"), "second"},
+		{"still pinned", 120 * time.Second, nil, "second"},
+		{
+			"unknown page", 121 * time.Second,
+			func(now time.Time) error { return expect(pin("fourth")(now), ErrUnknownPage) }, "second",
+		},
+		{"unpin", 122 * time.Second, unpin, "second"},
+		{"shown for its duration from unpinning", 131 * time.Second, nil, "second"},
+		{"rotates again", 132 * time.Second, nil, "third"},
+	}
+
+	for _, step := range steps {
+		now := start.Add(step.after)
+
+		if step.command != nil {
+			err := step.command(now)
+			if err != nil {
+				t.Errorf("%s: %s", step.name, err)
+			}
+		}
+
+		if page, _ := pages.Current(now); page.Name() != step.want {
+			t.Errorf("%s: got %s, want %s", step.name, page.Name(), step.want)
+		}
+	}
+}
+
+var errUnexpected = errors.New("unexpected result")
+
+// moveTo runs move, checking it says it's showing want
+func moveTo(move func(now time.Time) string, want string) func(now time.Time) error {
+	return func(now time.Time) error {
+		if got := move(now); got != want {
+			return fmt.Errorf("%w: moved to %s, want %s", errUnexpected, got, want)
+		}
+
+		return nil
+	}
+}
+
+// expect checks err is target
+func expect(err error, target error) error {
+	if !errors.Is(err, target) {
+		return fmt.Errorf("%w: got error %v, want %v", errUnexpected, err, target)
+	}
+
+	return nil
+}
+
+func TestPagesReplace(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		pin      string
+		replaced []testPage
+		want     string
+		pinned   bool
+	}{
+		{"starts again", "", []testPage{{"first", 10}, {"second", 10}}, "first", false},
+		{"keeps the pin", "second", []testPage{{"first", 10}, {"second", 10}}, "second", true},
+		{"drops a pin on a page that's gone", "second", []testPage{{"first", 10}, {"third", 10}}, "first", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pages := newTestPages(t, testPage{"first", 10}, testPage{"second", 10})
+			pages.Next(start)
+
+			if tt.pin != "" {
+				err := pages.Pin(start, tt.pin)
+				if err != nil {
+					t.Fatalf("error pinning: %s", err)
+				}
+			}
+
+			pages.Replace(newTestPages(t, tt.replaced...))
+
+			now := start.Add(time.Minute)
+			if page, _ := pages.Current(now); page.Name() != tt.want {
+				t.Errorf("got %s, want %s", page.Name(), tt.want)
+			}
+
+			// a pinned page is still showing long after, otherwise the pages have rotated
+			page, _ := pages.Current(now.Add(10 * time.Second))
+			if pinned := page.Name() == tt.want; pinned != tt.pinned {
+				t.Errorf("got %s after its duration, want pinned %t", page.Name(), tt.pinned)
+			}
+		})
+	}
+}
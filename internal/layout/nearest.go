@@ -0,0 +1,115 @@
+package layout
+
+import (
+	"image/draw"
+	"strings"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+const (
+	nearestPageType    = "nearest"
+	feetPerFlightLevel = 100
+)
+
+// nearestPage lists the aircraft nearest the station, one per row, with their altitude in hundreds of feet, the
+// direction to them and their distance
+type nearestPage struct {
+	name     string
+	duration time.Duration
+	query    adsb.Query
+	font     string
+	overflow oled.Overflow
+	// marquees scroll each row, so each scrolls from its start when a different aircraft takes the row
+	marquees []*oled.Marquee
+	speed    float64
+	pause    config.Duration
+}
+
+func newNearestPage(cfg config.PageConfig) (*nearestPage, error) {
+	sortBy, err := adsb.ParseSortKey(cfg.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	overflow, err := oled.ParseOverflow(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nearestPage{
+		name:     cfg.Name,
+		duration: cfg.Duration.Duration,
+		query:    adsb.Query{SortBy: sortBy, Limit: cfg.Count, MaxRange: cfg.Range},
+		font:     cfg.Font,
+		overflow: overflow,
+		speed:    cfg.ScrollSpeed,
+		pause:    cfg.ScrollPause,
+	}, nil
+}
+
+func (p *nearestPage) Name() string {
+	return p.name
+}
+
+func (p *nearestPage) Duration() time.Duration {
+	return p.duration
+}
+
+func (p *nearestPage) Draw(dst draw.Image, data *Data) {
+	nearest, err := data.Station.Query(adsb.Data{Planes: data.Aircraft}, p.query)
+	if err != nil {
+		oled.DrawLines(dst, []oled.Line{{Text: err.Error(), Font: p.font}})
+
+		return
+	}
+
+	// lines that don't fit are skipped, so listing every aircraft fills the display
+	lines := make([]oled.Line, 0, len(nearest))
+
+	for i, info := range nearest {
+		name := aircraftName(info.Aircraft)
+		if _, ok := data.Watched[info.Hex]; ok {
+			name = oled.Icon("star") + name
+		}
+
+		if i >= len(p.marquees) {
+			p.marquees = append(p.marquees, newMarquee(p.speed, p.pause))
+		}
+
+		lines = append(lines, p.marquees[i].Scroll(oled.Line{
+			Text:     name,
+			Right:    nearestDetail(info),
+			Font:     p.font,
+			Overflow: p.overflow,
+		}, oled.Width, data.Now))
+	}
+
+	oled.DrawLines(dst, lines)
+}
+
+// aircraftName is the callsign, or the hex code of aircraft that aren't sending one
+func aircraftName(aircraft adsb.Aircraft) string {
+	name := strings.TrimSpace(aircraft.CallSign)
+	if name == "" {
+		return aircraft.Hex
+	}
+
+	return name
+}
+
+func nearestDetail(info adsb.AircraftInfo) string {
+	var altitude string
+
+	switch value := info.Altitude.(type) {
+	case float64:
+		altitude = messagePrinter.Sprintf("%03.0f", value/feetPerFlightLevel)
+	case string:
+		altitude = "gnd"
+	}
+
+	return messagePrinter.Sprintf("%s %s%.1f", altitude, oled.Icon(oled.ArrowIconName(info.Bearing)), info.SlantRange)
+}
@@ -0,0 +1,177 @@
+package layout
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+const (
+	radarPageType     = "radar"
+	defaultRadarRings = 2
+	radarRadius       = oled.Height/2 - 1
+	radarTextX        = oled.Height + 2
+	headingTickLength = 4
+	watchedRadius     = 4
+)
+
+// autoRanges are the radar ranges in miles to choose from when scaling to fit the aircraft
+var autoRanges = []float64{1, 2, 5, 10, 20, 50, 100, 200, 300}
+
+var radarCenter = image.Point{X: oled.Height / 2, Y: oled.Height / 2}
+
+// radarPage plots the aircraft around the station by bearing and distance, with text about the scope on the right.
+// The closest aircraft is boxed and watched aircraft are circled.
+type radarPage struct {
+	name       string
+	duration   time.Duration
+	fixedRange float64
+	rings      int
+}
+
+// radarBlip is an aircraft's position on the scope
+type radarBlip struct {
+	hex      string
+	distance float64
+	bearing  float64
+	track    *float64
+}
+
+func newRadarPage(cfg config.PageConfig) *radarPage {
+	rings := cfg.Rings
+	if rings <= 0 {
+		rings = defaultRadarRings
+	}
+
+	return &radarPage{
+		name:       cfg.Name,
+		duration:   cfg.Duration.Duration,
+		fixedRange: cfg.Range,
+		rings:      rings,
+	}
+}
+
+func (p *radarPage) Name() string {
+	return p.name
+}
+
+func (p *radarPage) Duration() time.Duration {
+	return p.duration
+}
+
+func (p *radarPage) Draw(dst draw.Image, data *Data) {
+	blips := radarBlips(data)
+
+	scopeRange := p.fixedRange
+	if scopeRange <= 0 {
+		scopeRange = autoRange(blips)
+	}
+
+	for ring := 1; ring <= p.rings; ring++ {
+		oled.DrawCircle(dst, radarCenter, radarRadius*ring/p.rings)
+	}
+
+	// station
+	oled.DrawLine(dst, radarCenter.Add(image.Point{X: -2}), radarCenter.Add(image.Point{X: 2}))
+	oled.DrawLine(dst, radarCenter.Add(image.Point{Y: -2}), radarCenter.Add(image.Point{Y: 2}))
+
+	var inRange int
+
+	for _, blip := range blips {
+		if blip.distance > scopeRange {
+			continue
+		}
+
+		inRange++
+
+		point := scopePoint(blip.distance/scopeRange*radarRadius, blip.bearing)
+
+		oled.FillRect(dst, image.Rect(point.X-1, point.Y-1, point.X+1, point.Y+1))
+
+		if blip.track != nil {
+			oled.DrawLine(dst, point, point.Add(scopePoint(headingTickLength, *blip.track).Sub(radarCenter)))
+		}
+
+		if data.Closest != nil && blip.hex == data.Closest.Hex {
+			oled.DrawRect(dst, image.Rect(point.X-3, point.Y-3, point.X+3, point.Y+3))
+		}
+
+		if _, ok := data.Watched[blip.hex]; ok {
+			oled.DrawCircle(dst, point, watchedRadius)
+		}
+	}
+
+	p.drawText(dst, data, scopeRange, inRange)
+}
+
+func (p *radarPage) drawText(dst draw.Image, data *Data, scopeRange float64, inRange int) {
+	face := oled.Face(oled.DefaultFont)
+	lineHeight := face.Metrics().Height.Ceil()
+
+	lines := []string{
+		messagePrinter.Sprintf("%gmi", scopeRange),
+		messagePrinter.Sprintf("%s%d/%d", oled.Icon("airplane"), inRange, data.Total),
+	}
+
+	if data.Closest != nil {
+		lines = append(lines, data.Closest.Name, messagePrinter.Sprintf("%.1fmi", data.Closest.Distance))
+	}
+
+	for i, line := range lines {
+		rect := image.Rect(radarTextX, i*lineHeight, oled.Width, (i+1)*lineHeight)
+		oled.DrawText(dst, face, line, rect, oled.AlignLeft)
+	}
+}
+
+// radarBlips works out where each aircraft with a recent position is relative to the station
+func radarBlips(data *Data) []radarBlip {
+	blips := make([]radarBlip, 0, len(data.Aircraft))
+
+	for _, aircraft := range data.Aircraft {
+		relative, err := data.Station.RelativeTo(aircraft)
+		if err != nil {
+			// aircraft without a recent position can't be plotted
+			continue
+		}
+
+		blips = append(blips, radarBlip{
+			hex:      aircraft.Hex,
+			distance: relative.Distance,
+			bearing:  relative.Bearing,
+			track:    aircraft.Track,
+		})
+	}
+
+	return blips
+}
+
+// autoRange picks the smallest range that shows every aircraft
+func autoRange(blips []radarBlip) float64 {
+	var farthest float64
+
+	for _, blip := range blips {
+		farthest = max(farthest, blip.distance)
+	}
+
+	for _, scopeRange := range autoRanges {
+		if farthest <= scopeRange {
+			return scopeRange
+		}
+	}
+
+	return autoRanges[len(autoRanges)-1]
+}
+
+// scopePoint converts a distance in pixels and a bearing in degrees to a point on the scope
+func scopePoint(pixels float64, bearing float64) image.Point {
+	bearingRad := bearing * math.Pi / 180
+
+	return image.Point{
+		X: radarCenter.X + int(math.Round(pixels*math.Sin(bearingRad))),
+		Y: radarCenter.Y - int(math.Round(pixels*math.Cos(bearingRad))),
+	}
+}
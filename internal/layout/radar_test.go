@@ -0,0 +1,214 @@
+package layout
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+func TestAutoRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		distances []float64
+		want      float64
+	}{
+		{"no aircraft", nil, 1},
+		{"close", []float64{0.5}, 1},
+		{"on the edge", []float64{0.5, 1}, 1},
+		{"just past the edge", []float64{1.1}, 2},
+		{"the farthest", []float64{3, 7, 1}, 10},
+		{"far", []float64{250}, 300},
+		{"past the largest range", []float64{1000}, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			blips := make([]radarBlip, 0, len(tt.distances))
+
+			for _, distance := range tt.distances {
+				blips = append(blips, radarBlip{distance: distance})
+			}
+
+			if got := autoRange(blips); got != tt.want {
+				t.Errorf("got %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopePoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		pixels  float64
+		bearing float64
+		want    image.Point
+	}{
+		{"center", 0, 123, radarCenter},
+		{"north", radarRadius, 0, image.Pt(32, 1)},
+		{"east", radarRadius, 90, image.Pt(63, 32)},
+		{"south", radarRadius, 180, image.Pt(32, 63)},
+		{"west", radarRadius, 270, image.Pt(1, 32)},
+		{"northeast", 10, 45, image.Pt(39, 25)},
+		{"north again", 10, 360, image.Pt(32, 22)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := scopePoint(tt.pixels, tt.bearing); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRadarDraw(t *testing.T) {
+	t.Parallel()
+
+	station := adsb.Station{Latitude: 0, Longitude: 0}
+	track := 90.0
+	// about 5 miles north, 6.9 miles east and 15 miles south
+	north := located("north", 0.072, 0)
+	east := located("east", 0, 0.1)
+	east.Track = &track
+	south := located("south", -0.218, 0)
+
+	tests := []struct {
+		name       string
+		fixedRange float64
+		aircraft   []adsb.Aircraft
+		closest    string
+		watched    string
+		// want are the pixels the aircraft light, including every blip's 2x2 square
+		want []image.Point
+		// marks are pixels that the highlights light along with the blips
+		marks []image.Point
+	}{
+		{
+			name:       "fixed range",
+			fixedRange: 10,
+			aircraft:   []adsb.Aircraft{north, south},
+			want:       square(32, 17),
+		},
+		{
+			name:     "auto range fits the farthest",
+			aircraft: []adsb.Aircraft{north, south},
+			want:     append(square(32, 24), square(32, 55)...),
+		},
+		{
+			name:       "track",
+			fixedRange: 10,
+			aircraft:   []adsb.Aircraft{east},
+			// the track is a tick 4 pixels long
+			want: append(square(53, 32), image.Pt(54, 32), image.Pt(55, 32), image.Pt(56, 32), image.Pt(57, 32)),
+		},
+		{
+			name:       "closest is boxed",
+			fixedRange: 10,
+			aircraft:   []adsb.Aircraft{north},
+			closest:    "north",
+			// the box is 6 pixels across around the blip
+			marks: []image.Point{{29, 17}, {34, 17}, {32, 14}, {32, 19}},
+		},
+		{
+			name:       "watched is circled",
+			fixedRange: 10,
+			aircraft:   []adsb.Aircraft{north},
+			watched:    "north",
+			marks:      []image.Point{{28, 17}, {36, 17}, {32, 13}, {32, 21}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// a single ring around the edge keeps out of the way of the blips
+			page := newRadarPage(config.PageConfig{Range: tt.fixedRange, Rings: 1})
+			data := &Data{Station: station, Aircraft: tt.aircraft, Watched: map[string]string{tt.watched: "watched"}}
+
+			if tt.closest != "" {
+				data.Closest = &Closest{Aircraft: adsb.Aircraft{Hex: tt.closest}}
+			}
+
+			got := litScope(page, data)
+			blank := litScope(page, &Data{Station: station})
+
+			var added []image.Point
+
+			for _, point := range got {
+				if !slices.Contains(blank, point) {
+					added = append(added, point)
+				}
+			}
+
+			if tt.marks == nil {
+				sortPoints(added)
+				sortPoints(tt.want)
+
+				if !slices.Equal(added, tt.want) {
+					t.Errorf("got pixels %v lit, want %v", added, tt.want)
+				}
+
+				return
+			}
+
+			for _, mark := range tt.marks {
+				if !slices.Contains(added, mark) {
+					t.Errorf("got %v unlit, want it lit", mark)
+				}
+			}
+		})
+	}
+}
+
+// located is an aircraft with a position seen a second ago
+func located(hex string, latitude, longitude float64) adsb.Aircraft {
+	seen := 1.0
+
+	return adsb.Aircraft{Hex: hex, Latitude: latitude, Longitude: longitude, SeenPos: &seen}
+}
+
+// square is the pixels of a blip drawn at x, y
+func square(x, y int) []image.Point {
+	return []image.Point{{x - 1, y - 1}, {x, y - 1}, {x - 1, y}, {x, y}}
+}
+
+// litScope draws the radar page and returns the lit pixels of the scope, leaving out the text beside it
+func litScope(page *radarPage, data *Data) []image.Point {
+	dst := image.NewGray(image.Rect(0, 0, oled.Width, oled.Height))
+	page.Draw(dst, data)
+
+	var lit []image.Point
+
+	for y := range oled.Height {
+		for x := range oled.Height {
+			if dst.GrayAt(x, y).Y > 0 {
+				lit = append(lit, image.Pt(x, y))
+			}
+		}
+	}
+
+	return lit
+}
+
+func sortPoints(points []image.Point) {
+	slices.SortFunc(points, func(a, b image.Point) int {
+		if a.Y != b.Y {
+			return a.Y - b.Y
+		}
+
+		return a.X - b.X
+	})
+}
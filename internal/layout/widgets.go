@@ -0,0 +1,459 @@
+package layout
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/png" // icons are loaded from PNG files
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/oled"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var (
+	ErrUnknownWidgetType = errors.New("unknown widget type")
+	ErrUnknownAlign      = errors.New("unknown alignment")
+	ErrMissingField      = errors.New("missing field")
+)
+
+const (
+	defaultSparklineInterval = 10 * time.Second
+	templateErrorText        = "?"
+)
+
+var messagePrinter = message.NewPrinter(language.English)
+
+var templateFuncs = template.FuncMap{
+	"fmt":   messagePrinter.Sprintf,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"icon":  oled.Icon,
+}
+
+type widget interface {
+	draw(dst draw.Image, data *Data)
+}
+
+// samplingWidget is a widget keeping a history of the data
+type samplingWidget interface {
+	sample(data *Data)
+}
+
+// widgetPage is a page built from widgets in the configuration file
+type widgetPage struct {
+	name     string
+	duration time.Duration
+	widgets  []widget
+}
+
+func newWidgetPage(cfg config.PageConfig) (*widgetPage, error) {
+	page := &widgetPage{
+		name:     cfg.Name,
+		duration: cfg.Duration.Duration,
+		widgets:  make([]widget, 0, len(cfg.Widgets)),
+	}
+
+	for i, widgetCfg := range cfg.Widgets {
+		widgetCfg.ScrollSpeed = cmp.Or(widgetCfg.ScrollSpeed, cfg.ScrollSpeed)
+		widgetCfg.ScrollPause = cmp.Or(widgetCfg.ScrollPause, cfg.ScrollPause)
+		widgetCfg.Overflow = cmp.Or(widgetCfg.Overflow, cfg.Overflow)
+
+		newWidget, err := newWidget(widgetCfg)
+		if err != nil {
+			return nil, fmt.Errorf("error in widget %d: %w", i+1, err)
+		}
+
+		page.widgets = append(page.widgets, newWidget)
+	}
+
+	return page, nil
+}
+
+func (p *widgetPage) Name() string {
+	return p.name
+}
+
+func (p *widgetPage) Duration() time.Duration {
+	return p.duration
+}
+
+func (p *widgetPage) Draw(dst draw.Image, data *Data) {
+	for _, w := range p.widgets {
+		w.draw(dst, data)
+	}
+}
+
+func (p *widgetPage) Sample(data *Data) {
+	for _, w := range p.widgets {
+		if sampling, ok := w.(samplingWidget); ok {
+			sampling.sample(data)
+		}
+	}
+}
+
+func newWidget(cfg config.WidgetConfig) (widget, error) {
+	base, err := newWidgetBase(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Type {
+	case "text":
+		return newTextWidget(base, cfg)
+	case "icon":
+		return newIconWidget(base, cfg)
+	case "bar":
+		return newBarWidget(base, cfg)
+	case "sparkline":
+		return newSparklineWidget(base, cfg)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownWidgetType, cfg.Type)
+	}
+}
+
+// widgetBase holds what all widgets share: where they are and whether they're shown
+type widgetBase struct {
+	rect   image.Rectangle
+	ifTmpl *template.Template
+}
+
+func newWidgetBase(cfg config.WidgetConfig) (widgetBase, error) {
+	base := widgetBase{
+		rect: image.Rect(cfg.X, cfg.Y, cfg.X+cfg.Width, cfg.Y+cfg.Height),
+	}
+
+	if cfg.If != "" {
+		var err error
+
+		base.ifTmpl, err = parseTemplate("if", cfg.If)
+		if err != nil {
+			return widgetBase{}, err
+		}
+	}
+
+	return base, nil
+}
+
+// visible reports whether the widget's condition renders to something other than empty, "false" or "0"
+func (b widgetBase) visible(data *Data) bool {
+	if b.ifTmpl == nil {
+		return true
+	}
+
+	result, err := execTemplate(b.ifTmpl, data)
+	if err != nil {
+		return false
+	}
+
+	switch strings.TrimSpace(result) {
+	case "", "false", "0", "<no value>":
+		return false
+	default:
+		return true
+	}
+}
+
+type textWidget struct {
+	widgetBase
+
+	font     string
+	align    oled.Align
+	overflow oled.Overflow
+	marquee  *oled.Marquee
+	text     *template.Template
+}
+
+func newTextWidget(base widgetBase, cfg config.WidgetConfig) (*textWidget, error) {
+	align, err := parseAlign(cfg.Align)
+	if err != nil {
+		return nil, err
+	}
+
+	overflow, err := oled.ParseOverflow(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := parseTemplate("text", cfg.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	face := oled.Face(cfg.Font)
+
+	if cfg.Width == 0 {
+		base.rect.Max.X = oled.Width
+	}
+
+	if cfg.Height == 0 {
+		base.rect.Max.Y = base.rect.Min.Y + face.Metrics().Height.Ceil()
+	}
+
+	return &textWidget{
+		widgetBase: base,
+		font:       cfg.Font,
+		align:      align,
+		overflow:   overflow,
+		marquee:    newMarquee(cfg.ScrollSpeed, cfg.ScrollPause),
+		text:       text,
+	}, nil
+}
+
+func (w *textWidget) draw(dst draw.Image, data *Data) {
+	if !w.visible(data) {
+		return
+	}
+
+	text, err := execTemplate(w.text, data)
+	if err != nil {
+		text = templateErrorText
+	}
+
+	line := w.marquee.Scroll(oled.Line{Text: text, Font: w.font, Align: w.align, Overflow: w.overflow}, w.rect.Dx(),
+		data.Now)
+
+	oled.DrawTextLine(dst, oled.Face(w.font), line, w.rect)
+}
+
+// newMarquee scrolls lines at speed in pixels a second, pausing at each end
+func newMarquee(speed float64, pause config.Duration) *oled.Marquee {
+	return &oled.Marquee{Speed: speed, Pause: pause.Duration}
+}
+
+type iconWidget struct {
+	widgetBase
+
+	mask image.Image
+}
+
+func newIconWidget(base widgetBase, cfg config.WidgetConfig) (*iconWidget, error) {
+	if cfg.Icon == "" {
+		return nil, fmt.Errorf("%w: icon", ErrMissingField)
+	}
+
+	mask, ok := oled.IconMask(cfg.Icon)
+	if !ok {
+		var err error
+
+		mask, err = loadIcon(cfg.Icon)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &iconWidget{
+		widgetBase: base,
+		mask:       mask,
+	}, nil
+}
+
+func (w *iconWidget) draw(dst draw.Image, data *Data) {
+	if !w.visible(data) {
+		return
+	}
+
+	oled.DrawMask(dst, w.mask, w.rect.Min)
+}
+
+// loadIcon reads an image file, treating light opaque pixels as lit
+func loadIcon(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening icon: %w", err)
+	}
+
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding icon %s: %w", path, err)
+	}
+
+	bounds := img.Bounds()
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray, ok := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			_, _, _, alpha := img.At(x, y).RGBA()
+
+			if ok && gray.Y > 127 && alpha > 0x7FFF {
+				mask.SetAlpha(x-bounds.Min.X, y-bounds.Min.Y, color.Alpha{A: 0xFF})
+			}
+		}
+	}
+
+	return mask, nil
+}
+
+type barWidget struct {
+	widgetBase
+
+	value    *template.Template
+	minValue float64
+	maxValue float64
+}
+
+func newBarWidget(base widgetBase, cfg config.WidgetConfig) (*barWidget, error) {
+	value, err := parseTemplate("value", cfg.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	maxValue := cfg.Max
+	if maxValue == cfg.Min {
+		maxValue = cfg.Min + 100
+	}
+
+	return &barWidget{
+		widgetBase: base,
+		value:      value,
+		minValue:   cfg.Min,
+		maxValue:   maxValue,
+	}, nil
+}
+
+func (w *barWidget) draw(dst draw.Image, data *Data) {
+	if !w.visible(data) {
+		return
+	}
+
+	value, err := execFloatTemplate(w.value, data)
+	if err != nil {
+		return
+	}
+
+	oled.DrawBar(dst, w.rect, (value-w.minValue)/(w.maxValue-w.minValue))
+}
+
+// sparklineWidget keeps a history of value, one sample per interval, and draws it as a graph
+type sparklineWidget struct {
+	widgetBase
+
+	value    *template.Template
+	minValue float64
+	maxValue float64
+	interval time.Duration
+
+	mu         sync.Mutex
+	samples    []float64
+	lastSample time.Time
+}
+
+func newSparklineWidget(base widgetBase, cfg config.WidgetConfig) (*sparklineWidget, error) {
+	value, err := parseTemplate("value", cfg.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := cfg.Interval.Duration
+	if interval <= 0 {
+		interval = defaultSparklineInterval
+	}
+
+	return &sparklineWidget{
+		widgetBase: base,
+		value:      value,
+		minValue:   cfg.Min,
+		maxValue:   cfg.Max,
+		interval:   interval,
+		samples:    make([]float64, 0, base.rect.Dx()),
+	}, nil
+}
+
+// sample records value once interval has passed since the last sample. It's called on each update of the aircraft so
+// the history carries on while other pages are showing.
+func (w *sparklineWidget) sample(data *Data) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if data.Now.Sub(w.lastSample) < w.interval {
+		return
+	}
+
+	value, err := execFloatTemplate(w.value, data)
+	if err != nil {
+		return
+	}
+
+	if len(w.samples) >= w.rect.Dx() && len(w.samples) > 0 {
+		w.samples = w.samples[1:]
+	}
+
+	w.samples = append(w.samples, value)
+	w.lastSample = data.Now
+}
+
+func (w *sparklineWidget) draw(dst draw.Image, data *Data) {
+	if !w.visible(data) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	oled.DrawSparkline(dst, w.rect, w.samples, w.minValue, w.maxValue)
+}
+
+func parseAlign(align string) (oled.Align, error) {
+	switch align {
+	case "", "left":
+		return oled.AlignLeft, nil
+	case "center", "centre":
+		return oled.AlignCenter, nil
+	case "right":
+		return oled.AlignRight, nil
+	default:
+		return oled.AlignLeft, fmt.Errorf("%w: %q", ErrUnknownAlign, align)
+	}
+}
+
+func parseTemplate(name string, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("%w: %s", ErrMissingField, name)
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s template: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+func execTemplate(tmpl *template.Template, data *Data) (string, error) {
+	var result strings.Builder
+
+	err := tmpl.Execute(&result, data)
+	if err != nil {
+		return "", fmt.Errorf("error executing %s template: %w", tmpl.Name(), err)
+	}
+
+	return result.String(), nil
+}
+
+func execFloatTemplate(tmpl *template.Template, data *Data) (float64, error) {
+	result, err := execTemplate(tmpl, data)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(result), 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing %s template result: %w", tmpl.Name(), err)
+	}
+
+	return value, nil
+}
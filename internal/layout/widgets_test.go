@@ -0,0 +1,399 @@
+package layout
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+// TestSparklineSampling checks that a sparkline on a page that isn't showing, and that's hidden by its condition,
+// still records a sample each interval
+func TestSparklineSampling(t *testing.T) {
+	t.Parallel()
+
+	pages, err := NewPages([]config.PageConfig{
+		{Widgets: []config.WidgetConfig{{Type: "text", Text: "first"}}},
+		{Widgets: []config.WidgetConfig{{
+			Type: "sparkline", Width: 3, Height: 8, Value: "{{.Total}}", If: "{{false}}", Max: 10,
+			Interval: config.Duration{Duration: 10 * time.Second},
+		}}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("error building pages: %s", err)
+	}
+
+	page, ok := pages.pages[1].(*widgetPage)
+	if !ok {
+		t.Fatalf("got page %T, want a widget page", pages.pages[1])
+	}
+
+	sparkline, ok := page.widgets[0].(*sparklineWidget)
+	if !ok {
+		t.Fatalf("got widget %T, want a sparkline", page.widgets[0])
+	}
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		after time.Duration
+		total int
+		want  []float64
+	}{
+		{0, 1, []float64{1}},
+		{5 * time.Second, 2, []float64{1}},
+		{10 * time.Second, 3, []float64{1, 3}},
+		{15 * time.Second, 4, []float64{1, 3}},
+		{20 * time.Second, 5, []float64{1, 3, 5}},
+		// the oldest sample is dropped once there's one for each pixel across
+		{30 * time.Second, 6, []float64{3, 5, 6}},
+	}
+
+	for _, step := range steps {
+		pages.Sample(func(adsb.Filter) *Data {
+			return &Data{Now: start.Add(step.after), Total: step.total}
+		})
+
+		sparkline.mu.Lock()
+		got := slices.Clone(sparkline.samples)
+		sparkline.mu.Unlock()
+
+		if !slices.Equal(got, step.want) {
+			t.Errorf("after %s: got samples %v, want %v", step.after, got, step.want)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	t.Parallel()
+
+	data := &Data{
+		Total:    1234,
+		Closest:  &Closest{Name: "SWA1234", RelativePosition: adsb.RelativePosition{SlantRange: 6.94}},
+		Watched:  map[string]string{"a1b2c3": "friends"},
+		CPUTempC: 48,
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		data    *Data
+		want    string
+		wantErr bool
+	}{
+		{"plain", "hello", data, "hello", false},
+		{"field", "{{.CPUTempC}}C", data, "48C", false},
+		{"closest", "{{.Closest.Name}}", data, "SWA1234", false},
+		{"fmt", `{{fmt "%d aircraft, %.1fmi" .Total .Closest.SlantRange}}`, data, "1,234 aircraft, 6.9mi", false},
+		{"upper and trim", `{{upper (trim "  abc ")}}`, data, "ABC", false},
+		{"lower", `{{lower "ABC"}}`, data, "abc", false},
+		{"icon", `{{icon "airplane"}}`, data, oled.Icon("airplane"), false},
+		{"map", `{{index .Watched "a1b2c3"}}`, data, "friends", false},
+		{"unknown field", "{{.Nope}}", data, "", true},
+		{"unknown function", `{{shout "hi"}}`, data, "", true},
+		{"no closest aircraft", "{{.Closest.Name}}", &Data{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := parseTemplate("text", tt.text)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("error parsing: %s", err)
+				}
+
+				return
+			}
+
+			got, err := execTemplate(tmpl, tt.data)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("got %q and error %v, want %q and an error %t", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	_, err := parseTemplate("text", "")
+	if !errors.Is(err, ErrMissingField) {
+		t.Errorf("got error %v for an empty template, want %v", err, ErrMissingField)
+	}
+}
+
+func TestWidgetVisible(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cond string
+		want bool
+	}{
+		{"no condition", "", true},
+		{"true", "{{true}}", true},
+		{"false", "{{false}}", false},
+		{"zero", "{{.Total}}", false},
+		{"blank", "  ", false},
+		{"something", "{{.WithPosition}}", true},
+		{"missing key", `{{index .Watched "ffffff"}}`, false},
+		{"error", "{{.Closest.Name}}", false},
+	}
+
+	data := &Data{WithPosition: 3, Watched: map[string]string{}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			base, err := newWidgetBase(config.WidgetConfig{If: tt.cond})
+			if err != nil {
+				t.Fatalf("error creating widget: %s", err)
+			}
+
+			if got := base.visible(data); got != tt.want {
+				t.Errorf("got visible %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWidget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     config.WidgetConfig
+		wantErr error
+	}{
+		{"text", config.WidgetConfig{Type: "text", Text: "hi", Align: "centre", Overflow: "scroll"}, nil},
+		{"unknown type", config.WidgetConfig{Type: "dial"}, ErrUnknownWidgetType},
+		{"unknown alignment", config.WidgetConfig{Type: "text", Text: "hi", Align: "middle"}, ErrUnknownAlign},
+		{"unknown overflow", config.WidgetConfig{Type: "text", Text: "hi", Overflow: "wrap"}, oled.ErrUnknownOverflow},
+		{"no text", config.WidgetConfig{Type: "text"}, ErrMissingField},
+		{"no icon", config.WidgetConfig{Type: "icon"}, ErrMissingField},
+		{"missing icon file", config.WidgetConfig{Type: "icon", Icon: "/nonexistent.png"}, os.ErrNotExist},
+		{"no bar value", config.WidgetConfig{Type: "bar"}, ErrMissingField},
+		{"no sparkline value", config.WidgetConfig{Type: "sparkline"}, ErrMissingField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newWidget(tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	for _, cfg := range []config.WidgetConfig{
+		{Type: "text", Text: "{{.Total"},
+		{Type: "text", Text: "hi", If: "{{if}}"},
+	} {
+		_, err := newWidget(cfg)
+		if err == nil {
+			t.Errorf("got no error for %+v, want one for the bad template", cfg)
+		}
+	}
+}
+
+func TestTextWidget(t *testing.T) {
+	t.Parallel()
+
+	data := &Data{Total: 12}
+	face := oled.Face("")
+	lineHeight := face.Metrics().Height.Ceil()
+
+	tests := []struct {
+		name  string
+		cfg   config.WidgetConfig
+		text  string
+		rect  image.Rectangle
+		align oled.Align
+	}{
+		{
+			"left", config.WidgetConfig{X: 10, Y: 20, Width: 60, Height: 16, Text: "{{.Total}} planes"}, "12 planes",
+			image.Rect(10, 20, 70, 36), oled.AlignLeft,
+		},
+		{
+			"centre", config.WidgetConfig{X: 10, Y: 20, Width: 60, Height: 16, Text: "12", Align: "center"}, "12",
+			image.Rect(10, 20, 70, 36), oled.AlignCenter,
+		},
+		{
+			"right", config.WidgetConfig{X: 10, Y: 20, Width: 60, Height: 16, Text: "12", Align: "right"}, "12",
+			image.Rect(10, 20, 70, 36), oled.AlignRight,
+		},
+		// without a size it's as wide as the display and as tall as its font
+		{
+			"default size", config.WidgetConfig{X: 4, Y: 30, Text: "12", Align: "right"}, "12",
+			image.Rect(4, 30, oled.Width, 30+lineHeight), oled.AlignRight,
+		},
+		{
+			"error", config.WidgetConfig{X: 10, Y: 20, Width: 60, Height: 16, Text: "{{.Closest.Name}}"},
+			templateErrorText, image.Rect(10, 20, 70, 36), oled.AlignLeft,
+		},
+		{
+			"hidden", config.WidgetConfig{X: 10, Y: 20, Width: 60, Height: 16, Text: "12", If: "{{false}}"}, "",
+			image.Rect(10, 20, 70, 36), oled.AlignLeft,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.cfg.Type = "text"
+			got := render(t, tt.cfg, data)
+
+			want := image.NewGray(got.Bounds())
+			if tt.text != "" {
+				oled.DrawTextLine(want, face, oled.Line{Text: tt.text, Align: tt.align}, tt.rect)
+			}
+
+			checkSameImage(t, got, want)
+
+			lit := litBounds(got)
+			if !lit.In(tt.rect) || lit.Empty() != (tt.text == "") {
+				t.Errorf("got text drawn in %v, want it inside %v", lit, tt.rect)
+			}
+		})
+	}
+}
+
+func TestIconWidget(t *testing.T) {
+	t.Parallel()
+
+	// a white, a black and a transparent white pixel, only the first of which is lit
+	source := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	source.Set(0, 0, color.White)
+	source.Set(1, 0, color.Black)
+	source.Set(2, 0, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF})
+
+	path := filepath.Join(t.TempDir(), "icon.png")
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("error creating icon: %s", err)
+	}
+
+	err = png.Encode(file, source)
+	_ = file.Close()
+
+	if err != nil {
+		t.Fatalf("error writing icon: %s", err)
+	}
+
+	airplane, _ := oled.IconMask("airplane")
+
+	tests := []struct {
+		name string
+		icon string
+		want func(dst *image.Gray)
+	}{
+		{"built in", "airplane", func(dst *image.Gray) { oled.DrawMask(dst, airplane, image.Pt(5, 6)) }},
+		{"from a file", path, func(dst *image.Gray) { dst.SetGray(5, 6, color.Gray{Y: 0xFF}) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := render(t, config.WidgetConfig{Type: "icon", Icon: tt.icon, X: 5, Y: 6}, &Data{})
+
+			want := image.NewGray(got.Bounds())
+			tt.want(want)
+
+			checkSameImage(t, got, want)
+
+			if lit := litBounds(got); lit.Empty() || lit.Min.X < 5 || lit.Min.Y < 6 {
+				t.Errorf("got the icon drawn in %v, want it from (5,6)", lit)
+			}
+		})
+	}
+}
+
+func TestBarWidget(t *testing.T) {
+	t.Parallel()
+
+	rect := image.Rect(10, 40, 60, 48)
+
+	tests := []struct {
+		name     string
+		value    string
+		min, max float64
+		want     float64
+	}{
+		{"half", "{{.Total}}", 0, 10, 0.5},
+		{"from a minimum", "{{.Total}}", 4, 6, 0.5},
+		// without a range it's 0 to 100 from the minimum
+		{"default range", "{{.Total}}", 0, 0, 0.05},
+		{"clamped", "{{.Total}}", 0, 2, 1},
+		{"not a number", "{{.Closest}}", 0, 10, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := render(t, config.WidgetConfig{
+				Type: "bar", Value: tt.value, Min: tt.min, Max: tt.max, X: rect.Min.X, Y: rect.Min.Y,
+				Width: rect.Dx(), Height: rect.Dy(),
+			}, &Data{Total: 5})
+
+			want := image.NewGray(got.Bounds())
+			if tt.want >= 0 {
+				oled.DrawBar(want, rect, tt.want)
+			}
+
+			checkSameImage(t, got, want)
+		})
+	}
+}
+
+// render draws the widget made from cfg on an empty display
+func render(t *testing.T, cfg config.WidgetConfig, data *Data) *image.Gray {
+	t.Helper()
+
+	created, err := newWidget(cfg)
+	if err != nil {
+		t.Fatalf("error creating widget: %s", err)
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, oled.Width, oled.Height))
+	created.draw(dst, data)
+
+	return dst
+}
+
+func checkSameImage(t *testing.T, got *image.Gray, want *image.Gray) {
+	t.Helper()
+
+	if !slices.Equal(got.Pix, want.Pix) {
+		t.Errorf("got pixels lit in %v, want %v", litBounds(got), litBounds(want))
+	}
+}
+
+// litBounds is the smallest rectangle holding every lit pixel
+func litBounds(img *image.Gray) image.Rectangle {
+	var lit image.Rectangle
+
+	bounds := img.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img.GrayAt(x, y).Y > 0 {
+				lit = lit.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return lit
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type is the kind of a metric
+type Type string
+
+const (
+	Gauge     Type = "gauge"
+	Counter   Type = "counter"
+	Histogram Type = "histogram"
+)
+
+// contentType is the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Label is one label of a sample
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one value of a metric. Suffix is added to the metric's name, like "_bucket" for histograms.
+type Sample struct {
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Family is a metric and its samples
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []Sample
+}
+
+// Collector gathers metrics when they're scraped
+type Collector func() []Family
+
+// Registry serves the metrics of every registered collector in the Prometheus text format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// Register adds collector to the metrics served
+func (r *Registry) Register(collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, collector)
+}
+
+// Collect gathers the metrics of every registered collector
+func (r *Registry) Collect() []Family {
+	r.mu.Lock()
+	collectors := r.collectors
+	r.mu.Unlock()
+
+	var families []Family
+
+	for _, collector := range collectors {
+		families = append(families, collector()...)
+	}
+
+	return families
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+
+	_ = Write(w, r.Collect())
+}
+
+// Write writes families in the Prometheus text format, leaving out families with no samples
+func Write(w io.Writer, families []Family) error {
+	buf := bufio.NewWriter(w)
+
+	for _, family := range families {
+		if len(family.Samples) == 0 {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(buf, "# HELP %s %s\n", family.Name, escapeHelp(family.Help))
+		_, _ = fmt.Fprintf(buf, "# TYPE %s %s\n", family.Name, family.Type)
+
+		for _, sample := range family.Samples {
+			_, _ = buf.WriteString(family.Name + sample.Suffix)
+
+			writeLabels(buf, sample.Labels)
+
+			_, _ = buf.WriteString(" " + formatValue(sample.Value) + "\n")
+		}
+	}
+
+	err := buf.Flush()
+	if err != nil {
+		return fmt.Errorf("error writing metrics: %w", err)
+	}
+
+	return nil
+}
+
+func writeLabels(buf *bufio.Writer, labels []Label) {
+	if len(labels) == 0 {
+		return
+	}
+
+	_ = buf.WriteByte('{')
+
+	for i, label := range labels {
+		if i > 0 {
+			_ = buf.WriteByte(',')
+		}
+
+		_, _ = buf.WriteString(label.Name + `="` + escapeLabel(label.Value) + `"`)
+	}
+
+	_ = buf.WriteByte('}')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// Bool is 1 for true and 0 for false, for gauges that are either on or off
+func Bool(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
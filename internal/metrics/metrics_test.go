@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, families ...Family) string {
+	t.Helper()
+
+	var buf strings.Builder
+
+	err := Write(&buf, families)
+	if err != nil {
+		t.Fatalf("error writing metrics: %s", err)
+	}
+
+	return buf.String()
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		family Family
+		want   string
+	}{
+		{
+			name:   "gauge",
+			family: Family{Name: "up", Help: "Whether it's up", Type: Gauge, Samples: []Sample{{Value: 1}}},
+			want:   "# HELP up Whether it's up\n# TYPE up gauge\nup 1\n",
+		},
+		{
+			name:   "no samples",
+			family: Family{Name: "up", Help: "Whether it's up", Type: Gauge},
+			want:   "",
+		},
+		{
+			name:   "help escaping",
+			family: Family{Name: "x", Help: "a\\b\nc \"d\"", Type: Counter, Samples: []Sample{{Value: 2}}},
+			want:   "# HELP x a\\\\b\\nc \"d\"\n# TYPE x counter\nx 2\n",
+		},
+		{
+			name: "label escaping",
+			family: Family{Name: "x", Help: "h", Type: Gauge, Samples: []Sample{
+				{Labels: []Label{{Name: "path", Value: "C:\\tmp\n\"q\""}}, Value: 1},
+			}},
+			want: "# HELP x h\n# TYPE x gauge\nx{path=\"C:\\\\tmp\\n\\\"q\\\"\"} 1\n",
+		},
+		{
+			name: "label order",
+			family: Family{Name: "x", Help: "h", Type: Gauge, Samples: []Sample{
+				{Labels: []Label{{Name: "zone", Value: "b"}, {Name: "area", Value: "a"}}, Value: 1},
+			}},
+			want: "# HELP x h\n# TYPE x gauge\nx{zone=\"b\",area=\"a\"} 1\n",
+		},
+		{
+			name: "values",
+			family: Family{Name: "x", Help: "h", Type: Gauge, Samples: []Sample{
+				{Suffix: "_a", Value: 0.25}, {Suffix: "_b", Value: 1e-05}, {Suffix: "_c", Value: 1234567},
+				{Suffix: "_d", Value: math.Inf(1)}, {Suffix: "_e", Value: math.Inf(-1)},
+				{Suffix: "_f", Value: math.NaN()},
+			}},
+			want: "# HELP x h\n# TYPE x gauge\nx_a 0.25\nx_b 1e-05\nx_c 1.234567e+06\nx_d +Inf\nx_e -Inf\nx_f NaN\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := write(t, tt.family)
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounterVec(t *testing.T) {
+	t.Parallel()
+
+	counter := NewCounterVec("errors_total", "Errors by path", "path")
+	counter.Inc("/b")
+	counter.Inc("/a")
+	counter.Inc("/b")
+
+	want := `# HELP errors_total Errors by path
+# TYPE errors_total counter
+errors_total{path="/a"} 1
+errors_total{path="/b"} 2
+`
+
+	if got := write(t, counter.Collect()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	t.Parallel()
+
+	histogram := NewHistogramVec("took_seconds", "How long it took", "path", []float64{0.1, 1})
+	histogram.Observe("/b", 0.05)
+	histogram.Observe("/b", 0.5)
+	histogram.Observe("/b", 5)
+	// a value on a bound is in that bucket
+	histogram.Observe("/a", 0.1)
+
+	want := `# HELP took_seconds How long it took
+# TYPE took_seconds histogram
+took_seconds_bucket{path="/a",le="0.1"} 1
+took_seconds_bucket{path="/a",le="1"} 1
+took_seconds_bucket{path="/a",le="+Inf"} 1
+took_seconds_sum{path="/a"} 0.1
+took_seconds_count{path="/a"} 1
+took_seconds_bucket{path="/b",le="0.1"} 1
+took_seconds_bucket{path="/b",le="1"} 2
+took_seconds_bucket{path="/b",le="+Inf"} 3
+took_seconds_sum{path="/b"} 5.55
+took_seconds_count{path="/b"} 3
+`
+
+	if got := write(t, histogram.Collect()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnlabelledHistogram(t *testing.T) {
+	t.Parallel()
+
+	histogram := NewHistogramVec("frame_seconds", "Frames", "", []float64{0.01})
+	histogram.Observe("", 0.02)
+
+	want := `# HELP frame_seconds Frames
+# TYPE frame_seconds histogram
+frame_seconds_bucket{le="0.01"} 0
+frame_seconds_bucket{le="+Inf"} 1
+frame_seconds_sum 0.02
+frame_seconds_count 1
+`
+
+	if got := write(t, histogram.Collect()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	var registry Registry
+
+	registry.Register(func() []Family {
+		return []Family{{Name: "a", Help: "A", Type: Gauge, Samples: []Sample{{Value: 1}}}}
+	})
+	registry.Register(func() []Family {
+		return []Family{{Name: "b", Help: "B", Type: Gauge, Samples: []Sample{{Value: 2}}}}
+	})
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("got content type %q, want %q", got, contentType)
+	}
+
+	want := "# HELP a A\n# TYPE a gauge\na 1\n# HELP b B\n# TYPE b gauge\nb 2\n"
+	if rec.Body.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", rec.Body.String(), want)
+	}
+}
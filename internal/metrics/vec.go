@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// CounterVec is a counter for each value of a label. With an empty label name it's a single counter.
+type CounterVec struct {
+	name  string
+	help  string
+	label string
+
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+// NewCounterVec creates counters named name labelled by label
+func NewCounterVec(name, help, label string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		label:  label,
+		counts: make(map[string]float64),
+	}
+}
+
+// Inc adds one to the counter for labelValue
+func (c *CounterVec) Inc(labelValue string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[labelValue]++
+}
+
+// Collect returns the counters ordered by label value
+func (c *CounterVec) Collect() Family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	family := Family{Name: c.name, Help: c.help, Type: Counter}
+
+	for _, labelValue := range slices.Sorted(maps.Keys(c.counts)) {
+		family.Samples = append(family.Samples, Sample{
+			Labels: labelsFor(c.label, labelValue),
+			Value:  c.counts[labelValue],
+		})
+	}
+
+	return family
+}
+
+// HistogramVec is a histogram for each value of a label. With an empty label name it's a single histogram.
+type HistogramVec struct {
+	name  string
+	help  string
+	label string
+	// buckets are the upper bounds of the buckets in increasing order, there's always a +Inf bucket after them
+	buckets []float64
+
+	mu         sync.Mutex
+	histograms map[string]*histogram
+}
+
+type histogram struct {
+	// counts are the observations in each bucket, not cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec creates histograms named name labelled by label, with buckets as the bucket upper bounds in
+// increasing order
+func NewHistogramVec(name, help, label string, buckets []float64) *HistogramVec {
+	return &HistogramVec{
+		name:       name,
+		help:       help,
+		label:      label,
+		buckets:    buckets,
+		histograms: make(map[string]*histogram),
+	}
+}
+
+// Observe adds value to the histogram for labelValue
+func (h *HistogramVec) Observe(labelValue string, value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hist, ok := h.histograms[labelValue]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.histograms[labelValue] = hist
+	}
+
+	// values over the last bound are only counted in the +Inf bucket
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		hist.counts[i]++
+	}
+
+	hist.sum += value
+	hist.count++
+}
+
+// Collect returns the histograms ordered by label value, with cumulative buckets as Prometheus expects
+func (h *HistogramVec) Collect() Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	family := Family{Name: h.name, Help: h.help, Type: Histogram}
+
+	for _, labelValue := range slices.Sorted(maps.Keys(h.histograms)) {
+		hist := h.histograms[labelValue]
+		labels := labelsFor(h.label, labelValue)
+
+		var cumulative uint64
+
+		for i, bound := range h.buckets {
+			cumulative += hist.counts[i]
+
+			family.Samples = append(family.Samples, Sample{
+				Suffix: "_bucket",
+				Labels: append(slices.Clone(labels), Label{Name: "le", Value: formatValue(bound)}),
+				Value:  float64(cumulative),
+			})
+		}
+
+		family.Samples = append(family.Samples,
+			Sample{Suffix: "_bucket", Labels: append(slices.Clone(labels), Label{Name: "le", Value: "+Inf"}),
+				Value: float64(hist.count)},
+			Sample{Suffix: "_sum", Labels: labels, Value: hist.sum},
+			Sample{Suffix: "_count", Labels: labels, Value: float64(hist.count)},
+		)
+	}
+
+	return family
+}
+
+func labelsFor(name, value string) []Label {
+	if name == "" {
+		return nil
+	}
+
+	return []Label{{Name: name, Value: value}}
+}
@@ -0,0 +1,298 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrBadPacket         = errors.New("bad packet")
+	ErrConnectionRefused = errors.New("connection refused")
+	ErrClosed            = errors.New("connection closed")
+	ErrSubscribeRefused  = errors.New("subscription refused")
+)
+
+const (
+	defaultKeepAlive = time.Minute
+	connectTimeout   = 10 * time.Second
+	writeTimeout     = 10 * time.Second
+)
+
+// connectReturnCodes are the reasons a broker gives for refusing a connection
+var connectReturnCodes = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Will is the message the broker publishes for the client if it goes away without disconnecting
+type Will struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options say where and how to connect
+type Options struct {
+	// Address is the broker's host and port
+	Address string
+	// TLS connects with TLS when set
+	TLS      *tls.Config
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive is how often the client pings the broker to show it's still there, a minute by default
+	KeepAlive time.Duration
+	Will      *Will
+	// OnMessage is called with each message published to the topics subscribed to, one at a time
+	OnMessage func(topic string, payload []byte)
+}
+
+// Client is a connection to an MQTT 3.1.1 broker that publishes and subscribes at QoS 0
+type Client struct {
+	conn      net.Conn
+	keepAlive time.Duration
+	onMessage func(topic string, payload []byte)
+	packetID  atomic.Uint32
+
+	writeMu sync.Mutex
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// Dial connects to the broker, returning once the broker has accepted the connection
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = defaultKeepAlive
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	conn, err := dial(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	err = connect(ctx, conn, reader, opts)
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	client := &Client{
+		conn:      conn,
+		keepAlive: opts.KeepAlive,
+		onMessage: opts.OnMessage,
+		done:      make(chan struct{}),
+	}
+
+	go client.readLoop(reader)
+	go client.pingLoop()
+
+	return client, nil
+}
+
+func dial(ctx context.Context, opts Options) (net.Conn, error) {
+	var conn net.Conn
+
+	var err error
+
+	if opts.TLS != nil {
+		dialer := &tls.Dialer{Config: opts.TLS}
+		conn, err = dialer.DialContext(ctx, "tcp", opts.Address)
+	} else {
+		dialer := &net.Dialer{}
+		conn, err = dialer.DialContext(ctx, "tcp", opts.Address)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", opts.Address, err)
+	}
+
+	return conn, nil
+}
+
+// connect sends CONNECT and waits for the broker's CONNACK
+func connect(ctx context.Context, conn net.Conn, reader *bufio.Reader, opts Options) error {
+	deadline, _ := ctx.Deadline()
+
+	_ = conn.SetDeadline(deadline)
+
+	err := connectPacket(opts).writeTo(conn)
+	if err != nil {
+		return err
+	}
+
+	ack, err := readPacket(reader)
+	if err != nil {
+		return err
+	}
+
+	if ack.kind != typeConnAck || len(ack.body) != 2 {
+		return fmt.Errorf("%w: expected CONNACK", ErrBadPacket)
+	}
+
+	if code := ack.body[1]; code != 0 {
+		reason, ok := connectReturnCodes[code]
+		if !ok {
+			reason = fmt.Sprintf("code %d", code)
+		}
+
+		return fmt.Errorf("%w: %s", ErrConnectionRefused, reason)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return nil
+}
+
+// Publish sends a message at QoS 0, so it isn't acknowledged. Retained messages are kept by the broker and sent to
+// anyone subscribing later.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	return c.write(publishPacket(topic, payload, retain))
+}
+
+// Subscribe asks the broker for the messages published to topics, which are passed to OnMessage. A refused
+// subscription closes the connection.
+func (c *Client) Subscribe(topics ...string) error {
+	// packet identifiers run from 1 to 65535
+	packetID := uint16((c.packetID.Add(1)-1)%math.MaxUint16 + 1) //nolint:gosec
+
+	return c.write(subscribePacket(packetID, topics))
+}
+
+// Done is closed when the connection is lost or closed, Err then gives the reason
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err is why the connection ended, nil while it's still open
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close disconnects from the broker, which then doesn't publish the will
+func (c *Client) Close() error {
+	err := c.write(packet{kind: typeDisconnect})
+
+	c.fail(ErrClosed)
+
+	return err
+}
+
+func (c *Client) write(p packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.err
+	default:
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	err := p.writeTo(c.conn)
+	if err != nil {
+		c.fail(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// fail closes the connection, keeping the first reason given
+func (c *Client) fail(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+
+		_ = c.conn.Close()
+
+		close(c.done)
+	})
+}
+
+// readLoop reads what the broker sends until the connection ends. The broker pings back within the keep alive, so a
+// broker that's gone quiet for longer than that is taken to be gone.
+func (c *Client) readLoop(reader *bufio.Reader) {
+	for {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+
+		received, err := readPacket(reader)
+		if err == nil {
+			err = c.handle(received)
+		}
+
+		if err != nil {
+			c.fail(err)
+
+			return
+		}
+	}
+}
+
+// handle passes on published messages and checks subscriptions were accepted, anything else only shows the broker is
+// still there
+func (c *Client) handle(received packet) error {
+	switch received.kind {
+	case typePublish:
+		topic, payload, err := parsePublish(received)
+		if err != nil {
+			return err
+		}
+
+		if c.onMessage != nil {
+			c.onMessage(topic, payload)
+		}
+	case typeSubAck:
+		if len(received.body) < 3 {
+			return fmt.Errorf("%w: SUBACK is cut short", ErrBadPacket)
+		}
+
+		if slices.Contains(received.body[2:], subscribeFailure) {
+			return ErrSubscribeRefused
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			err := c.write(packet{kind: typePingReq})
+			if err != nil {
+				return
+			}
+		}
+	}
+}
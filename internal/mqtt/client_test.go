@@ -0,0 +1,436 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+const testTimeout = 5 * time.Second
+
+// broker is a stand-in for an MQTT broker that accepts a single connection
+type broker struct {
+	t        *testing.T
+	listener net.Listener
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+func newBroker(t *testing.T) *broker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %s", err)
+	}
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	return &broker{t: t, listener: listener}
+}
+
+func (b *broker) addr() string {
+	return b.listener.Addr().String()
+}
+
+// accept waits for the client to connect
+func (b *broker) accept() {
+	b.t.Helper()
+
+	conn, err := b.listener.Accept()
+	if err != nil {
+		b.t.Errorf("error accepting: %s", err)
+
+		return
+	}
+
+	b.t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	_ = conn.SetDeadline(time.Now().Add(testTimeout))
+
+	b.conn = conn
+	b.reader = bufio.NewReader(conn)
+}
+
+// expect reads a packet and checks it's exactly want
+func (b *broker) expect(want []byte) {
+	b.t.Helper()
+
+	got := make([]byte, len(want))
+
+	_, err := io.ReadFull(b.reader, got)
+	if err != nil {
+		b.t.Errorf("error reading packet: %s", err)
+
+		return
+	}
+
+	if !bytes.Equal(got, want) {
+		b.t.Errorf("got packet % x, want % x", got, want)
+	}
+}
+
+func (b *broker) send(data []byte) {
+	b.t.Helper()
+
+	_, err := b.conn.Write(data)
+	if err != nil {
+		b.t.Errorf("error writing packet: %s", err)
+	}
+}
+
+// connectBytes is the CONNECT sent with Options{ClientID: "id"} and a minute's keep alive
+var connectBytes = []byte{
+	0x10, 0x0e,
+	0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x02, 0x00, 0x3c,
+	0x00, 0x02, 'i', 'd',
+}
+
+// dialBroker connects a client to a stand-in broker that checks the CONNECT is connect and accepts it
+func dialBroker(t *testing.T, opts Options, connect []byte) (*Client, *broker) {
+	t.Helper()
+
+	brk := newBroker(t)
+	opts.Address = brk.addr()
+
+	accepted := make(chan struct{})
+
+	go func() {
+		defer close(accepted)
+
+		brk.accept()
+		brk.expect(connect)
+		brk.send([]byte{0x20, 0x02, 0x00, 0x00})
+	}()
+
+	client, err := Dial(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("error dialing: %s", err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	<-accepted
+
+	return client, brk
+}
+
+func waitDone(t *testing.T, client *Client) {
+	t.Helper()
+
+	select {
+	case <-client.Done():
+	case <-time.After(testTimeout):
+		t.Fatal("connection didn't end")
+	}
+}
+
+func TestRemainingLength(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		length int
+		want   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{2097151, []byte{0xff, 0xff, 0x7f}},
+		{2097152, []byte{0x80, 0x80, 0x80, 0x01}},
+		{maxRemainingLen, []byte{0xff, 0xff, 0xff, 0x7f}},
+	}
+
+	for _, test := range tests {
+		got := appendRemainingLength(nil, test.length)
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("appendRemainingLength(%d) = % x, want % x", test.length, got, test.want)
+		}
+
+		length, err := readRemainingLength(bufio.NewReader(bytes.NewReader(got)))
+		if err != nil || length != test.length {
+			t.Errorf("readRemainingLength(% x) = %d, %v, want %d", got, length, err, test.length)
+		}
+	}
+
+	_, err := readRemainingLength(bufio.NewReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x01})))
+	if !errors.Is(err, ErrBadPacket) {
+		t.Errorf("reading a five byte length gave %v, want %v", err, ErrBadPacket)
+	}
+}
+
+func TestConnectPacket(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts Options
+		want []byte
+	}{
+		{
+			name: "clean session",
+			opts: Options{ClientID: "id", KeepAlive: time.Minute},
+			want: connectBytes,
+		},
+		{
+			name: "will, user name and password",
+			opts: Options{
+				ClientID:  "id",
+				Username:  "u",
+				Password:  "p",
+				KeepAlive: 30 * time.Second,
+				Will:      &Will{Topic: "t", Payload: []byte("off"), Retain: true},
+			},
+			want: []byte{
+				0x10, 0x1c,
+				0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0xe6, 0x00, 0x1e,
+				0x00, 0x02, 'i', 'd',
+				0x00, 0x01, 't',
+				0x00, 0x03, 'o', 'f', 'f',
+				0x00, 0x01, 'u',
+				0x00, 0x01, 'p',
+			},
+		},
+		{
+			name: "will not retained",
+			opts: Options{ClientID: "id", KeepAlive: time.Minute, Will: &Will{Topic: "t", Payload: []byte("x")}},
+			want: []byte{
+				0x10, 0x14,
+				0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x06, 0x00, 0x3c,
+				0x00, 0x02, 'i', 'd',
+				0x00, 0x01, 't',
+				0x00, 0x01, 'x',
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+
+		err := connectPacket(test.opts).writeTo(&buf)
+		if err != nil {
+			t.Errorf("%s: error writing CONNECT: %s", test.name, err)
+
+			continue
+		}
+
+		if !bytes.Equal(buf.Bytes(), test.want) {
+			t.Errorf("%s: got % x, want % x", test.name, buf.Bytes(), test.want)
+		}
+	}
+}
+
+func TestParsePublish(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		packet      packet
+		wantTopic   string
+		wantPayload string
+		wantErr     error
+	}{
+		{
+			name:        "QoS 0",
+			packet:      packet{kind: typePublish, body: []byte{0x00, 0x01, 'a', 'h', 'i'}},
+			wantTopic:   "a",
+			wantPayload: "hi",
+		},
+		{
+			name:        "QoS 1 skips the packet identifier",
+			packet:      packet{kind: typePublish, flags: 0x02, body: []byte{0x00, 0x01, 'a', 0x00, 0x07, 'h', 'i'}},
+			wantTopic:   "a",
+			wantPayload: "hi",
+		},
+		{
+			name:    "topic cut short",
+			packet:  packet{kind: typePublish, body: []byte{0x00, 0x05, 'a'}},
+			wantErr: ErrBadPacket,
+		},
+		{
+			name:    "packet identifier cut short",
+			packet:  packet{kind: typePublish, flags: 0x02, body: []byte{0x00, 0x01, 'a', 0x00}},
+			wantErr: ErrBadPacket,
+		},
+	}
+
+	for _, test := range tests {
+		topic, payload, err := parsePublish(test.packet)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.wantErr)
+
+			continue
+		}
+
+		if topic != test.wantTopic || string(payload) != test.wantPayload {
+			t.Errorf("%s: got %q %q, want %q %q", test.name, topic, payload, test.wantTopic, test.wantPayload)
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+
+	client, brk := dialBroker(t, Options{ClientID: "id"}, connectBytes)
+
+	err := client.Publish("a/b", []byte("hi"), true)
+	if err != nil {
+		t.Fatalf("error publishing: %s", err)
+	}
+
+	brk.expect([]byte{0x31, 0x07, 0x00, 0x03, 'a', '/', 'b', 'h', 'i'})
+
+	err = client.Publish("a", nil, false)
+	if err != nil {
+		t.Fatalf("error publishing: %s", err)
+	}
+
+	brk.expect([]byte{0x30, 0x03, 0x00, 0x01, 'a'})
+
+	err = client.Close()
+	if err != nil {
+		t.Fatalf("error closing: %s", err)
+	}
+
+	brk.expect([]byte{0xe0, 0x00})
+
+	if !errors.Is(client.Err(), ErrClosed) {
+		t.Errorf("Err() after Close() = %v, want %v", client.Err(), ErrClosed)
+	}
+
+	err = client.Publish("a", nil, false)
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("publishing after Close() gave %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	type message struct {
+		topic   string
+		payload string
+	}
+
+	messages := make(chan message, 1)
+
+	client, brk := dialBroker(t, Options{ClientID: "id", OnMessage: func(topic string, payload []byte) {
+		messages <- message{topic, string(payload)}
+	}}, connectBytes)
+
+	err := client.Subscribe("cmd")
+	if err != nil {
+		t.Fatalf("error subscribing: %s", err)
+	}
+
+	brk.expect([]byte{0x82, 0x08, 0x00, 0x01, 0x00, 0x03, 'c', 'm', 'd', 0x00})
+	brk.send([]byte{0x90, 0x03, 0x00, 0x01, 0x00})
+	brk.send([]byte{0x30, 0x09, 0x00, 0x03, 'c', 'm', 'd', 'n', 'e', 'x', 't'})
+
+	select {
+	case got := <-messages:
+		if got != (message{"cmd", "next"}) {
+			t.Errorf("got message %+v", got)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("message wasn't passed on")
+	}
+
+	if client.Err() != nil {
+		t.Errorf("Err() on an open connection = %v", client.Err())
+	}
+}
+
+func TestSubscribeRefused(t *testing.T) {
+	t.Parallel()
+
+	client, brk := dialBroker(t, Options{ClientID: "id"}, connectBytes)
+
+	err := client.Subscribe("cmd")
+	if err != nil {
+		t.Fatalf("error subscribing: %s", err)
+	}
+
+	brk.expect([]byte{0x82, 0x08, 0x00, 0x01, 0x00, 0x03, 'c', 'm', 'd', 0x00})
+	brk.send([]byte{0x90, 0x03, 0x00, 0x01, subscribeFailure})
+
+	waitDone(t, client)
+
+	if !errors.Is(client.Err(), ErrSubscribeRefused) {
+		t.Errorf("Err() = %v, want %v", client.Err(), ErrSubscribeRefused)
+	}
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	// the keep alive is sent in whole seconds, so the CONNECT has a keep alive of 0
+	connect := bytes.Clone(connectBytes)
+	connect[10], connect[11] = 0, 0
+
+	_, brk := dialBroker(t, Options{ClientID: "id", KeepAlive: 100 * time.Millisecond}, connect)
+
+	brk.expect([]byte{0xc0, 0x00})
+	brk.send([]byte{0xd0, 0x00})
+	brk.expect([]byte{0xc0, 0x00})
+}
+
+func TestBrokerDrops(t *testing.T) {
+	t.Parallel()
+
+	client, brk := dialBroker(t, Options{ClientID: "id"}, connectBytes)
+
+	_ = brk.conn.Close()
+
+	waitDone(t, client)
+
+	if client.Err() == nil {
+		t.Error("Err() after the broker dropped the connection is nil")
+	}
+
+	err := client.Publish("a", nil, false)
+	if err == nil {
+		t.Error("publishing after the broker dropped the connection didn't fail")
+	}
+}
+
+func TestDialRefused(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		connack []byte
+		wantErr error
+	}{
+		{"bad user name or password", []byte{0x20, 0x02, 0x00, 0x04}, ErrConnectionRefused},
+		{"not authorized", []byte{0x20, 0x02, 0x00, 0x05}, ErrConnectionRefused},
+		{"not a CONNACK", []byte{0xd0, 0x00}, ErrBadPacket},
+	}
+
+	for _, test := range tests {
+		brk := newBroker(t)
+
+		go func() {
+			brk.accept()
+			brk.expect(connectBytes)
+			brk.send(test.connack)
+		}()
+
+		_, err := Dial(context.Background(), Options{Address: brk.addr(), ClientID: "id"})
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.wantErr)
+		}
+	}
+}
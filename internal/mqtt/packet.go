@@ -0,0 +1,221 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// packet types, the top four bits of the first byte of each packet
+const (
+	typeConnect     = 1
+	typeConnAck     = 2
+	typePublish     = 3
+	typeSubscribe   = 8
+	typeSubAck      = 9
+	typePingReq     = 12
+	typeDisconnect  = 14
+	protocolLevel   = 4 // MQTT 3.1.1
+	maxRemainingLen = 268435455
+
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+
+	flagRetain = 0x01
+	// flagsSubscribe are the reserved flags SUBSCRIBE has to have
+	flagsSubscribe = 0x02
+	qosMask        = 0x06
+
+	// subscribeFailure is the SUBACK return code for a refused subscription
+	subscribeFailure = 0x80
+)
+
+// packet is a control packet, the fixed header's type and flags and everything after the remaining length
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func (p packet) writeTo(w io.Writer) error {
+	if len(p.body) > maxRemainingLen {
+		return fmt.Errorf("%w: %d bytes is too long", ErrBadPacket, len(p.body))
+	}
+
+	header := []byte{p.kind<<4 | p.flags}
+	header = appendRemainingLength(header, len(p.body))
+
+	_, err := w.Write(append(header, p.body...))
+	if err != nil {
+		return fmt.Errorf("error writing packet: %w", err)
+	}
+
+	return nil
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return packet{}, fmt.Errorf("error reading packet: %w", err)
+	}
+
+	length, err := readRemainingLength(r)
+	if err != nil {
+		return packet{}, err
+	}
+
+	body := make([]byte, length)
+
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return packet{}, fmt.Errorf("error reading packet: %w", err)
+	}
+
+	return packet{kind: first >> 4, flags: first & 0x0f, body: body}, nil
+}
+
+// appendRemainingLength encodes length in 7 bit groups, least significant first, the top bit marking there's more
+func appendRemainingLength(b []byte, length int) []byte {
+	for {
+		digit := byte(length % 128) //nolint:gosec
+		length /= 128
+
+		if length > 0 {
+			digit |= 0x80
+		}
+
+		b = append(b, digit)
+
+		if length == 0 {
+			return b
+		}
+	}
+}
+
+func readRemainingLength(r *bufio.Reader) (int, error) {
+	var length int
+
+	for i := range 4 {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("error reading packet length: %w", err)
+		}
+
+		length |= int(digit&0x7f) << (7 * i)
+
+		if digit&0x80 == 0 {
+			return length, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: remaining length is too long", ErrBadPacket)
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+// readString reads a length prefixed string from the start of b, returning it and the rest of b
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, fmt.Errorf("%w: string is cut short", ErrBadPacket)
+	}
+
+	length := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+length {
+		return "", nil, fmt.Errorf("%w: string is cut short", ErrBadPacket)
+	}
+
+	return string(b[2 : 2+length]), b[2+length:], nil
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(min(len(data), math.MaxUint16))) //nolint:gosec
+
+	return append(b, data...)
+}
+
+func connectPacket(opts Options) packet {
+	var flags byte = flagCleanSession
+
+	body := appendString(nil, "MQTT")
+	body = append(body, protocolLevel)
+
+	payload := appendString(nil, opts.ClientID)
+
+	if opts.Will != nil {
+		flags |= flagWill
+
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+
+	if opts.Username != "" {
+		flags |= flagUsername
+		payload = appendString(payload, opts.Username)
+	}
+
+	if opts.Password != "" {
+		flags |= flagPassword
+		payload = appendString(payload, opts.Password)
+	}
+
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(min(opts.KeepAlive.Seconds(), math.MaxUint16)))
+	body = append(body, payload...)
+
+	return packet{kind: typeConnect, body: body}
+}
+
+// publishPacket is a QoS 0 PUBLISH, which has no packet identifier
+func publishPacket(topic string, payload []byte, retain bool) packet {
+	var flags byte
+
+	if retain {
+		flags |= flagRetain
+	}
+
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+
+	return packet{kind: typePublish, flags: flags, body: body}
+}
+
+// subscribePacket subscribes to topics at QoS 0
+func subscribePacket(packetID uint16, topics []string) packet {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+
+	for _, topic := range topics {
+		body = appendString(body, topic)
+		body = append(body, 0)
+	}
+
+	return packet{kind: typeSubscribe, flags: flagsSubscribe, body: body}
+}
+
+// parsePublish gives the topic and payload of a PUBLISH, skipping the packet identifier sent at QoS 1 and 2
+func parsePublish(p packet) (string, []byte, error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if p.flags&qosMask != 0 {
+		if len(rest) < 2 {
+			return "", nil, fmt.Errorf("%w: PUBLISH is cut short", ErrBadPacket)
+		}
+
+		rest = rest[2:]
+	}
+
+	return topic, rest, nil
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/events"
+	"github.com/swills/luma-adsb/internal/layout"
+)
+
+var (
+	ErrBadTarget = errors.New("bad notification target")
+	ErrBadStatus = errors.New("bad response status")
+)
+
+// Notifier sends events as notifications to the configured targets. Each event is sent once however many times it's
+// published to each target while Dedupe lasts, and targets in their quiet hours only get the most important ones.
+type Notifier struct {
+	client     *http.Client
+	retries    int
+	dedupe     time.Duration
+	priorities map[string]layout.Priority
+	targets    []*target
+
+	mu   sync.Mutex
+	sent map[sentKey]time.Time
+}
+
+// sentKey is an event key sent to a target
+type sentKey struct {
+	target *target
+	key    string
+}
+
+// New creates a notifier for the targets in cfg. priorities gives the priority of each event type, others are
+// normal, and defaultEvents are the event types sent to targets that don't list their own.
+func New(cfg config.NotifyConfig, priorities map[string]layout.Priority, defaultEvents []string) (*Notifier, error) {
+	notifier := &Notifier{
+		client:     &http.Client{Timeout: cfg.Timeout.Duration},
+		retries:    *cfg.Retries,
+		dedupe:     cfg.Dedupe.Duration,
+		priorities: priorities,
+		sent:       make(map[sentKey]time.Time),
+	}
+
+	for i, targetCfg := range cfg.Targets {
+		tgt, err := newTarget(targetCfg, i, defaultEvents)
+		if err != nil {
+			return nil, fmt.Errorf("error in notification target %d: %w", i+1, err)
+		}
+
+		notifier.targets = append(notifier.targets, tgt)
+	}
+
+	return notifier, nil
+}
+
+// Notify queues event for each target that wants it, it's meant to be subscribed to the event bus so it doesn't wait
+// for anything to be sent
+func (n *Notifier) Notify(event events.Event) {
+	if len(n.targets) == 0 {
+		return
+	}
+
+	// the lock is held until the event is recorded so the same event published twice at once is only sent once
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.forget(event.Time)
+
+	priority, ok := n.priorities[event.Type]
+	if !ok {
+		priority = layout.PriorityNormal
+	}
+
+	notification := Notification{Event: event, Priority: priority}
+
+	for _, tgt := range n.targets {
+		sent := sentKey{target: tgt, key: event.Key}
+
+		// a target that didn't take the event, like one in its quiet hours, can still get it when it's published again
+		if _, ok := n.sent[sent]; ok || !tgt.wants(event.Time, notification) {
+			continue
+		}
+
+		select {
+		case tgt.queue <- notification:
+			n.sent[sent] = event.Time
+		default:
+			fmt.Printf("dropping %s notification to %s, too many are waiting\n", event.Type, tgt.name)
+		}
+	}
+}
+
+// forget drops the events sent longer than the dedupe time before now, n.mu must be held
+func (n *Notifier) forget(now time.Time) {
+	for sent, sentAt := range n.sent {
+		if now.Sub(sentAt) >= n.dedupe {
+			delete(n.sent, sent)
+		}
+	}
+}
+
+// Run sends the queued notifications until ctx is done
+func (n *Notifier) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, tgt := range n.targets {
+		wg.Go(func() {
+			tgt.run(ctx, n.client, n.retries)
+		})
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,374 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/events"
+	"github.com/swills/luma-adsb/internal/layout"
+)
+
+// request is what a target sent
+type request struct {
+	path    string
+	headers http.Header
+	body    string
+}
+
+var testEvent = events.Event{
+	Time:    time.Date(2026, 5, 1, 12, 30, 0, 0, time.UTC),
+	Type:    "emergency",
+	Key:     "emergency/a1b2c3",
+	Hex:     "a1b2c3",
+	Title:   "Emergency",
+	Message: "UAL1 squawking 7700",
+}
+
+// record starts a server recording the request sent to it
+func record(t *testing.T) (*httptest.Server, <-chan request) {
+	t.Helper()
+
+	requests := make(chan request, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{path: r.URL.Path, headers: r.Header, body: string(body)}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func TestRequestFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		cfg      config.NotifyTarget
+		priority layout.Priority
+		path     string
+		headers  map[string]string
+		body     string
+	}{
+		{
+			name:     "webhook",
+			cfg:      config.NotifyTarget{Type: typeWebhook, Headers: map[string]string{"X-Api-Key": "secret"}},
+			priority: layout.PriorityCritical,
+			path:     "/hook",
+			headers:  map[string]string{"Content-Type": "application/json", "X-Api-Key": "secret"},
+			body: `{"time":"2026-05-01T12:30:00Z","type":"emergency","key":"emergency/a1b2c3","hex":"a1b2c3",` +
+				`"title":"Emergency","message":"UAL1 squawking 7700","priority":"critical"}`,
+		},
+		{
+			name: "webhook body",
+			cfg: config.NotifyTarget{
+				Type: typeWebhook, Title: "{{.Type}}", Body: `{"content": {{json .Message}}, "title": {{json .Title}}}`,
+			},
+			priority: layout.PriorityNormal,
+			path:     "/hook",
+			headers:  map[string]string{"Content-Type": "application/json"},
+			body:     `{"content": "UAL1 squawking 7700", "title": "Emergency"}`,
+		},
+		{
+			name:     "ntfy",
+			cfg:      config.NotifyTarget{Type: typeNtfy, Token: "tk_abc"},
+			priority: layout.PriorityHigh,
+			path:     "/hook",
+			headers: map[string]string{
+				"Content-Type": "text/plain; charset=utf-8", "Title": "Emergency", "Priority": "4",
+				"Authorization": "Bearer tk_abc",
+			},
+			body: "UAL1 squawking 7700",
+		},
+		{
+			name:     "gotify",
+			cfg:      config.NotifyTarget{Type: typeGotify, Token: "app", Message: "{{.Message}} ({{.Hex}})"},
+			priority: layout.PriorityLow,
+			path:     "/hook/message",
+			headers:  map[string]string{"Content-Type": "application/json", "X-Gotify-Key": "app"},
+			body:     `{"message":"UAL1 squawking 7700 (a1b2c3)","priority":2,"title":"Emergency"}`,
+		},
+		{
+			name:     "slack",
+			cfg:      config.NotifyTarget{Type: typeSlack},
+			priority: layout.PriorityNormal,
+			path:     "/hook",
+			headers:  map[string]string{"Content-Type": "application/json"},
+			body:     `{"text":"*Emergency*\nUAL1 squawking 7700"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, requests := record(t)
+			tt.cfg.URL = server.URL + "/hook"
+
+			tgt, err := newTarget(tt.cfg, 0, nil)
+			if err != nil {
+				t.Fatalf("error creating target: %s", err)
+			}
+
+			err = tgt.send(t.Context(), server.Client(), Notification{Event: testEvent, Priority: tt.priority}, 0)
+			if err != nil {
+				t.Fatalf("error sending: %s", err)
+			}
+
+			got := <-requests
+
+			if got.path != tt.path {
+				t.Errorf("got path %s, want %s", got.path, tt.path)
+			}
+
+			for name, want := range tt.headers {
+				if got.headers.Get(name) != want {
+					t.Errorf("got %s header %q, want %q", name, got.headers.Get(name), want)
+				}
+			}
+
+			if got.body != tt.body {
+				t.Errorf("got body %s, want %s", got.body, tt.body)
+			}
+		})
+	}
+}
+
+func TestNewTarget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     config.NotifyTarget
+		wantErr bool
+	}{
+		{"webhook", config.NotifyTarget{Type: typeWebhook, URL: "https://example.com/hook"}, false},
+		{"unknown type", config.NotifyTarget{Type: "email", URL: "https://example.com/hook"}, true},
+		{"no url", config.NotifyTarget{Type: typeNtfy}, true},
+		{"not http", config.NotifyTarget{Type: typeNtfy, URL: "ftp://example.com/"}, true},
+		{"bad template", config.NotifyTarget{Type: typeSlack, URL: "https://example.com/", Title: "{{.Title"}, true},
+		{
+			"bad quiet hours",
+			config.NotifyTarget{
+				Type: typeSlack, URL: "https://example.com/", QuietHours: config.QuietHours{Start: "10pm"},
+			},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newTarget(tt.cfg, 0, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		wantSent int
+		wantErr  bool
+	}{
+		{"sent", []int{http.StatusOK}, 3, 1, false},
+		{"no retries", []int{http.StatusServiceUnavailable}, 0, 1, true},
+		{"retried", []int{http.StatusServiceUnavailable, http.StatusOK}, 3, 2, false},
+		{"out of retries", []int{http.StatusInternalServerError, http.StatusTooManyRequests}, 1, 2, true},
+		{"not retried", []int{http.StatusBadRequest, http.StatusOK}, 3, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var sent atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.statuses[min(int(sent.Add(1))-1, len(tt.statuses)-1)])
+			}))
+			t.Cleanup(server.Close)
+
+			tgt, err := newTarget(config.NotifyTarget{Type: typeWebhook, URL: server.URL}, 0, nil)
+			if err != nil {
+				t.Fatalf("error creating target: %s", err)
+			}
+
+			err = tgt.send(t.Context(), server.Client(), Notification{Event: testEvent}, tt.retries)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrBadStatus) {
+				t.Errorf("got error %v, want %v", err, ErrBadStatus)
+			}
+
+			if int(sent.Load()) != tt.wantSent {
+				t.Errorf("got %d requests, want %d", sent.Load(), tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestSendCancelled(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	tgt, err := newTarget(config.NotifyTarget{Type: typeWebhook, URL: server.URL}, 0, nil)
+	if err != nil {
+		t.Fatalf("error creating target: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	err = tgt.send(ctx, server.Client(), Notification{Event: testEvent}, 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	t.Parallel()
+
+	at := func(clock string) time.Time {
+		parsed, _ := time.Parse("15:04", clock)
+
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.QuietHours
+		now      string
+		priority layout.Priority
+		want     bool
+	}{
+		{"none", config.QuietHours{}, "23:00", layout.PriorityLow, false},
+		{"before", config.QuietHours{Start: "09:00", End: "17:00"}, "08:59", layout.PriorityNormal, false},
+		{"start", config.QuietHours{Start: "09:00", End: "17:00"}, "09:00", layout.PriorityNormal, true},
+		{"end", config.QuietHours{Start: "09:00", End: "17:00"}, "17:00", layout.PriorityNormal, false},
+		{"critical", config.QuietHours{Start: "09:00", End: "17:00"}, "12:00", layout.PriorityCritical, false},
+		{"overnight late", config.QuietHours{Start: "22:00", End: "07:00"}, "23:30", layout.PriorityHigh, true},
+		{"overnight early", config.QuietHours{Start: "22:00", End: "07:00"}, "06:59", layout.PriorityHigh, true},
+		{"overnight day", config.QuietHours{Start: "22:00", End: "07:00"}, "12:00", layout.PriorityLow, false},
+		{
+			"min priority", config.QuietHours{Start: "22:00", End: "07:00", MinPriority: "high"}, "01:00",
+			layout.PriorityHigh, false,
+		},
+		{
+			"below min priority", config.QuietHours{Start: "22:00", End: "07:00", MinPriority: "high"}, "01:00",
+			layout.PriorityNormal, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			quiet, err := newQuietHours(tt.cfg)
+			if err != nil {
+				t.Fatalf("error creating quiet hours: %s", err)
+			}
+
+			got := quiet.holds(at(tt.now), tt.priority)
+			if got != tt.want {
+				t.Errorf("got holds %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyDedupe(t *testing.T) {
+	t.Parallel()
+
+	retries := 0
+	cfg := config.NotifyConfig{
+		Targets: []config.NotifyTarget{
+			{Type: typeWebhook, URL: "https://example.com/feeders", Events: []string{"feeder_down"}},
+			{
+				Type: typeWebhook, URL: "https://example.com/quiet", Events: []string{"emergency"},
+				QuietHours: config.QuietHours{Start: "00:00", End: "10:05"},
+			},
+			{Type: typeWebhook, URL: "https://example.com/all", Events: []string{"emergency"}},
+		},
+		Dedupe:  config.Duration{Duration: 10 * time.Minute},
+		Retries: &retries,
+	}
+	priorities := map[string]layout.Priority{"emergency": layout.PriorityHigh}
+
+	notifier, err := New(cfg, priorities, []string{"emergency", "feeder_down"})
+	if err != nil {
+		t.Fatalf("error creating notifier: %s", err)
+	}
+
+	morning := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name  string
+		event events.Event
+		// want is how many notifications the feeders, quiet and all targets have queued
+		want []int
+	}{
+		{"sent", events.Event{Time: morning, Type: "feeder_down", Key: "fr24"}, []int{1, 0, 0}},
+		{"duplicate", events.Event{Time: morning.Add(time.Minute), Type: "feeder_down", Key: "fr24"}, []int{1, 0, 0}},
+		{
+			"other key", events.Event{Time: morning.Add(time.Minute), Type: "feeder_down", Key: "piaware"},
+			[]int{2, 0, 0},
+		},
+		{
+			"dedupe over", events.Event{Time: morning.Add(10 * time.Minute), Type: "feeder_down", Key: "fr24"},
+			[]int{3, 0, 0},
+		},
+		{"quiet hours", events.Event{Time: morning.Add(time.Hour), Type: "emergency", Key: "7700"}, []int{3, 0, 1}},
+		// the held back event wasn't recorded as sent to the quiet target so it goes once its quiet hours are over,
+		// while the target that already has it doesn't get it again
+		{
+			"after quiet hours", events.Event{Time: morning.Add(65 * time.Minute), Type: "emergency", Key: "7700"},
+			[]int{3, 1, 1},
+		},
+		{
+			"duplicate again", events.Event{Time: morning.Add(69 * time.Minute), Type: "emergency", Key: "7700"},
+			[]int{3, 1, 1},
+		},
+		{
+			"dedupe over for one target", events.Event{Time: morning.Add(70 * time.Minute), Type: "emergency",
+				Key: "7700"}, []int{3, 1, 2},
+		},
+	}
+
+	for _, step := range steps {
+		notifier.Notify(step.event)
+
+		got := make([]int, 0, len(notifier.targets))
+
+		for _, tgt := range notifier.targets {
+			got = append(got, len(tgt.queue))
+		}
+
+		if !slices.Equal(got, step.want) {
+			t.Errorf("%s: got %v queued, want %v", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/layout"
+)
+
+// quietHours is a time of day, in minutes past midnight, when only notifications of minPriority and above are sent
+type quietHours struct {
+	start       int
+	end         int
+	minPriority layout.Priority
+}
+
+func newQuietHours(cfg config.QuietHours) (quietHours, error) {
+	// without quiet hours every priority is sent
+	if cfg.Start == "" && cfg.End == "" {
+		return quietHours{minPriority: layout.PriorityLow}, nil
+	}
+
+	start, err := minuteOfDay(cfg.Start)
+	if err != nil {
+		return quietHours{}, fmt.Errorf("error in quiet hours start: %w", err)
+	}
+
+	end, err := minuteOfDay(cfg.End)
+	if err != nil {
+		return quietHours{}, fmt.Errorf("error in quiet hours end: %w", err)
+	}
+
+	quiet := quietHours{start: start, end: end, minPriority: layout.PriorityCritical}
+
+	if cfg.MinPriority != "" {
+		quiet.minPriority, err = layout.ParsePriority(cfg.MinPriority)
+		if err != nil {
+			return quietHours{}, fmt.Errorf("error in quiet hours: %w", err)
+		}
+	}
+
+	return quiet, nil
+}
+
+func minuteOfDay(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q should be a time like \"22:00\"", ErrBadTarget, clock)
+	}
+
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// holds reports whether a notification of priority at now is held back, which drops it
+func (q quietHours) holds(now time.Time, priority layout.Priority) bool {
+	if priority >= q.minPriority {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+
+	// quiet hours running past midnight are quiet after the start or before the end
+	if q.start > q.end {
+		return minute >= q.start || minute < q.end
+	}
+
+	return minute >= q.start && minute < q.end
+}
@@ -0,0 +1,299 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/config"
+	"github.com/swills/luma-adsb/internal/events"
+	"github.com/swills/luma-adsb/internal/layout"
+)
+
+// target types
+const (
+	typeWebhook = "webhook"
+	typeNtfy    = "ntfy"
+	typeGotify  = "gotify"
+	typeSlack   = "slack"
+)
+
+const (
+	// queueLength is how many notifications can wait for a target before more are dropped
+	queueLength = 32
+	// firstRetry is how long to wait before trying again, doubling each time
+	firstRetry = time.Second
+)
+
+// ntfyPriorities and gotifyPriorities are what each priority is sent as
+var (
+	ntfyPriorities = map[layout.Priority]string{
+		layout.PriorityLow: "2", layout.PriorityNormal: "3", layout.PriorityHigh: "4", layout.PriorityCritical: "5",
+	}
+	gotifyPriorities = map[layout.Priority]int{
+		layout.PriorityLow: 2, layout.PriorityNormal: 5, layout.PriorityHigh: 8, layout.PriorityCritical: 10,
+	}
+)
+
+// templateFuncs are available in the templates, json quotes a value so it can go in a JSON body
+var templateFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+
+		return string(data), err //nolint:wrapcheck
+	},
+}
+
+// Notification is an event along with its priority, it's what the templates are evaluated against
+type Notification struct {
+	events.Event
+
+	Priority layout.Priority
+}
+
+// webhookBody is what a webhook is sent without a body template
+type webhookBody struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Key      string    `json:"key"`
+	Hex      string    `json:"hex,omitempty"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Priority string    `json:"priority"`
+}
+
+// target is somewhere notifications are sent, with its own queue so one that's slow or down doesn't hold up the others
+type target struct {
+	name    string
+	kind    string
+	url     string
+	token   string
+	headers map[string]string
+	events  []string
+	title   *template.Template
+	message *template.Template
+	// body is nil unless it's set
+	body  *template.Template
+	quiet quietHours
+
+	queue chan Notification
+}
+
+func newTarget(cfg config.NotifyTarget, index int, defaultEvents []string) (*target, error) {
+	if !slices.Contains([]string{typeWebhook, typeNtfy, typeGotify, typeSlack}, cfg.Type) {
+		return nil, fmt.Errorf("%w: type should be %q, %q, %q or %q", ErrBadTarget, typeWebhook, typeNtfy, typeGotify,
+			typeSlack)
+	}
+
+	parsed, err := url.Parse(cfg.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("%w: url should be an http or https URL", ErrBadTarget)
+	}
+
+	quiet, err := newQuietHours(cfg.QuietHours)
+	if err != nil {
+		return nil, err
+	}
+
+	tgt := &target{
+		name:    cmp.Or(cfg.Name, fmt.Sprintf("%s-%d", cfg.Type, index+1)),
+		kind:    cfg.Type,
+		url:     cfg.URL,
+		token:   cfg.Token,
+		headers: cfg.Headers,
+		events:  cfg.Events,
+		quiet:   quiet,
+		queue:   make(chan Notification, queueLength),
+	}
+
+	if len(tgt.events) == 0 {
+		tgt.events = defaultEvents
+	}
+
+	tgt.title, err = parseTemplate("title", cmp.Or(cfg.Title, "{{.Title}}"))
+	if err != nil {
+		return nil, err
+	}
+
+	tgt.message, err = parseTemplate("message", cmp.Or(cfg.Message, "{{.Message}}"))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Body != "" {
+		tgt.body, err = parseTemplate("body", cfg.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tgt, nil
+}
+
+func parseTemplate(name string, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s template: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+// wants reports whether notification should be sent to the target at now
+func (t *target) wants(now time.Time, notification Notification) bool {
+	return slices.Contains(t.events, notification.Type) && !t.quiet.holds(now, notification.Priority)
+}
+
+// run sends the queued notifications until ctx is done
+func (t *target) run(ctx context.Context, client *http.Client, retries int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-t.queue:
+			err := t.send(ctx, client, notification, retries)
+			if err != nil {
+				fmt.Printf("error sending %s notification to %s: %s\n", notification.Type, t.name, err)
+			}
+		}
+	}
+}
+
+// send sends notification, trying again after a while when the target can't be reached or has a problem of its own
+func (t *target) send(ctx context.Context, client *http.Client, notification Notification, retries int) error {
+	wait := firstRetry
+
+	for attempt := 0; ; attempt++ {
+		retry, err := t.post(ctx, client, notification)
+		if err == nil || !retry || attempt == retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error sending notification: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+
+		wait *= 2
+	}
+}
+
+// post makes one attempt at sending notification, reporting whether it's worth trying again if it fails
+func (t *target) post(ctx context.Context, client *http.Client, notification Notification) (bool, error) {
+	req, err := t.request(ctx, notification)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("error sending notification: %w", err)
+	}
+
+	_ = res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+
+		return retry, fmt.Errorf("%w: %s", ErrBadStatus, res.Status)
+	}
+
+	return false, nil
+}
+
+// request builds the request sending notification in the target's format
+func (t *target) request(ctx context.Context, notification Notification) (*http.Request, error) {
+	title, err := render(t.title, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := render(t.message, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	address := t.url
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	var body []byte
+
+	switch t.kind {
+	case typeNtfy:
+		body = []byte(message)
+		headers = map[string]string{"Content-Type": "text/plain; charset=utf-8", "Title": title,
+			"Priority": ntfyPriorities[notification.Priority]}
+
+		if t.token != "" {
+			headers["Authorization"] = "Bearer " + t.token
+		}
+	case typeGotify:
+		address = strings.TrimSuffix(t.url, "/") + "/message"
+		headers["X-Gotify-Key"] = t.token
+		body, err = json.Marshal(map[string]any{
+			"title": title, "message": message, "priority": gotifyPriorities[notification.Priority],
+		})
+	case typeSlack:
+		body, err = json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n%s", title, message)})
+	default:
+		body, err = t.webhookBody(notification, title, message)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error encoding notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating http req: %w", err)
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+// webhookBody is the body template evaluated against notification, or the event as JSON without one
+func (t *target) webhookBody(notification Notification, title string, message string) ([]byte, error) {
+	if t.body != nil {
+		body, err := render(t.body, notification)
+
+		return []byte(body), err
+	}
+
+	return json.Marshal(webhookBody{ //nolint:wrapcheck
+		Time:     notification.Time,
+		Type:     notification.Type,
+		Key:      notification.Key,
+		Hex:      notification.Hex,
+		Title:    title,
+		Message:  message,
+		Priority: notification.Priority.String(),
+	})
+}
+
+func render(tmpl *template.Template, notification Notification) (string, error) {
+	var buf bytes.Buffer
+
+	err := tmpl.Execute(&buf, notification)
+	if err != nil {
+		return "", fmt.Errorf("error evaluating %s template: %w", tmpl.Name(), err)
+	}
+
+	return buf.String(), nil
+}
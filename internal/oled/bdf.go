@@ -0,0 +1,189 @@
+package oled
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrBadBDF   = errors.New("malformed BDF font")
+	errBadGlyph = errors.New("bad glyph")
+)
+
+// bdfGlyphState accumulates a glyph between STARTCHAR and ENDCHAR
+type bdfGlyphState struct {
+	encoding int
+	advance  int
+	bbx      image.Rectangle
+	rows     [][]byte
+}
+
+// parseBDF reads a Glyph Bitmap Distribution Format font. Encodings are treated as Unicode code points.
+//
+//nolint:cyclop,funlen
+func parseBDF(reader io.Reader) (*bitmapFace, error) {
+	face := newBitmapFace()
+
+	var glyph *bdfGlyphState
+
+	var inBitmap, ended bool
+
+	lineNo := 0
+
+	scanner := bufio.NewScanner(reader)
+	for !ended && scanner.Scan() {
+		lineNo++
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if inBitmap && fields[0] != "ENDCHAR" {
+			row, err := hex.DecodeString(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrBadBDF, lineNo, err)
+			}
+
+			glyph.rows = append(glyph.rows, row)
+
+			continue
+		}
+
+		args, err := atoiAll(fields[1:])
+
+		switch fields[0] {
+		case "FONT_ASCENT":
+			if err != nil || len(args) != 1 {
+				return nil, fmt.Errorf("%w: line %d: bad FONT_ASCENT", ErrBadBDF, lineNo)
+			}
+
+			face.ascent = args[0]
+		case "FONT_DESCENT":
+			if err != nil || len(args) != 1 {
+				return nil, fmt.Errorf("%w: line %d: bad FONT_DESCENT", ErrBadBDF, lineNo)
+			}
+
+			face.descent = args[0]
+		case "DEFAULT_CHAR":
+			if err == nil && len(args) == 1 {
+				face.defaultChar = rune(args[0])
+			}
+		case "STARTCHAR":
+			glyph = &bdfGlyphState{encoding: -1}
+		case "ENCODING":
+			if glyph == nil || err != nil || len(args) < 1 {
+				return nil, fmt.Errorf("%w: line %d: bad ENCODING", ErrBadBDF, lineNo)
+			}
+
+			glyph.encoding = args[0]
+		case "DWIDTH":
+			if glyph == nil || err != nil || len(args) < 1 {
+				return nil, fmt.Errorf("%w: line %d: bad DWIDTH", ErrBadBDF, lineNo)
+			}
+
+			glyph.advance = args[0]
+		case "BBX":
+			if glyph == nil || err != nil || len(args) != 4 {
+				return nil, fmt.Errorf("%w: line %d: bad BBX", ErrBadBDF, lineNo)
+			}
+
+			// BBX is width, height, x offset, y offset of the lower left corner above the baseline
+			glyph.bbx = image.Rect(args[2], -(args[1] + args[3]), args[2]+args[0], -args[3])
+		case "BITMAP":
+			if glyph == nil {
+				return nil, fmt.Errorf("%w: line %d: BITMAP outside of STARTCHAR", ErrBadBDF, lineNo)
+			}
+
+			inBitmap = true
+		case "ENDCHAR":
+			if glyph == nil {
+				return nil, fmt.Errorf("%w: line %d: ENDCHAR outside of STARTCHAR", ErrBadBDF, lineNo)
+			}
+
+			err = glyph.check()
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrBadBDF, lineNo, err)
+			}
+
+			if glyph.encoding >= 0 {
+				face.glyphs[rune(glyph.encoding)] = glyph.toBitmapGlyph()
+			}
+
+			glyph = nil
+			inBitmap = false
+		case "ENDFONT":
+			ended = true
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error reading BDF font: %w", err)
+	}
+
+	// a font cut short can still have whole glyphs in it, so it's only known to be complete once ENDFONT is read
+	if !ended {
+		return nil, fmt.Errorf("%w: no ENDFONT", ErrBadBDF)
+	}
+
+	if len(face.glyphs) == 0 || face.ascent+face.descent <= 0 {
+		return nil, fmt.Errorf("%w: no glyphs or font metrics", ErrBadBDF)
+	}
+
+	return face, nil
+}
+
+// check reports whether the glyph has a row of bitmap for each line of its bounding box, each as wide as the box
+func (g *bdfGlyphState) check() error {
+	if len(g.rows) != g.bbx.Dy() {
+		return fmt.Errorf("%w: %d bitmap rows for a glyph %d high", errBadGlyph, len(g.rows), g.bbx.Dy())
+	}
+
+	for _, row := range g.rows {
+		if len(row)*8 < g.bbx.Dx() {
+			return fmt.Errorf("%w: bitmap row narrower than the glyph", errBadGlyph)
+		}
+	}
+
+	return nil
+}
+
+func (g *bdfGlyphState) toBitmapGlyph() bitmapGlyph {
+	mask := image.NewAlpha(image.Rect(0, 0, g.bbx.Dx(), g.bbx.Dy()))
+
+	for y, row := range g.rows {
+		for x := range g.bbx.Dx() {
+			if row[x/8]&(0x80>>(x%8)) != 0 {
+				mask.Pix[y*mask.Stride+x] = 0xFF
+			}
+		}
+	}
+
+	return bitmapGlyph{
+		advance: g.advance,
+		bounds:  g.bbx,
+		mask:    mask,
+	}
+}
+
+func atoiAll(fields []string) ([]int, error) {
+	values := make([]int, 0, len(fields))
+
+	for _, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing number: %w", err)
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
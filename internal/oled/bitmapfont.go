@@ -0,0 +1,99 @@
+package oled
+
+import (
+	"image"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// bitmapGlyph is a single glyph of a BDF or PCF font. bounds is relative to the dot, mask has its origin at 0,0.
+type bitmapGlyph struct {
+	advance int
+	bounds  image.Rectangle
+	mask    *image.Alpha
+}
+
+// bitmapFace is a font.Face backed by pre-rendered glyphs, possibly proportional
+type bitmapFace struct {
+	glyphs      map[rune]bitmapGlyph
+	ascent      int
+	descent     int
+	defaultChar rune
+}
+
+func newBitmapFace() *bitmapFace {
+	return &bitmapFace{
+		glyphs:      make(map[rune]bitmapGlyph),
+		defaultChar: -1,
+	}
+}
+
+// glyph returns the glyph for r, or the font's default character if r is missing. Like basicfont, GlyphBounds and
+// GlyphAdvance report the default character's size but only return ok for glyphs the font really has.
+func (f *bitmapFace) glyph(r rune) (bitmapGlyph, bool) {
+	glyph, ok := f.glyphs[r]
+	if !ok {
+		glyph, ok = f.glyphs[f.defaultChar]
+	}
+
+	return glyph, ok
+}
+
+func (f *bitmapFace) Close() error {
+	return nil
+}
+
+func (f *bitmapFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6,
+	bool) {
+	glyph, ok := f.glyph(r)
+	if !ok {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+
+	origin := image.Point{X: dot.X.Round(), Y: dot.Y.Round()}
+
+	return glyph.bounds.Add(origin), glyph.mask, image.Point{}, fixed.I(glyph.advance), true
+}
+
+func (f *bitmapFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	glyph, ok := f.glyph(r)
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+
+	bounds := fixed.Rectangle26_6{
+		Min: fixed.P(glyph.bounds.Min.X, glyph.bounds.Min.Y),
+		Max: fixed.P(glyph.bounds.Max.X, glyph.bounds.Max.Y),
+	}
+
+	_, exact := f.glyphs[r]
+
+	return bounds, fixed.I(glyph.advance), exact
+}
+
+func (f *bitmapFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	glyph, ok := f.glyph(r)
+	if !ok {
+		return 0, false
+	}
+
+	_, exact := f.glyphs[r]
+
+	return fixed.I(glyph.advance), exact
+}
+
+func (f *bitmapFace) Kern(_, _ rune) fixed.Int26_6 {
+	return 0
+}
+
+func (f *bitmapFace) Metrics() font.Metrics {
+	return font.Metrics{
+		Height:     fixed.I(f.ascent + f.descent),
+		Ascent:     fixed.I(f.ascent),
+		Descent:    fixed.I(f.descent),
+		CapHeight:  fixed.I(f.ascent),
+		XHeight:    fixed.I(f.ascent),
+		CaretSlope: image.Point{X: 0, Y: 1},
+	}
+}
@@ -0,0 +1,226 @@
+package oled
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tinyGlyphs are the glyphs of the fonts in testdata, drawn with a row of '#' and '.' for each line of the bitmap
+var tinyGlyphs = map[rune]struct {
+	advance int
+	bounds  image.Rectangle
+	rows    []string
+}{
+	'?': {5, image.Rect(1, -6, 4, -2), []string{"###", "..#", ".#.", ".#."}},
+	'A': {5, image.Rect(0, -6, 4, 0), []string{".##.", "#..#", "#..#", "####", "#..#", "#..#"}},
+	'g': {5, image.Rect(1, -3, 4, 2), []string{"###", "#.#", "###", "..#", "###"}},
+}
+
+func readTestFont(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("error reading %s: %s", name, err)
+	}
+
+	return data
+}
+
+func parseTestFont(name string, data []byte) (*bitmapFace, error) {
+	if strings.HasSuffix(name, ".bdf") {
+		return parseBDF(bytes.NewReader(data))
+	}
+
+	return parsePCF(data)
+}
+
+func TestParseBitmapFonts(t *testing.T) {
+	t.Parallel()
+
+	// tiny-be.pcf is big endian with the ascent in its accelerators, tiny-le.pcf is little endian with compressed
+	// metrics and 32 bit padded rows
+	for _, name := range []string{"tiny.bdf", "tiny-be.pcf", "tiny-le.pcf"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			face, err := parseTestFont(name, readTestFont(t, name))
+			if err != nil {
+				t.Fatalf("error parsing: %s", err)
+			}
+
+			if face.ascent != 6 || face.descent != 2 || face.defaultChar != '?' {
+				t.Errorf("got ascent %d, descent %d and default %q, want 6, 2 and '?'", face.ascent, face.descent,
+					face.defaultChar)
+			}
+
+			if len(face.glyphs) != len(tinyGlyphs) {
+				t.Errorf("got %d glyphs, want %d", len(face.glyphs), len(tinyGlyphs))
+			}
+
+			for r, want := range tinyGlyphs {
+				got, ok := face.glyphs[r]
+				if !ok {
+					t.Errorf("%q: missing", r)
+
+					continue
+				}
+
+				if got.advance != want.advance || got.bounds != want.bounds {
+					t.Errorf("%q: got advance %d and bounds %v, want %d and %v", r, got.advance, got.bounds,
+						want.advance, want.bounds)
+				}
+
+				if rows := maskRows(got.mask); !slices.Equal(rows, want.rows) {
+					t.Errorf("%q: got bitmap %q, want %q", r, rows, want.rows)
+				}
+			}
+
+			// missing glyphs are drawn with the default character
+			if glyph, ok := face.glyph('z'); !ok || glyph.bounds != tinyGlyphs['?'].bounds {
+				t.Errorf("got %v for a missing glyph, want the default character", glyph.bounds)
+			}
+		})
+	}
+}
+
+func maskRows(mask *image.Alpha) []string {
+	rows := make([]string, 0, mask.Rect.Dy())
+
+	for y := range mask.Rect.Dy() {
+		var row strings.Builder
+
+		for x := range mask.Rect.Dx() {
+			if mask.AlphaAt(x, y).A != 0 {
+				row.WriteByte('#')
+			} else {
+				row.WriteByte('.')
+			}
+		}
+
+		rows = append(rows, row.String())
+	}
+
+	return rows
+}
+
+// TestTruncatedFonts checks that every part of a font short of the whole thing is rejected without panicking
+func TestTruncatedFonts(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"tiny.bdf", "tiny-be.pcf", "tiny-le.pcf"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := readTestFont(t, name)
+			// the BDF is cut before its final newline too, it's complete without it
+			whole := len(bytes.TrimSpace(data))
+
+			for length := range whole {
+				_, err := parseTestFont(name, data[:length])
+				if err == nil {
+					t.Errorf("got no error for the first %d of %d bytes", length, len(data))
+				}
+			}
+		})
+	}
+}
+
+// TestCorruptPCF checks that overwriting any byte of a font gives an error or a font, never a panic
+func TestCorruptPCF(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"tiny-be.pcf", "tiny-le.pcf"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := readTestFont(t, name)
+
+			for i := range data {
+				for _, value := range []byte{0x00, 0x01, 0x7f, 0x80, 0xff} {
+					corrupt := bytes.Clone(data)
+					corrupt[i] = value
+
+					_, err := parsePCF(corrupt)
+					if err != nil && !errors.Is(err, ErrBadPCF) {
+						t.Errorf("byte %d set to %#x: got error %v, want %v", i, value, err, ErrBadPCF)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMalformedPCF(t *testing.T) {
+	t.Parallel()
+
+	data := readTestFont(t, "tiny-be.pcf")
+
+	tests := []struct {
+		name   string
+		offset int
+		value  []byte
+	}{
+		{"bad magic", 0, []byte("fcp\x01")},
+		{"no tables", 4, []byte{0, 0, 0, 0}},
+		{"too many tables", 4, []byte{0xff, 0xff, 0, 0}},
+		{"table out of range", 8 + 12, []byte{0xff, 0xff, 0, 0}},
+		{"metrics count too big", 8 + 4*16 + 4, []byte{0x00, 0xff, 0xff, 0xff}},
+		{"no metrics table", 8, []byte{0xff, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			corrupt := bytes.Clone(data)
+			copy(corrupt[tt.offset:], tt.value)
+
+			_, err := parsePCF(corrupt)
+			if !errors.Is(err, ErrBadPCF) {
+				t.Errorf("got error %v, want %v", err, ErrBadPCF)
+			}
+		})
+	}
+}
+
+func TestMalformedBDF(t *testing.T) {
+	t.Parallel()
+
+	font := string(readTestFont(t, "tiny.bdf"))
+
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		wantErr error
+	}{
+		{"bad ascent", "FONT_ASCENT 6", "FONT_ASCENT six", ErrBadBDF},
+		{"bad bbx", "BBX 4 6 0 0", "BBX 4 6 0", ErrBadBDF},
+		{"bad encoding", "ENCODING 65", "ENCODING", ErrBadBDF},
+		{"bad bitmap row", "F0\n", "FZ\n", ErrBadBDF},
+		{"missing bitmap row", "F0\n", "", ErrBadBDF},
+		{"extra bitmap row", "F0\n", "F0\nF0\n", ErrBadBDF},
+		{"narrow bitmap row", "BBX 4 6 0 0", "BBX 9 6 0 0", ErrBadBDF},
+		{"bitmap outside glyph", "STARTCHAR A\n", "BITMAP\nSTARTCHAR A\n", ErrBadBDF},
+		{"missing endchar", "ENDCHAR\nSTARTCHAR A", "STARTCHAR A", ErrBadBDF},
+		{"no glyphs", "CHARS 3", "CHARS 0\nENDFONT", ErrBadBDF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseBDF(strings.NewReader(strings.Replace(font, tt.old, tt.new, 1)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package oled
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Align is the horizontal placement of text
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Line is one row of text. Text is placed according to Align and made to fit by Overflow, Right is drawn right-aligned
+// on the same row. Font names a font added with RegisterFont, the default font is used if it's empty or unknown.
+type Line struct {
+	Text     string
+	Right    string
+	Font     string
+	Align    Align
+	Overflow Overflow
+	// Scroll is how many pixels text too wide for the line is moved left when Overflow is OverflowScroll
+	Scroll int
+}
+
+// topMargin keeps the first line of the default font at the same position it has always been drawn at
+const topMargin = 4
+
+var (
+	white = &image.Uniform{C: color.White}
+	black = &image.Uniform{C: color.Black}
+)
+
+// UpdateDisplayLines draws each string as a line in the default font, stopping at the first empty string
+func UpdateDisplayLines(dispLines []string, oled *Display) {
+	lines := make([]Line, 0, len(dispLines))
+
+	for _, line := range dispLines {
+		if line == "" {
+			break
+		}
+
+		lines = append(lines, Line{Text: line})
+	}
+
+	UpdateDisplay(lines, oled)
+}
+
+// UpdateDisplay draws lines top to bottom, each using its own font, skipping any that don't fit on the display
+func UpdateDisplay(lines []Line, oled *Display) {
+	Render(oled, func(dst draw.Image) {
+		DrawLines(dst, lines)
+	})
+}
+
+// Render clears the frame, calls drawFn to draw it and sends it to the display
+func Render(oled *Display, drawFn func(dst draw.Image)) {
+	// skip this frame if the previous one is still being written
+	if !oled.mu.TryLock() {
+		return
+	}
+	defer oled.mu.Unlock()
+
+	start := time.Now()
+
+	draw.Draw(oled.Img, oled.Img.Bounds(), black, image.Point{}, draw.Src)
+
+	drawFn(oled.Img)
+
+	oled.frames++
+	oled.flush()
+
+	if oled.frameObserver != nil {
+		oled.frameObserver(time.Since(start))
+	}
+}
+
+// DrawLines draws lines top to bottom, each using its own font, skipping any that don't fit
+func DrawLines(dst draw.Image, lines []Line) {
+	top := topMargin
+
+	for _, line := range lines {
+		face := Face(line.Font)
+		metrics := face.Metrics()
+
+		baseline := top + metrics.Ascent.Ceil()
+		if baseline > dst.Bounds().Max.Y {
+			break
+		}
+
+		drawLine(dst, face, line, baseline)
+
+		top += metrics.Height.Ceil()
+	}
+}
+
+func drawLine(dst draw.Image, face font.Face, line Line, baseline int) {
+	width := dst.Bounds().Dx()
+
+	if line.Right != "" {
+		rightWidth := MeasureString(face, line.Right)
+		drawString(dst, face, line.Right, width-rightWidth, baseline)
+
+		width -= rightWidth
+	}
+
+	drawOverflow(dst, face, line, image.Rect(0, baseline-face.Metrics().Ascent.Ceil(), width, dst.Bounds().Max.Y),
+		baseline)
+}
+
+// drawOverflow draws the text of line inside rect, making it fit as the line's Overflow says
+func drawOverflow(dst draw.Image, face font.Face, line Line, rect image.Rectangle, baseline int) {
+	text := line.Text
+
+	switch line.Overflow {
+	case OverflowScroll:
+		overflow := MeasureString(face, text) - rect.Dx()
+		if overflow > 0 {
+			drawString(clip(dst, rect), face, text, rect.Min.X-min(max(line.Scroll, 0), overflow), baseline)
+
+			return
+		}
+	case OverflowShrink:
+		face = shrinkToFit(face, text, rect.Dx())
+		text = Truncate(face, text, rect.Dx())
+	case OverflowEllipsis:
+		text = Truncate(face, text, rect.Dx())
+	}
+
+	drawString(dst, face, text, alignX(face, text, rect.Min.X, rect.Dx(), line.Align), baseline)
+}
+
+func drawString(dst draw.Image, face font.Face, text string, x int, baseline int) {
+	drawer := &font.Drawer{
+		Dst:  dst,
+		Src:  white,
+		Face: face,
+		Dot:  fixed.P(x, baseline),
+	}
+
+	drawer.DrawString(text)
+}
+
+// alignX returns the x position text should start at to be aligned within width pixels starting at left
+func alignX(face font.Face, text string, left int, width int, align Align) int {
+	switch align {
+	case AlignCenter:
+		return left + (width-MeasureString(face, text))/2
+	case AlignRight:
+		return left + width - MeasureString(face, text)
+	case AlignLeft:
+	}
+
+	return left
+}
+
+// DrawText draws a single line of text inside rect, truncating it to fit. The text is placed at the top of rect.
+func DrawText(dst draw.Image, face font.Face, text string, rect image.Rectangle, align Align) {
+	DrawTextLine(dst, face, Line{Text: text, Align: align}, rect)
+}
+
+// DrawTextLine draws the text of line inside rect in face, making it fit as the line's Overflow says. The text is
+// placed at the top of rect, the line's Font and Right are ignored.
+func DrawTextLine(dst draw.Image, face font.Face, line Line, rect image.Rectangle) {
+	drawOverflow(clip(dst, rect), face, line, rect, rect.Min.Y+face.Metrics().Ascent.Ceil())
+}
+
+// DrawBar draws an outlined bar inside rect filled from the left by fraction, which is clamped to 0 to 1
+func DrawBar(dst draw.Image, rect image.Rectangle, fraction float64) {
+	if rect.Dx() < 3 || rect.Dy() < 3 {
+		return
+	}
+
+	fraction = min(max(fraction, 0), 1)
+
+	drawOutline(dst, rect)
+
+	inner := rect.Inset(1)
+	inner.Max.X = inner.Min.X + int(float64(inner.Dx())*fraction+0.5)
+
+	draw.Draw(dst, inner, white, image.Point{}, draw.Src)
+}
+
+// DrawSparkline draws values as a line graph inside rect, the most recent value on the right. If minValue and maxValue
+// are equal the range is taken from values.
+func DrawSparkline(dst draw.Image, rect image.Rectangle, values []float64, minValue, maxValue float64) {
+	if len(values) == 0 || rect.Empty() {
+		return
+	}
+
+	if len(values) > rect.Dx() {
+		values = values[len(values)-rect.Dx():]
+	}
+
+	if minValue == maxValue {
+		minValue, maxValue = values[0], values[0]
+
+		for _, value := range values {
+			minValue = min(minValue, value)
+			maxValue = max(maxValue, value)
+		}
+	}
+
+	scaleY := func(value float64) int {
+		if maxValue == minValue {
+			return rect.Max.Y - 1
+		}
+
+		fraction := min(max((value-minValue)/(maxValue-minValue), 0), 1)
+
+		return rect.Max.Y - 1 - int(fraction*float64(rect.Dy()-1)+0.5)
+	}
+
+	x := rect.Max.X - len(values)
+	prevY := scaleY(values[0])
+
+	for _, value := range values {
+		y := scaleY(value)
+
+		// join to the previous point so steep changes stay connected
+		for step := min(y, prevY); step <= max(y, prevY); step++ {
+			dst.Set(x, step, color.White)
+		}
+
+		prevY = y
+		x++
+	}
+}
+
+// DrawMask draws the set pixels of mask with its top left corner at point
+func DrawMask(dst draw.Image, mask image.Image, point image.Point) {
+	bounds := mask.Bounds()
+
+	draw.DrawMask(dst, bounds.Sub(bounds.Min).Add(point), white, image.Point{}, mask, bounds.Min, draw.Over)
+}
+
+func drawOutline(dst draw.Image, rect image.Rectangle) {
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		dst.Set(x, rect.Min.Y, color.White)
+		dst.Set(x, rect.Max.Y-1, color.White)
+	}
+
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		dst.Set(rect.Min.X, y, color.White)
+		dst.Set(rect.Max.X-1, y, color.White)
+	}
+}
+
+// clippedImage restricts drawing to a rectangle
+type clippedImage struct {
+	draw.Image
+
+	rect image.Rectangle
+}
+
+func (c *clippedImage) Bounds() image.Rectangle {
+	return c.rect
+}
+
+func (c *clippedImage) Set(x, y int, col color.Color) {
+	if (image.Point{X: x, Y: y}).In(c.rect) {
+		c.Image.Set(x, y, col)
+	}
+}
+
+func clip(dst draw.Image, rect image.Rectangle) draw.Image {
+	return &clippedImage{Image: dst, rect: rect.Intersect(dst.Bounds())}
+}
+
+// DrawLine draws a one pixel line between two points
+func DrawLine(dst draw.Image, from image.Point, to image.Point) {
+	dx := abs(to.X - from.X)
+	dy := -abs(to.Y - from.Y)
+
+	stepX, stepY := 1, 1
+	if from.X > to.X {
+		stepX = -1
+	}
+
+	if from.Y > to.Y {
+		stepY = -1
+	}
+
+	errSum := dx + dy
+	x, y := from.X, from.Y
+
+	for {
+		dst.Set(x, y, color.White)
+
+		if x == to.X && y == to.Y {
+			return
+		}
+
+		doubled := 2 * errSum
+		if doubled >= dy {
+			errSum += dy
+			x += stepX
+		}
+
+		if doubled <= dx {
+			errSum += dx
+			y += stepY
+		}
+	}
+}
+
+// DrawCircle draws a one pixel circle outline
+func DrawCircle(dst draw.Image, center image.Point, radius int) {
+	x, y := radius, 0
+	errSum := 1 - radius
+
+	for x >= y {
+		for _, point := range []image.Point{
+			{X: x, Y: y}, {X: y, Y: x}, {X: -y, Y: x}, {X: -x, Y: y},
+			{X: -x, Y: -y}, {X: -y, Y: -x}, {X: y, Y: -x}, {X: x, Y: -y},
+		} {
+			dst.Set(center.X+point.X, center.Y+point.Y, color.White)
+		}
+
+		y++
+
+		if errSum < 0 {
+			errSum += 2*y + 1
+		} else {
+			x--
+			errSum += 2*(y-x) + 1
+		}
+	}
+}
+
+// DrawRect draws the outline of rect
+func DrawRect(dst draw.Image, rect image.Rectangle) {
+	drawOutline(dst, rect)
+}
+
+// FillRect sets every pixel in rect
+func FillRect(dst draw.Image, rect image.Rectangle) {
+	draw.Draw(dst, rect, white, image.Point{}, draw.Src)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+// Invert swaps lit and unlit pixels across the whole of dst
+func Invert(dst draw.Image) {
+	bounds := dst.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// the display is monochrome so any channel will do
+			if red, _, _, _ := dst.At(x, y).RGBA(); red >= 0x8000 {
+				dst.Set(x, y, color.Black)
+			} else {
+				dst.Set(x, y, color.White)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package oled
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/inconsolata"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var ErrUnknownFontType = errors.New("unknown font type")
+
+const (
+	// DefaultFont is used for lines that don't name a font, or name one that isn't registered
+	DefaultFont = "default"
+
+	defaultTTFSize = 12
+	largeFontSize  = 20
+	ellipsis       = "…"
+	asciiEllipsis  = ".."
+)
+
+var fontsMu sync.RWMutex
+
+// fonts holds every font wrapped so that it can also draw icons
+var fonts = map[string]font.Face{
+	DefaultFont: withIcons(basicfont.Face7x13),
+	"7x13":      withIcons(basicfont.Face7x13),
+	"8x16":      withIcons(inconsolata.Regular8x16),
+	"8x16bold":  withIcons(inconsolata.Bold8x16),
+	"large":     withIcons(mustTTFFace(gomonobold.TTF, largeFontSize)),
+}
+
+func mustTTFFace(data []byte, size float64) font.Face {
+	face, err := ttfFace(data, size)
+	if err != nil {
+		panic(err)
+	}
+
+	return face
+}
+
+func ttfFace(data []byte, size float64) (font.Face, error) {
+	parsed, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing font: %w", err)
+	}
+
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating font face: %w", err)
+	}
+
+	return face, nil
+}
+
+// LoadFont loads a BDF, PCF, TTF or OTF font. size is the pixel size TTF and OTF fonts are rasterised at and is
+// ignored for bitmap fonts.
+func LoadFont(path string, size float64) (font.Face, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading font: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".bdf":
+		return parseBDF(bytes.NewReader(data))
+	case ".pcf":
+		return parsePCF(data)
+	case ".ttf", ".otf":
+		if size <= 0 {
+			size = defaultTTFSize
+		}
+
+		return ttfFace(data, size)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFontType, path)
+	}
+}
+
+// RegisterFont makes face available to lines by name, replacing any font already using that name
+func RegisterFont(name string, face font.Face) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	fonts[name] = withIcons(face)
+}
+
+// Face returns the font registered as name, or the default font. The font can also draw the strings returned by Icon.
+func Face(name string) font.Face {
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+
+	face, ok := fonts[name]
+	if !ok {
+		return fonts[DefaultFont]
+	}
+
+	return face
+}
+
+// MeasureString returns the width of text in pixels
+func MeasureString(face font.Face, text string) int {
+	return font.MeasureString(face, text).Ceil()
+}
+
+// shrinkToFit returns the largest registered font no taller than face that text fits in width pixels with, or if
+// there isn't one the font it's narrowest in
+func shrinkToFit(face font.Face, text string, width int) font.Face {
+	if MeasureString(face, text) <= width {
+		return face
+	}
+
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+
+	best, bestHeight, bestWidth := face, fixed.Int26_6(0), 0
+	narrowest, narrowestWidth := face, MeasureString(face, text)
+	maxHeight := face.Metrics().Height
+
+	for _, candidate := range fonts {
+		height := candidate.Metrics().Height
+		if height > maxHeight {
+			continue
+		}
+
+		textWidth := MeasureString(candidate, text)
+
+		if textWidth < narrowestWidth {
+			narrowest, narrowestWidth = candidate, textWidth
+		}
+
+		// of fonts the same height the one filling the most of the line is the least shrunk
+		if textWidth > width || height < bestHeight || (height == bestHeight && textWidth <= bestWidth) {
+			continue
+		}
+
+		best, bestHeight, bestWidth = candidate, height, textWidth
+	}
+
+	if bestHeight == 0 {
+		return narrowest
+	}
+
+	return best
+}
+
+// Truncate shortens text to fit in width pixels, ending it with an ellipsis if anything was removed
+func Truncate(face font.Face, text string, width int) string {
+	if MeasureString(face, text) <= width {
+		return text
+	}
+
+	suffix := ellipsis
+	if _, ok := face.GlyphAdvance([]rune(ellipsis)[0]); !ok {
+		suffix = asciiEllipsis
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+
+		candidate := strings.TrimRight(string(runes), " ") + suffix
+		if MeasureString(face, candidate) <= width {
+			return candidate
+		}
+	}
+
+	return ""
+}
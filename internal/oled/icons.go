@@ -0,0 +1,204 @@
+package oled
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	iconSize = 8
+	// iconRuneBase is the first Unicode private use code point, icons are drawn in place of these runes
+	iconRuneBase = '\uE000'
+)
+
+// iconRows are 8x8 bitmaps, one byte per row with the most significant bit on the left
+var iconRows = map[string][iconSize]byte{
+	"airplane":    {0x18, 0x18, 0x7E, 0xFF, 0x18, 0x18, 0x3C, 0x00},
+	"helicopter":  {0x7E, 0x10, 0x70, 0xF9, 0xFE, 0x70, 0x48, 0xFC},
+	"up":          {0x18, 0x3C, 0x7E, 0xFF, 0x18, 0x18, 0x18, 0x18},
+	"down":        {0x18, 0x18, 0x18, 0x18, 0xFF, 0x7E, 0x3C, 0x18},
+	"warning":     {0x18, 0x3C, 0x24, 0x66, 0x66, 0xFF, 0xE7, 0xFF},
+	"thermometer": {0x18, 0x24, 0x24, 0x2C, 0x3C, 0x7E, 0x7E, 0x3C},
+	"check":       {0x00, 0x01, 0x03, 0x86, 0xCC, 0x78, 0x30, 0x00},
+	"cross":       {0xC3, 0xE7, 0x7E, 0x3C, 0x3C, 0x7E, 0xE7, 0xC3},
+	"arrow-ne":    {0x1F, 0x07, 0x0D, 0x19, 0x30, 0x60, 0xC0, 0x80},
+	"clock":       {0x3C, 0x52, 0x91, 0x91, 0x9D, 0x81, 0x42, 0x3C},
+	"star":        {0x18, 0x18, 0xFF, 0x7E, 0x3C, 0x7E, 0x66, 0xC3},
+}
+
+// arrowDirections are the directions of the arrow icons, clockwise from north
+var arrowDirections = []string{"n", "ne", "e", "se", "s", "sw", "w", "nw"}
+
+// icons are the masks of all icons, including the arrows in each direction
+var icons, iconNames = buildIcons()
+
+func buildIcons() (map[string]*image.Alpha, []string) {
+	masks := make(map[string]*image.Alpha, len(iconRows)+len(arrowDirections))
+	names := make([]string, 0, len(iconRows)+len(arrowDirections))
+
+	// keep the rune for each icon stable by adding them in a fixed order
+	for _, name := range []string{
+		"airplane", "helicopter", "up", "down", "warning", "thermometer", "check", "cross",
+	} {
+		masks[name] = rowsToMask(iconRows[name])
+		names = append(names, name)
+	}
+
+	masks["clock"] = rowsToMask(iconRows["clock"])
+	names = append(names, "clock")
+
+	// the other arrows are the north and north east arrows turned a quarter at a time
+	for i, direction := range arrowDirections {
+		mask := masks["up"]
+		if i%2 == 1 {
+			mask = rowsToMask(iconRows["arrow-ne"])
+		}
+
+		for range i / 2 {
+			mask = rotateMask(mask)
+		}
+
+		masks["arrow-"+direction] = mask
+		names = append(names, "arrow-"+direction)
+	}
+
+	masks["star"] = rowsToMask(iconRows["star"])
+	names = append(names, "star")
+
+	return masks, names
+}
+
+func rowsToMask(rows [iconSize]byte) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, iconSize, iconSize))
+
+	for y, row := range rows {
+		for x := range iconSize {
+			if row&(0x80>>x) != 0 {
+				mask.Pix[y*mask.Stride+x] = 0xFF
+			}
+		}
+	}
+
+	return mask
+}
+
+// rotateMask turns a square mask a quarter turn clockwise
+func rotateMask(mask *image.Alpha) *image.Alpha {
+	size := mask.Bounds().Dx()
+	rotated := image.NewAlpha(image.Rect(0, 0, size, size))
+
+	for y := range size {
+		for x := range size {
+			rotated.Pix[y*rotated.Stride+x] = mask.Pix[(size-1-x)*mask.Stride+y]
+		}
+	}
+
+	return rotated
+}
+
+// ArrowIconName gives the name of the arrow icon closest to pointing along bearing, in degrees from north
+func ArrowIconName(bearing float64) string {
+	bearing = math.Mod(math.Mod(bearing, 360)+360, 360)
+	index := int(math.Round(bearing/(360/float64(len(arrowDirections))))) % len(arrowDirections)
+
+	return "arrow-" + arrowDirections[index]
+}
+
+// IconNames lists the built-in icons
+func IconNames() []string {
+	return append([]string(nil), iconNames...)
+}
+
+// IconMask returns the mask of the named icon
+func IconMask(name string) (image.Image, bool) {
+	mask, ok := icons[name]
+
+	return mask, ok
+}
+
+// DrawIcon draws the named icon with its top left corner at point
+func DrawIcon(dst draw.Image, name string, point image.Point) {
+	mask, ok := icons[name]
+	if !ok {
+		return
+	}
+
+	DrawMask(dst, mask, point)
+}
+
+// Icon returns a string that is drawn as the named icon when it is part of the text of a line or text widget. The
+// icon sits on the baseline and is followed by a one pixel gap. Unknown icons give an empty string.
+func Icon(name string) string {
+	for i, iconName := range iconNames {
+		if iconName == name {
+			return string(rune(iconRuneBase + i))
+		}
+	}
+
+	return ""
+}
+
+func iconForRune(r rune) (*image.Alpha, bool) {
+	index := int(r - iconRuneBase)
+	if index < 0 || index >= len(iconNames) {
+		return nil, false
+	}
+
+	return icons[iconNames[index]], true
+}
+
+// iconFace draws icons in place of the runes returned by Icon, everything else comes from the wrapped face
+type iconFace struct {
+	font.Face
+}
+
+func withIcons(face font.Face) font.Face {
+	if _, ok := face.(*iconFace); ok {
+		return face
+	}
+
+	return &iconFace{Face: face}
+}
+
+func (f *iconFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6,
+	bool) {
+	mask, ok := iconForRune(r)
+	if !ok {
+		return f.Face.Glyph(dot, r)
+	}
+
+	x, y := dot.X.Round(), dot.Y.Round()
+
+	return image.Rect(x, y-iconSize, x+iconSize, y), mask, image.Point{}, fixed.I(iconSize + 1), true
+}
+
+func (f *iconFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	if _, ok := iconForRune(r); !ok {
+		return f.Face.GlyphBounds(r)
+	}
+
+	return fixed.R(0, -iconSize, iconSize, 0), fixed.I(iconSize + 1), true
+}
+
+func (f *iconFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	if _, ok := iconForRune(r); !ok {
+		return f.Face.GlyphAdvance(r)
+	}
+
+	return fixed.I(iconSize + 1), true
+}
+
+func (f *iconFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	_, icon0 := iconForRune(r0)
+	_, icon1 := iconForRune(r1)
+
+	if icon0 || icon1 {
+		return 0
+	}
+
+	return f.Face.Kern(r0, r1)
+}
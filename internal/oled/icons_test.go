@@ -0,0 +1,135 @@
+package oled
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestArrowIconName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		bearing float64
+		want    string
+	}{
+		{0, "arrow-n"},
+		{22.4, "arrow-n"},
+		{22.5, "arrow-ne"},
+		{45, "arrow-ne"},
+		{90, "arrow-e"},
+		{135, "arrow-se"},
+		{180, "arrow-s"},
+		{225, "arrow-sw"},
+		{270, "arrow-w"},
+		{315, "arrow-nw"},
+		// the boundary between nw and n, and the wrap past 360
+		{337.4, "arrow-nw"},
+		{337.5, "arrow-n"},
+		{359.9, "arrow-n"},
+		{360, "arrow-n"},
+		{450, "arrow-e"},
+		{-45, "arrow-nw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.bearing), func(t *testing.T) {
+			t.Parallel()
+
+			got := ArrowIconName(tt.bearing)
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+
+			if _, ok := IconMask(got); !ok {
+				t.Errorf("no icon named %s", got)
+			}
+		})
+	}
+}
+
+func TestIconRunes(t *testing.T) {
+	t.Parallel()
+
+	seen := make(map[rune]string)
+
+	for _, name := range IconNames() {
+		icon := Icon(name)
+
+		r, size := utf8.DecodeRuneInString(icon)
+		if size != len(icon) || !unicode.In(r, unicode.Co) {
+			t.Errorf("%s: got %q, want a single private use rune", name, icon)
+
+			continue
+		}
+
+		if other, ok := seen[r]; ok {
+			t.Errorf("%s: got %U, the same as %s", name, r, other)
+		}
+
+		seen[r] = name
+
+		// the rune draws the named icon
+		mask, ok := IconMask(name)
+		if drawn, isIcon := iconForRune(r); !ok || !isIcon || drawn != mask {
+			t.Errorf("%s: got %U drawn as another icon", name, r)
+		}
+	}
+
+	if icon := Icon("unknown"); icon != "" {
+		t.Errorf("got %q for an unknown icon, want an empty string", icon)
+	}
+}
+
+func TestRotateMask(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		rows [iconSize]byte
+		want [iconSize]byte
+	}{
+		{
+			name: "top edge to right edge",
+			rows: [iconSize]byte{0xFF, 0, 0, 0, 0, 0, 0, 0},
+			want: [iconSize]byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01},
+		},
+		{
+			name: "top left corner to top right corner",
+			rows: [iconSize]byte{0xC0, 0x80, 0, 0, 0, 0, 0, 0},
+			want: [iconSize]byte{0x03, 0x01, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "left edge to top edge",
+			rows: [iconSize]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80},
+			want: [iconSize]byte{0xFF, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "north arrow to east arrow",
+			rows: iconRows["up"],
+			want: [iconSize]byte{0x08, 0x0C, 0x0E, 0xFF, 0xFF, 0x0E, 0x0C, 0x08},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := rotateMask(rowsToMask(tt.rows))
+			if want := maskRows(rowsToMask(tt.want)); !slices.Equal(maskRows(got), want) {
+				t.Errorf("got %q, want %q", maskRows(got), want)
+			}
+
+			// four turns is all the way round
+			for range 3 {
+				got = rotateMask(got)
+			}
+
+			if want := maskRows(rowsToMask(tt.rows)); !slices.Equal(maskRows(got), want) {
+				t.Errorf("got %q after four turns, want %q", maskRows(got), want)
+			}
+		})
+	}
+}
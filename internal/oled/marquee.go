@@ -0,0 +1,144 @@
+package oled
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrUnknownOverflow = errors.New("unknown overflow")
+
+// Overflow is what a line does with text too wide for it
+type Overflow int
+
+const (
+	// OverflowEllipsis cuts the text short, ending it with an ellipsis
+	OverflowEllipsis Overflow = iota
+	// OverflowScroll moves the text left by the line's Scroll so all of it can be read in turn
+	OverflowScroll
+	// OverflowShrink draws the text in the largest registered font no taller than the line's font that it fits in,
+	// or the one it's narrowest in cut short if there isn't one
+	OverflowShrink
+)
+
+const (
+	// DefaultScrollSpeed is how fast text scrolls in pixels a second
+	DefaultScrollSpeed = 30.0
+	// DefaultScrollPause is how long scrolling text stays still at each end
+	DefaultScrollPause = time.Second
+)
+
+// ParseOverflow converts "ellipsis", "scroll" or "shrink" to an Overflow, an empty string is OverflowEllipsis
+func ParseOverflow(name string) (Overflow, error) {
+	switch name {
+	case "", "ellipsis":
+		return OverflowEllipsis, nil
+	case "scroll":
+		return OverflowScroll, nil
+	case "shrink":
+		return OverflowShrink, nil
+	default:
+		return OverflowEllipsis, fmt.Errorf("%w: %q", ErrUnknownOverflow, name)
+	}
+}
+
+// Marquee works out how far a scrolling line has moved. The text waits at its start for Pause, scrolls left at Speed
+// until its end is showing, waits there for Pause and then starts again. It starts from the beginning whenever the
+// text changes.
+type Marquee struct {
+	// Speed is in pixels a second, DefaultScrollSpeed if it isn't set
+	Speed float64
+	// Pause is how long the text waits at each end, DefaultScrollPause if it isn't set
+	Pause time.Duration
+
+	text    string
+	started time.Time
+}
+
+// Scroll sets how far line has moved at now if it scrolls, measuring it in its font against width pixels less the
+// width of its Right
+func (m *Marquee) Scroll(line Line, width int, now time.Time) Line {
+	if line.Overflow != OverflowScroll {
+		return line
+	}
+
+	line.Scroll = m.Offset(line.Text, overflow(line, width), now)
+
+	return line
+}
+
+// Cycle gives how long line takes to scroll to its end and back at the start, pausing at each end, or zero if it
+// doesn't scroll
+func (m *Marquee) Cycle(line Line, width int) time.Duration {
+	if line.Overflow != OverflowScroll {
+		return 0
+	}
+
+	return m.cycle(overflow(line, width))
+}
+
+// overflow is how many pixels line's text is too wide for width pixels less the width of its Right
+func overflow(line Line, width int) int {
+	face := Face(line.Font)
+
+	if line.Right != "" {
+		width -= MeasureString(face, line.Right)
+	}
+
+	return MeasureString(face, line.Text) - width
+}
+
+// Offset gives how many pixels to move text left at now, overflow being how many pixels too wide it is
+func (m *Marquee) Offset(text string, overflow int, now time.Time) int {
+	if text != m.text || m.started.IsZero() {
+		m.text = text
+		m.started = now
+	}
+
+	if overflow <= 0 {
+		return 0
+	}
+
+	speed, pause := m.speed(), m.pause()
+	scrolling := m.scrolling(overflow)
+	elapsed := now.Sub(m.started) % m.cycle(overflow)
+
+	switch {
+	case elapsed < pause:
+		return 0
+	case elapsed < pause+scrolling:
+		return min(int((elapsed-pause).Seconds()*speed), overflow)
+	default:
+		return overflow
+	}
+}
+
+func (m *Marquee) speed() float64 {
+	if m.Speed <= 0 {
+		return DefaultScrollSpeed
+	}
+
+	return m.Speed
+}
+
+func (m *Marquee) pause() time.Duration {
+	if m.Pause <= 0 {
+		return DefaultScrollPause
+	}
+
+	return m.Pause
+}
+
+// scrolling is how long it takes to scroll overflow pixels
+func (m *Marquee) scrolling(overflow int) time.Duration {
+	return time.Duration(float64(overflow) / m.speed() * float64(time.Second))
+}
+
+// cycle is how long text overflow pixels too wide takes to scroll there and back with the pauses, zero if it fits
+func (m *Marquee) cycle(overflow int) time.Duration {
+	if overflow <= 0 {
+		return 0
+	}
+
+	return m.pause() + m.scrolling(overflow) + m.pause()
+}
@@ -0,0 +1,128 @@
+package oled
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMarqueeOffset(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	// 60 pixels too wide at 30 pixels a second takes 2 seconds to scroll, a cycle with the pauses is 4 seconds
+	marquee := &Marquee{Speed: 30, Pause: time.Second}
+
+	steps := []struct {
+		name     string
+		after    time.Duration
+		text     string
+		overflow int
+		want     int
+	}{
+		{"starts still", 0, "long", 60, 0},
+		{"still paused", 999 * time.Millisecond, "long", 60, 0},
+		{"scrolling", 1500 * time.Millisecond, "long", 60, 15},
+		{"nearly there", 2900 * time.Millisecond, "long", 60, 57},
+		{"paused at the end", 3 * time.Second, "long", 60, 60},
+		{"still at the end", 3900 * time.Millisecond, "long", 60, 60},
+		{"back to the start", 4 * time.Second, "long", 60, 0},
+		{"scrolling again", 6 * time.Second, "long", 60, 30},
+		{"new text starts again", 6500 * time.Millisecond, "longer", 90, 0},
+		{"new text scrolls", 8500 * time.Millisecond, "longer", 90, 30},
+		{"fits", 9 * time.Second, "longer", 0, 0},
+		{"fits after all", 9 * time.Second, "short", -10, 0},
+	}
+
+	for _, step := range steps {
+		if got := marquee.Offset(step.text, step.overflow, start.Add(step.after)); got != step.want {
+			t.Errorf("%s: got %d, want %d", step.name, got, step.want)
+		}
+	}
+}
+
+func TestMarqueeDefaults(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var marquee Marquee
+
+	marquee.Offset("text", 100, start)
+
+	after := DefaultScrollPause + time.Second
+	if got := marquee.Offset("text", 100, start.Add(after)); got != int(DefaultScrollSpeed) {
+		t.Errorf("got %d after %s, want %d", got, after, int(DefaultScrollSpeed))
+	}
+}
+
+func TestMarqueeScroll(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	line := Line{Text: "a line much too wide for the space it has", Overflow: OverflowScroll}
+
+	var marquee Marquee
+
+	marquee.Scroll(line, 20, start)
+
+	if got := marquee.Scroll(line, 20, start.Add(2*time.Second)).Scroll; got != int(DefaultScrollSpeed) {
+		t.Errorf("got scroll %d, want %d", got, int(DefaultScrollSpeed))
+	}
+
+	line.Overflow = OverflowEllipsis
+
+	if got := marquee.Scroll(line, 20, start.Add(2*time.Second)).Scroll; got != 0 {
+		t.Errorf("got scroll %d for a line that doesn't scroll, want 0", got)
+	}
+}
+
+func TestMarqueeCycle(t *testing.T) {
+	t.Parallel()
+
+	marquee := &Marquee{Speed: 30, Pause: time.Second}
+	line := Line{Text: "a line much too wide for the space it has", Overflow: OverflowScroll}
+	width := MeasureString(Face(line.Font), line.Text) - 60
+
+	// 60 pixels at 30 pixels a second with a second's pause at each end
+	if got := marquee.Cycle(line, width); got != 4*time.Second {
+		t.Errorf("got a cycle of %s, want 4s", got)
+	}
+
+	if got := marquee.Cycle(line, width+60); got != 0 {
+		t.Errorf("got a cycle of %s for a line that fits, want 0", got)
+	}
+
+	line.Overflow = OverflowEllipsis
+
+	if got := marquee.Cycle(line, width); got != 0 {
+		t.Errorf("got a cycle of %s for a line that doesn't scroll, want 0", got)
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		want    Overflow
+		wantErr error
+	}{
+		{"", OverflowEllipsis, nil},
+		{"ellipsis", OverflowEllipsis, nil},
+		{"scroll", OverflowScroll, nil},
+		{"shrink", OverflowShrink, nil},
+		{"wrap", OverflowEllipsis, ErrUnknownOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseOverflow(tt.name)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %d and error %v, want %d and %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
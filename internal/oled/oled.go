@@ -5,74 +5,397 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"sync"
+	"time"
 
 	goi2coled "github.com/waxdred/go-i2c-oled"
 	"github.com/waxdred/go-i2c-oled/ssd1306"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
-	"golang.org/x/image/math/fixed"
 )
 
-func InitDisplay() *goi2coled.I2c {
-	// Initialize the OLED display with the provided parameters
-	oled, err := goi2coled.NewI2c(ssd1306.SSD1306_SWITCHCAPVCC, 64, 128, 0x3C, 1)
+// Width and Height are the size of the display in pixels
+const (
+	Width  = 128
+	Height = 64
+)
+
+const (
+	displayAddress = 0x3C
+	displayBus     = 1
+
+	defaultContrast = 0xCF
+
+	// failureThreshold is the number of consecutive failed writes before the controller is re-initialised
+	failureThreshold = 3
+	initialBackoff   = 1 * time.Second
+	maxBackoff       = 1 * time.Minute
+
+	cmdSegRemapNormal  = 0xA1
+	cmdSegRemapFlipped = 0xA0
+	cmdComScanNormal   = 0xC8
+	cmdComScanFlipped  = 0xC0
+)
+
+// HealthState describes whether frames are reaching the panel
+type HealthState string
+
+const (
+	HealthOK         HealthState = "ok"
+	HealthDegraded   HealthState = "degraded"
+	HealthRecovering HealthState = "recovering"
+	HealthHeadless   HealthState = "headless"
+)
+
+// Health is a snapshot of the display's I2C health
+type Health struct {
+	State             HealthState `json:"state"`
+	ConsecutiveErrors int         `json:"consecutive_errors"`
+	TotalErrors       int         `json:"total_errors"`
+	Reinits           int         `json:"reinits"`
+	LastError         string      `json:"last_error,omitempty"`
+	LastErrorTime     time.Time   `json:"last_error_time,omitzero"`
+}
+
+// device is the part of the SSD1306 controller the display uses, so tests can stand in for the panel
+type device interface {
+	SetContrast(contrast int) error
+	WriteCommand(cmd byte) (int, error)
+	DisplayOn() (int, error)
+	DisplayOff() (int, error)
+	Close() error
+	// Send writes a frame to the panel
+	Send(frame image.Image) error
+}
+
+// i2cDevice is an SSD1306 controller on the I2C bus
+type i2cDevice struct {
+	*goi2coled.I2c
+}
+
+func (dev i2cDevice) Send(frame image.Image) error {
+	draw.Draw(dev.Img, dev.Img.Bounds(), frame, image.Point{}, draw.Src)
+	dev.Draw()
+
+	err := dev.Display()
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("error writing frame: %w", err)
 	}
 
-	_, err = oled.DisplayOn()
+	return nil
+}
+
+func openI2C() (device, error) {
+	// Initialize the OLED display with the provided parameters
+	dev, err := goi2coled.NewI2c(ssd1306.SSD1306_SWITCHCAPVCC, Height, Width, displayAddress, displayBus)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("error opening display: %w", err)
+	}
+
+	return i2cDevice{I2c: dev}, nil
+}
+
+// Display wraps the SSD1306 controller. Frames are always rendered into Img, so callers keep working while the
+// panel is missing or being recovered.
+type Display struct {
+	mu sync.Mutex
+
+	dev device
+	// openDevice initialises the controller
+	openDevice func() (device, error)
+	// now is the time, for backing off between attempts to re-initialise the controller
+	now func() time.Time
+
+	Img draw.Image
+	// frames counts the frames rendered into Img
+	frames uint64
+	// frameObserver is told how long each frame took to draw and send to the panel
+	frameObserver func(took time.Duration)
+
+	contrast int
+	flipped  bool
+	on       bool
+
+	health      Health
+	backoff     time.Duration
+	nextAttempt time.Time
+}
+
+func InitDisplay() *Display {
+	return newDisplay(openI2C, time.Now)
+}
+
+func newDisplay(openDevice func() (device, error), now func() time.Time) *Display {
+	display := &Display{
+		openDevice: openDevice,
+		now:        now,
+		Img:        image.NewRGBA(image.Rect(0, 0, Width, Height)),
+		contrast:   defaultContrast,
+		on:         true,
+		backoff:    initialBackoff,
 	}
 
 	// Set the entire OLED image to black
-	draw.Draw(oled.Img, oled.Img.Bounds(), &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
+	draw.Draw(display.Img, display.Img.Bounds(), &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
+
+	err := display.open()
+	if err != nil {
+		fmt.Printf("no display available, running headless: %s\n", err)
+
+		display.health.State = HealthHeadless
+		display.recordError(err)
+		display.nextAttempt = now().Add(display.backoff)
+
+		return display
+	}
+
+	display.health.State = HealthOK
 
-	return oled
+	return display
 }
 
-func ClearDisplay(oled *goi2coled.I2c) {
-	draw.Draw(oled.Img, oled.Img.Bounds(), &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
-	oled.Clear()
-	oled.Draw()
-	_ = oled.Display()
+// open initialises the controller and restores contrast, orientation and power state. Caller must hold mu or
+// otherwise have exclusive access.
+func (d *Display) open() error {
+	dev, err := d.openDevice()
+	if err != nil {
+		return err
+	}
+
+	err = restoreState(dev, d.contrast, d.flipped, d.on)
+	if err != nil {
+		_ = dev.Close()
+
+		return err
+	}
+
+	d.dev = dev
+
+	return nil
 }
 
-func UpdateDisplayLines(dispLines []string, oled *goi2coled.I2c) {
-	fontHeight := basicfont.Face7x13.Metrics().Height
+func restoreState(dev device, contrast int, flipped bool, on bool) error {
+	err := dev.SetContrast(contrast)
+	if err != nil {
+		return fmt.Errorf("error setting contrast: %w", err)
+	}
 
-	var err error
+	err = writeOrientation(dev, flipped)
+	if err != nil {
+		return err
+	}
 
-	var drawer *font.Drawer
+	if on {
+		_, err = dev.DisplayOn()
+	} else {
+		_, err = dev.DisplayOff()
+	}
 
-	point := fixed.Point26_6{
-		X: fixed.Int26_6(0 * 64),
-		Y: fixed.Int26_6(15 * 64),
-	} // x = 0, y = 15
+	if err != nil {
+		return fmt.Errorf("error setting display power: %w", err)
+	}
 
-	drawer = &font.Drawer{
-		Dst:  oled.Img,
-		Src:  &image.Uniform{C: color.White},
-		Face: basicfont.Face7x13,
-		Dot:  point,
+	return nil
+}
+
+func writeOrientation(dev device, flipped bool) error {
+	segRemap := byte(cmdSegRemapNormal)
+	comScan := byte(cmdComScanNormal)
+
+	if flipped {
+		segRemap = cmdSegRemapFlipped
+		comScan = cmdComScanFlipped
 	}
 
-	draw.Draw(oled.Img, oled.Img.Bounds(), &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
+	_, err := dev.WriteCommand(segRemap)
+	if err != nil {
+		return fmt.Errorf("error setting orientation: %w", err)
+	}
 
-	for i, line := range dispLines {
-		if line == "" || i > 5 {
-			break
-		}
+	_, err = dev.WriteCommand(comScan)
+	if err != nil {
+		return fmt.Errorf("error setting orientation: %w", err)
+	}
+
+	return nil
+}
+
+// recordError notes a failed write. Caller must hold mu.
+func (d *Display) recordError(err error) {
+	d.health.ConsecutiveErrors++
+	d.health.TotalErrors++
+	d.health.LastError = err.Error()
+	d.health.LastErrorTime = d.now()
+}
 
-		drawer.DrawString(line)
-		drawer.Dot.X = fixed.Int26_6(0)
-		drawer.Dot.Y += fontHeight
+// reinit closes the controller and tries to bring it back, backing off between attempts. Caller must hold mu.
+func (d *Display) reinit() {
+	if d.now().Before(d.nextAttempt) {
+		return
 	}
 
-	oled.Draw()
+	if d.dev != nil {
+		_ = d.dev.Close()
+		d.dev = nil
+	}
+
+	if d.health.State != HealthHeadless {
+		d.health.State = HealthRecovering
+	}
+
+	d.health.Reinits++
+
+	err := d.open()
+	if err != nil {
+		d.recordError(err)
+		d.nextAttempt = d.now().Add(d.backoff)
+		d.backoff = min(d.backoff*2, maxBackoff)
+
+		return
+	}
+
+	fmt.Printf("display re-initialised after %d errors\n", d.health.ConsecutiveErrors)
 
-	err = oled.Display()
+	d.health.State = HealthOK
+	d.health.ConsecutiveErrors = 0
+	d.backoff = initialBackoff
+	d.nextAttempt = time.Time{}
+}
+
+// flush sends Img to the panel, tracking failures and recovering the controller as needed. Caller must hold mu.
+func (d *Display) flush() {
+	if d.dev == nil {
+		d.reinit()
+
+		if d.dev == nil {
+			return
+		}
+	}
+
+	err := d.dev.Send(d.Img)
 	if err != nil {
 		fmt.Printf("error: %s\n", err)
+
+		d.recordError(err)
+
+		if d.health.ConsecutiveErrors >= failureThreshold {
+			d.reinit()
+		} else {
+			d.health.State = HealthDegraded
+		}
+
+		return
+	}
+
+	d.health.ConsecutiveErrors = 0
+	d.health.State = HealthOK
+}
+
+// Health returns a snapshot of the display health
+func (d *Display) Health() Health {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.health
+}
+
+// Frame returns a copy of the last frame rendered and a number that changes each time a frame is rendered
+func (d *Display) Frame() (*image.RGBA, uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	frame := image.NewRGBA(d.Img.Bounds())
+	draw.Draw(frame, frame.Bounds(), d.Img, d.Img.Bounds().Min, draw.Src)
+
+	return frame, d.frames
+}
+
+// ObserveFrames calls observe with how long each frame takes to draw and send to the panel
+func (d *Display) ObserveFrames(observe func(took time.Duration)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.frameObserver = observe
+}
+
+// SetContrast changes the panel contrast, 0 to 255. The value is kept and restored after re-initialisation.
+func (d *Display) SetContrast(contrast int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.contrast = contrast
+
+	if d.dev == nil {
+		return nil
 	}
+
+	err := d.dev.SetContrast(contrast)
+	if err != nil {
+		return fmt.Errorf("error setting contrast: %w", err)
+	}
+
+	return nil
+}
+
+// SetFlipped rotates the panel 180 degrees. The value is kept and restored after re-initialisation.
+func (d *Display) SetFlipped(flipped bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.flipped = flipped
+
+	if d.dev == nil {
+		return nil
+	}
+
+	return writeOrientation(d.dev, flipped)
+}
+
+// SetPower turns the panel on or off. The value is kept and restored after re-initialisation.
+func (d *Display) SetPower(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.on = on
+
+	if d.dev == nil {
+		return nil
+	}
+
+	var err error
+
+	if on {
+		_, err = d.dev.DisplayOn()
+	} else {
+		_, err = d.dev.DisplayOff()
+	}
+
+	if err != nil {
+		return fmt.Errorf("error setting display power: %w", err)
+	}
+
+	return nil
+}
+
+// Close turns the panel off and releases the I2C device
+func (d *Display) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.dev == nil {
+		return
+	}
+
+	_, _ = d.dev.DisplayOff()
+
+	_ = d.dev.Close()
+
+	d.dev = nil
+}
+
+func ClearDisplay(oled *Display) {
+	oled.mu.Lock()
+	defer oled.mu.Unlock()
+
+	draw.Draw(oled.Img, oled.Img.Bounds(), &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
+	oled.frames++
+	oled.flush()
 }
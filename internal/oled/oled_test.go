@@ -0,0 +1,234 @@
+package oled
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"testing"
+	"time"
+)
+
+var errNoPanel = errors.New("no panel")
+
+// fakePanel stands in for the SSD1306 across re-initialisations, keeping the settings of the last controller opened
+type fakePanel struct {
+	failOpen bool
+	failSend bool
+
+	opens    int
+	closes   int
+	frames   int
+	contrast int
+	commands []byte
+	on       bool
+}
+
+func (p *fakePanel) open() (device, error) {
+	if p.failOpen {
+		return nil, errNoPanel
+	}
+
+	p.opens++
+	p.contrast = 0
+	p.commands = nil
+	p.on = false
+
+	return fakeDevice{panel: p}, nil
+}
+
+type fakeDevice struct {
+	panel *fakePanel
+}
+
+func (dev fakeDevice) SetContrast(contrast int) error {
+	dev.panel.contrast = contrast
+
+	return nil
+}
+
+func (dev fakeDevice) WriteCommand(cmd byte) (int, error) {
+	dev.panel.commands = append(dev.panel.commands, cmd)
+
+	return 1, nil
+}
+
+func (dev fakeDevice) DisplayOn() (int, error) {
+	dev.panel.on = true
+
+	return 1, nil
+}
+
+func (dev fakeDevice) DisplayOff() (int, error) {
+	dev.panel.on = false
+
+	return 1, nil
+}
+
+func (dev fakeDevice) Close() error {
+	dev.panel.closes++
+
+	return nil
+}
+
+func (dev fakeDevice) Send(image.Image) error {
+	if dev.panel.failSend {
+		return errNoPanel
+	}
+
+	dev.panel.frames++
+
+	return nil
+}
+
+// fakeClock is a time that only moves when told to
+type fakeClock struct {
+	time time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.time
+}
+
+func TestDisplayRecovery(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	panel := &fakePanel{}
+	clock := &fakeClock{time: start}
+
+	display := newDisplay(panel.open, clock.now)
+	if display.Health().State != HealthOK {
+		t.Fatalf("got state %s, want %s", display.Health().State, HealthOK)
+	}
+
+	err := errors.Join(display.SetContrast(0x40), display.SetFlipped(true), display.SetPower(false))
+	if err != nil {
+		t.Fatalf("error changing settings: %s", err)
+	}
+
+	steps := []struct {
+		name     string
+		after    time.Duration
+		failOpen bool
+		failSend bool
+		want     HealthState
+		errors   int
+		reinits  int
+		closes   int
+		frames   int
+	}{
+		{"ok", 0, false, false, HealthOK, 0, 0, 0, 1},
+		{"first failure", 0, false, true, HealthDegraded, 1, 0, 0, 1},
+		{"second failure", 0, false, true, HealthDegraded, 2, 0, 0, 1},
+		// the third failure closes the controller, and opening it again fails too
+		{"third failure", 0, true, true, HealthRecovering, 4, 1, 1, 1},
+		{"backing off", 999 * time.Millisecond, true, true, HealthRecovering, 4, 1, 1, 1},
+		{"tries again", time.Second, true, true, HealthRecovering, 5, 2, 1, 1},
+		// the wait doubles after each attempt
+		{"backing off longer", 2999 * time.Millisecond, false, false, HealthRecovering, 5, 2, 1, 1},
+		{"recovers", 3 * time.Second, false, false, HealthOK, 0, 3, 1, 2},
+		{"ok again", 4 * time.Second, false, false, HealthOK, 0, 3, 1, 3},
+	}
+
+	for _, step := range steps {
+		clock.time = start.Add(step.after)
+		panel.failOpen = step.failOpen
+		panel.failSend = step.failSend
+
+		ClearDisplay(display)
+
+		health := display.Health()
+		if health.State != step.want || health.ConsecutiveErrors != step.errors || health.Reinits != step.reinits {
+			t.Errorf("%s: got %s with %d errors and %d reinits, want %s with %d and %d", step.name, health.State,
+				health.ConsecutiveErrors, health.Reinits, step.want, step.errors, step.reinits)
+		}
+
+		if panel.closes != step.closes || panel.frames != step.frames {
+			t.Errorf("%s: got %d closes and %d frames, want %d and %d", step.name, panel.closes, panel.frames,
+				step.closes, step.frames)
+		}
+	}
+
+	if health := display.Health(); health.TotalErrors != 5 || !health.LastErrorTime.Equal(start.Add(time.Second)) {
+		t.Errorf("got %d errors, the last at %s, want 5 at %s", health.TotalErrors, health.LastErrorTime,
+			start.Add(time.Second))
+	}
+
+	checkRestored(t, panel, 0x40, true, false)
+}
+
+func TestDisplayBackoff(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	panel := &fakePanel{failOpen: true}
+	clock := &fakeClock{time: start}
+
+	display := newDisplay(panel.open, clock.now)
+
+	// settings changed while headless are kept for when the panel appears
+	err := errors.Join(display.SetContrast(0x10), display.SetFlipped(true))
+	if err != nil {
+		t.Fatalf("error changing settings: %s", err)
+	}
+
+	want := []time.Duration{
+		time.Second, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		32 * time.Second, time.Minute, time.Minute,
+	}
+
+	for i, wait := range want {
+		if got := display.nextAttempt.Sub(clock.time); got != wait {
+			t.Errorf("attempt %d: got a wait of %s, want %s", i, got, wait)
+		}
+
+		// nothing is tried before the wait is up
+		clock.time = display.nextAttempt.Add(-time.Millisecond)
+		ClearDisplay(display)
+
+		if display.Health().Reinits != i {
+			t.Errorf("attempt %d: got %d reinits before the wait was up, want %d", i, display.Health().Reinits, i)
+		}
+
+		clock.time = display.nextAttempt
+		ClearDisplay(display)
+	}
+
+	if health := display.Health(); health.State != HealthHeadless || health.ConsecutiveErrors != len(want)+1 {
+		t.Errorf("got %s with %d errors, want %s with %d", health.State, health.ConsecutiveErrors, HealthHeadless,
+			len(want)+1)
+	}
+
+	panel.failOpen = false
+	clock.time = display.nextAttempt
+	ClearDisplay(display)
+
+	if health := display.Health(); health.State != HealthOK || panel.frames != 1 {
+		t.Errorf("got %s with %d frames sent, want %s with 1", health.State, panel.frames, HealthOK)
+	}
+
+	checkRestored(t, panel, 0x10, true, true)
+
+	// a later failure starts backing off from the beginning again
+	display.mu.Lock()
+	defer display.mu.Unlock()
+
+	if display.backoff != initialBackoff {
+		t.Errorf("got backoff %s after recovering, want %s", display.backoff, initialBackoff)
+	}
+}
+
+// checkRestored checks the panel was given the contrast, orientation and power when it was last opened
+func checkRestored(t *testing.T, panel *fakePanel, contrast int, flipped bool, on bool) {
+	t.Helper()
+
+	orientation := []byte{cmdSegRemapNormal, cmdComScanNormal}
+	if flipped {
+		orientation = []byte{cmdSegRemapFlipped, cmdComScanFlipped}
+	}
+
+	if panel.contrast != contrast || !slices.Equal(panel.commands, orientation) || panel.on != on {
+		t.Errorf("got contrast %#x, commands %#x and on %t, want %#x, %#x and %t", panel.contrast, panel.commands,
+			panel.on, contrast, orientation, on)
+	}
+}
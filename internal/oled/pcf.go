@@ -0,0 +1,436 @@
+package oled
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+var ErrBadPCF = errors.New("malformed PCF font")
+
+const (
+	pcfMagic = "\x01fcp"
+
+	pcfAccelerators    = 1 << 1
+	pcfMetrics         = 1 << 2
+	pcfBitmaps         = 1 << 3
+	pcfBDFEncodings    = 1 << 5
+	pcfBDFAccelerators = 1 << 8
+
+	pcfFormatMask        = 0xFFFFFF00
+	pcfCompressedMetrics = 0x00000100
+	pcfGlyphPadMask      = 3
+	pcfByteMask          = 1 << 2
+	pcfBitMask           = 1 << 3
+	pcfScanUnitShift     = 4
+	pcfScanUnitMask      = 3
+
+	pcfNoGlyph = 0xFFFF
+)
+
+type pcfTable struct {
+	format uint32
+	offset uint32
+	size   uint32
+}
+
+type pcfMetric struct {
+	leftBearing  int
+	rightBearing int
+	width        int
+	ascent       int
+	descent      int
+}
+
+// pcfReader reads a single table of a PCF font in the byte order given by the table's format
+type pcfReader struct {
+	data   []byte
+	pos    int
+	format uint32
+	order  binary.ByteOrder
+}
+
+func newPCFReader(data []byte, table pcfTable) (*pcfReader, error) {
+	end := uint64(table.offset) + uint64(table.size)
+	if table.size < 4 || end > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: table out of range", ErrBadPCF)
+	}
+
+	tableData := data[table.offset:end]
+
+	format := binary.LittleEndian.Uint32(tableData)
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if format&pcfByteMask != 0 {
+		order = binary.BigEndian
+	}
+
+	return &pcfReader{data: tableData, pos: 4, format: format, order: order}, nil
+}
+
+func (r *pcfReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("%w: short table", ErrBadPCF)
+	}
+
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return b, nil
+}
+
+func (r *pcfReader) uint8() (int, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(b[0]), nil
+}
+
+func (r *pcfReader) int16() (int, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(int16(r.order.Uint16(b))), nil //nolint:gosec
+}
+
+func (r *pcfReader) uint16() (int, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(r.order.Uint16(b)), nil
+}
+
+func (r *pcfReader) int32() (int, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(int32(r.order.Uint32(b))), nil //nolint:gosec
+}
+
+// parsePCF reads a Portable Compiled Format font. Encodings are treated as Unicode code points.
+func parsePCF(data []byte) (*bitmapFace, error) {
+	tables, err := pcfTables(data)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := pcfReadMetrics(data, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmaps, err := pcfReadBitmaps(data, tables, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	face := newBitmapFace()
+
+	err = pcfReadEncodings(data, tables, metrics, bitmaps, face)
+	if err != nil {
+		return nil, err
+	}
+
+	pcfReadAscent(data, tables, metrics, face)
+
+	if len(face.glyphs) == 0 || face.ascent+face.descent <= 0 {
+		return nil, fmt.Errorf("%w: no glyphs or font metrics", ErrBadPCF)
+	}
+
+	return face, nil
+}
+
+func pcfTables(data []byte) (map[uint32]pcfTable, error) {
+	if len(data) < 8 || string(data[:4]) != pcfMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrBadPCF)
+	}
+
+	count := int(binary.LittleEndian.Uint32(data[4:]))
+	if count <= 0 || 8+count*16 > len(data) {
+		return nil, fmt.Errorf("%w: bad table count", ErrBadPCF)
+	}
+
+	tables := make(map[uint32]pcfTable, count)
+
+	for i := range count {
+		entry := data[8+i*16:]
+
+		table := pcfTable{
+			format: binary.LittleEndian.Uint32(entry[4:]),
+			size:   binary.LittleEndian.Uint32(entry[8:]),
+			offset: binary.LittleEndian.Uint32(entry[12:]),
+		}
+
+		// every table is checked, even ones that aren't read, so a font that's been cut short is caught
+		if uint64(table.offset)+uint64(table.size) > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: table out of range", ErrBadPCF)
+		}
+
+		tables[binary.LittleEndian.Uint32(entry)] = table
+	}
+
+	return tables, nil
+}
+
+func pcfReadMetrics(data []byte, tables map[uint32]pcfTable) ([]pcfMetric, error) {
+	table, ok := tables[pcfMetrics]
+	if !ok {
+		return nil, fmt.Errorf("%w: no metrics table", ErrBadPCF)
+	}
+
+	reader, err := newPCFReader(data, table)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := reader.format&pcfFormatMask == pcfCompressedMetrics
+
+	var count int
+
+	if compressed {
+		count, err = reader.int16()
+	} else {
+		count, err = reader.int32()
+	}
+
+	// each metric is five bytes compressed or six 16 bit values, a count the table can't hold is a corrupt font
+	size := 12
+	if compressed {
+		size = 5
+	}
+
+	if err != nil || count < 0 || count*size > len(reader.data)-reader.pos {
+		return nil, fmt.Errorf("%w: bad metrics count", ErrBadPCF)
+	}
+
+	metrics := make([]pcfMetric, count)
+
+	for i := range metrics {
+		var values [5]int
+
+		for j := range values {
+			if compressed {
+				values[j], err = reader.uint8()
+				values[j] -= 0x80
+			} else {
+				values[j], err = reader.int16()
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !compressed {
+			// attributes
+			_, err = reader.uint16()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		metrics[i] = pcfMetric{
+			leftBearing:  values[0],
+			rightBearing: values[1],
+			width:        values[2],
+			ascent:       values[3],
+			descent:      values[4],
+		}
+	}
+
+	return metrics, nil
+}
+
+func pcfReadBitmaps(data []byte, tables map[uint32]pcfTable, metrics []pcfMetric) ([]*image.Alpha, error) {
+	table, ok := tables[pcfBitmaps]
+	if !ok {
+		return nil, fmt.Errorf("%w: no bitmaps table", ErrBadPCF)
+	}
+
+	reader, err := newPCFReader(data, table)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := reader.int32()
+	if err != nil || count != len(metrics) {
+		return nil, fmt.Errorf("%w: bitmap count does not match metrics", ErrBadPCF)
+	}
+
+	offsets := make([]int, count)
+	for i := range offsets {
+		offsets[i], err = reader.int32()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sizes [4]int
+	for i := range sizes {
+		sizes[i], err = reader.int32()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	padIndex := int(reader.format & pcfGlyphPadMask)
+
+	bitmapData, err := reader.bytes(sizes[padIndex])
+	if err != nil {
+		return nil, err
+	}
+
+	bitmapData = pcfNormaliseBits(bitmapData, reader.format)
+
+	pad := 1 << padIndex
+	masks := make([]*image.Alpha, count)
+
+	for i, metric := range metrics {
+		width := metric.rightBearing - metric.leftBearing
+		height := metric.ascent + metric.descent
+
+		stride := ((width+7)/8 + pad - 1) / pad * pad
+		if width < 0 || height < 0 || offsets[i] < 0 || offsets[i]+height*stride > len(bitmapData) {
+			return nil, fmt.Errorf("%w: glyph %d out of range", ErrBadPCF, i)
+		}
+
+		mask := image.NewAlpha(image.Rect(0, 0, width, height))
+
+		for y := range height {
+			for x := range width {
+				if bitmapData[offsets[i]+y*stride+x/8]&(0x80>>(x%8)) != 0 {
+					mask.Pix[y*mask.Stride+x] = 0xFF
+				}
+			}
+		}
+
+		masks[i] = mask
+	}
+
+	return masks, nil
+}
+
+// pcfNormaliseBits converts bitmap data to most significant bit and byte first, the same way FreeType does
+func pcfNormaliseBits(bitmapData []byte, format uint32) []byte {
+	normalised := make([]byte, len(bitmapData))
+	copy(normalised, bitmapData)
+
+	if format&pcfBitMask == 0 {
+		for i, b := range normalised {
+			normalised[i] = bits.Reverse8(b)
+		}
+	}
+
+	if (format&pcfByteMask == 0) != (format&pcfBitMask == 0) {
+		unit := 1 << ((format >> pcfScanUnitShift) & pcfScanUnitMask)
+
+		for i := 0; i+unit <= len(normalised); i += unit {
+			for j := range unit / 2 {
+				normalised[i+j], normalised[i+unit-1-j] = normalised[i+unit-1-j], normalised[i+j]
+			}
+		}
+	}
+
+	return normalised
+}
+
+func pcfReadEncodings(data []byte, tables map[uint32]pcfTable, metrics []pcfMetric, masks []*image.Alpha,
+	face *bitmapFace) error {
+	table, ok := tables[pcfBDFEncodings]
+	if !ok {
+		return fmt.Errorf("%w: no encodings table", ErrBadPCF)
+	}
+
+	reader, err := newPCFReader(data, table)
+	if err != nil {
+		return err
+	}
+
+	var header [5]int
+	for i := range header {
+		header[i], err = reader.int16()
+		if err != nil {
+			return err
+		}
+	}
+
+	minByte2, maxByte2, minByte1, maxByte1, defaultChar := header[0], header[1], header[2], header[3], header[4]
+	face.defaultChar = rune(defaultChar)
+
+	for byte1 := minByte1; byte1 <= maxByte1; byte1++ {
+		for byte2 := minByte2; byte2 <= maxByte2; byte2++ {
+			var index int
+
+			index, err = reader.uint16()
+			if err != nil {
+				return err
+			}
+
+			if index == pcfNoGlyph || index >= len(metrics) {
+				continue
+			}
+
+			metric := metrics[index]
+
+			face.glyphs[rune(byte1<<8|byte2)] = bitmapGlyph{
+				advance: metric.width,
+				bounds: image.Rect(metric.leftBearing, -metric.ascent, metric.rightBearing,
+					metric.descent),
+				mask: masks[index],
+			}
+		}
+	}
+
+	return nil
+}
+
+// pcfReadAscent takes the font ascent and descent from the accelerator tables, falling back to the glyph metrics
+func pcfReadAscent(data []byte, tables map[uint32]pcfTable, metrics []pcfMetric, face *bitmapFace) {
+	for _, tableType := range []uint32{pcfBDFAccelerators, pcfAccelerators} {
+		table, ok := tables[tableType]
+		if !ok {
+			continue
+		}
+
+		reader, err := newPCFReader(data, table)
+		if err != nil {
+			continue
+		}
+
+		// eight flag bytes precede the ascent and descent
+		_, err = reader.bytes(8)
+		if err != nil {
+			continue
+		}
+
+		ascent, err := reader.int32()
+		if err != nil {
+			continue
+		}
+
+		descent, err := reader.int32()
+		if err != nil {
+			continue
+		}
+
+		face.ascent = ascent
+		face.descent = descent
+
+		return
+	}
+
+	for _, metric := range metrics {
+		face.ascent = max(face.ascent, metric.ascent)
+		face.descent = max(face.descent, metric.descent)
+	}
+}
@@ -0,0 +1,318 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+var ErrBadStatus = errors.New("bad status")
+
+const (
+	// DefaultURL is the adsb.im routeset API, which takes the same requests as adsb.lol's
+	DefaultURL = "https://adsb.im/api/0/routeset"
+
+	defaultTTL         = 6 * time.Hour
+	defaultNegativeTTL = 30 * time.Minute
+	defaultMinInterval = 15 * time.Second
+	defaultBackoff     = 5 * time.Minute
+	defaultBatchSize   = 100
+	defaultTimeout     = 10 * time.Second
+)
+
+// Airport is one stop on a route
+type Airport struct {
+	ICAO      string  `json:"icao"`
+	IATA      string  `json:"iata"`
+	Name      string  `json:"name"`
+	Location  string  `json:"location"`
+	Country   string  `json:"countryiso2"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+}
+
+// Code is the airport's IATA code, or its ICAO code if it doesn't have one
+func (a Airport) Code() string {
+	if a.IATA != "" {
+		return a.IATA
+	}
+
+	return a.ICAO
+}
+
+// Route is where a flight is going, the airports in the order they're visited
+type Route struct {
+	CallSign string    `json:"callsign"`
+	Airports []Airport `json:"_airports"`
+	// Plausible is false when the route doesn't fit where the aircraft is, it may be out of date
+	Plausible bool `json:"-"`
+}
+
+// Origin is the first airport of the route
+func (r Route) Origin() Airport {
+	if len(r.Airports) == 0 {
+		return Airport{}
+	}
+
+	return r.Airports[0]
+}
+
+// Destination is the last airport of the route
+func (r Route) Destination() Airport {
+	if len(r.Airports) == 0 {
+		return Airport{}
+	}
+
+	return r.Airports[len(r.Airports)-1]
+}
+
+// Codes joins the airport codes of the route with sep
+func (r Route) Codes(sep string) string {
+	codes := make([]string, 0, len(r.Airports))
+
+	for _, airport := range r.Airports {
+		codes = append(codes, airport.Code())
+	}
+
+	return strings.Join(codes, sep)
+}
+
+// cached is a route, or the lack of one, and when to forget it
+type cached struct {
+	route   Route
+	found   bool
+	expires time.Time
+}
+
+// Client looks routes up by callsign, remembering both the routes it finds and the callsigns it doesn't so each is
+// only asked for again once it has expired. It makes at most one request every MinInterval, backing off for Backoff
+// after a failure.
+type Client struct {
+	// URL is where the routeset requests are sent, DefaultURL if empty
+	URL        string
+	HTTPClient *http.Client
+
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MinInterval time.Duration
+	Backoff     time.Duration
+	// BatchSize is the most callsigns asked for in a request
+	BatchSize int
+
+	mu        sync.Mutex
+	cache     map[string]cached
+	nextAt    time.Time
+	requested map[string]bool
+}
+
+// NewClient creates a client for the routeset API at url with the default limits
+func NewClient(url string) *Client {
+	return &Client{
+		URL:         url,
+		HTTPClient:  &http.Client{Timeout: defaultTimeout},
+		TTL:         defaultTTL,
+		NegativeTTL: defaultNegativeTTL,
+		MinInterval: defaultMinInterval,
+		Backoff:     defaultBackoff,
+		BatchSize:   defaultBatchSize,
+	}
+}
+
+// Cached returns the route of callsign if it has been looked up and found, without making a request
+func (c *Client) Cached(now time.Time, callSign string) (Route, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.cache[normalise(callSign)]
+	if !ok || !entry.found || now.After(entry.expires) {
+		return Route{}, false
+	}
+
+	return entry.route, true
+}
+
+// routeRequest is one aircraft in a routeset request, the position lets the API judge whether the route is plausible
+type routeRequest struct {
+	CallSign  string  `json:"callsign"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+}
+
+// routeResponse is one route in the reply, unknown routes have "unknown" airport codes
+type routeResponse struct {
+	Route
+
+	AirportCodes string `json:"airport_codes"`
+	Plausible    any    `json:"plausible"`
+}
+
+// Update looks up the routes of the aircraft with callsigns and positions that aren't cached. Nothing is requested if
+// the last request was less than MinInterval ago, or while backing off after a failure.
+func (c *Client) Update(ctx context.Context, now time.Time, station adsb.Station, planes []adsb.Aircraft) error {
+	batch := c.pending(now, station, planes)
+	if len(batch) == 0 {
+		return nil
+	}
+
+	found, err := c.fetch(ctx, batch)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, plane := range batch {
+		delete(c.requested, plane.CallSign)
+	}
+
+	if err != nil {
+		c.nextAt = now.Add(c.Backoff)
+
+		return err
+	}
+
+	for _, plane := range batch {
+		route, ok := found[plane.CallSign]
+		if ok {
+			c.cache[plane.CallSign] = cached{route: route, found: true, expires: now.Add(c.TTL)}
+		} else {
+			c.cache[plane.CallSign] = cached{expires: now.Add(c.NegativeTTL)}
+		}
+	}
+
+	return nil
+}
+
+// pending picks the aircraft to ask about, reserving the next request slot if there are any
+func (c *Client) pending(now time.Time, station adsb.Station, planes []adsb.Aircraft) []routeRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Before(c.nextAt) {
+		return nil
+	}
+
+	if c.cache == nil {
+		c.cache = make(map[string]cached)
+		c.requested = make(map[string]bool)
+	}
+
+	for callSign, entry := range c.cache {
+		if now.After(entry.expires) {
+			delete(c.cache, callSign)
+		}
+	}
+
+	var batch []routeRequest
+
+	for _, plane := range planes {
+		callSign := normalise(plane.CallSign)
+		if callSign == "" || c.requested[callSign] {
+			continue
+		}
+
+		if _, ok := c.cache[callSign]; ok {
+			continue
+		}
+
+		position, ok := station.PositionOf(plane)
+		if !ok {
+			continue
+		}
+
+		batch = append(batch, routeRequest{CallSign: callSign, Latitude: position.Latitude,
+			Longitude: position.Longitude})
+		c.requested[callSign] = true
+
+		if len(batch) >= c.BatchSize {
+			break
+		}
+	}
+
+	if len(batch) > 0 {
+		c.nextAt = now.Add(c.MinInterval)
+	}
+
+	return batch
+}
+
+// fetch asks the API for the routes of batch, returning the known ones by callsign
+func (c *Client) fetch(ctx context.Context, batch []routeRequest) (map[string]Route, error) {
+	body, err := json.Marshal(map[string][]routeRequest{"planes": batch})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding route request: %w", err)
+	}
+
+	url := c.URL
+	if url == "" {
+		url = DefaultURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating http req: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making http request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w looking up routes: %d", ErrBadStatus, res.StatusCode)
+	}
+
+	respBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	var responses []routeResponse
+
+	err = json.Unmarshal(respBody, &responses)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling routes: %w", err)
+	}
+
+	found := make(map[string]Route, len(responses))
+
+	for _, response := range responses {
+		if response.AirportCodes == "unknown" || len(response.Airports) == 0 {
+			continue
+		}
+
+		route := response.Route
+		route.CallSign = normalise(route.CallSign)
+		route.Plausible = isTrue(response.Plausible)
+		found[route.CallSign] = route
+	}
+
+	return found, nil
+}
+
+// isTrue reads the plausible flag, which is sent as a number or a boolean
+func isTrue(value any) bool {
+	switch flag := value.(type) {
+	case bool:
+		return flag
+	case float64:
+		return flag != 0
+	default:
+		return false
+	}
+}
+
+func normalise(callSign string) string {
+	return strings.ToUpper(strings.TrimSpace(callSign))
+}
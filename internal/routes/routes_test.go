@@ -0,0 +1,285 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+// known are the routes the stand-in API has, others are sent back as unknown
+var known = map[string]string{
+	"UAL1": `{"callsign":"UAL1","airport_codes":"KSFO-KEWR","plausible":1,"_airports":[` +
+		`{"icao":"KSFO","iata":"SFO","name":"San Francisco International Airport"},` +
+		`{"icao":"KEWR","iata":"EWR","name":"Newark Liberty International Airport"}]}`,
+	"N123AB": `{"callsign":"N123AB","airport_codes":"KPAO-KHAF","plausible":false,"_airports":[` +
+		`{"icao":"KPAO","name":"Palo Alto Airport"},{"icao":"KHAF","name":"Half Moon Bay Airport"}]}`,
+}
+
+// api is a stand-in for the routeset API, recording the callsigns asked for in each request
+type api struct {
+	server *httptest.Server
+	status atomic.Int32
+
+	mu       sync.Mutex
+	requests [][]string
+}
+
+func newAPI(t *testing.T) *api {
+	t.Helper()
+
+	stand := &api{}
+	stand.status.Store(http.StatusOK)
+
+	stand.server = httptest.NewServer(http.HandlerFunc(stand.serve))
+	t.Cleanup(stand.server.Close)
+
+	return stand
+}
+
+func (a *api) serve(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Planes []routeRequest `json:"planes"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || r.Method != http.MethodPost {
+		http.Error(w, "bad request", http.StatusBadRequest)
+
+		return
+	}
+
+	callSigns := make([]string, 0, len(request.Planes))
+	responses := make([]json.RawMessage, 0, len(request.Planes))
+
+	for _, plane := range request.Planes {
+		callSigns = append(callSigns, plane.CallSign)
+
+		route, ok := known[plane.CallSign]
+		if !ok {
+			route = `{"callsign":"` + plane.CallSign + `","airport_codes":"unknown","_airports":[]}`
+		}
+
+		responses = append(responses, json.RawMessage(route))
+	}
+
+	a.mu.Lock()
+	a.requests = append(a.requests, callSigns)
+	a.mu.Unlock()
+
+	status := int(a.status.Load())
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(responses)
+}
+
+// asked returns the callsigns asked for in each request since it was last called
+func (a *api) asked() [][]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	requests := a.requests
+	a.requests = nil
+
+	return requests
+}
+
+func (a *api) client() *Client {
+	client := NewClient(a.server.URL)
+	client.HTTPClient = a.server.Client()
+
+	return client
+}
+
+func plane(callSign string) adsb.Aircraft {
+	seen := 1.0
+
+	return adsb.Aircraft{CallSign: callSign, Latitude: 37.6, Longitude: -122.4, SeenPos: &seen}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	stand := newAPI(t)
+	client := stand.client()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	planes := []adsb.Aircraft{
+		plane("UAL1  "), plane("n123ab"), plane("ZZZ9"), plane(""),
+		{CallSign: "NOPOS"},
+	}
+
+	err := client.Update(t.Context(), now, adsb.Station{}, planes)
+	if err != nil {
+		t.Fatalf("error updating: %s", err)
+	}
+
+	want := [][]string{{"UAL1", "N123AB", "ZZZ9"}}
+	if got := stand.asked(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("got requests %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		callSign      string
+		wantFound     bool
+		wantCodes     string
+		wantPlausible bool
+	}{
+		{"UAL1", true, "SFO-EWR", true},
+		{" ual1", true, "SFO-EWR", true},
+		{"N123AB", true, "KPAO-KHAF", false},
+		{"ZZZ9", false, "", false},
+		{"NOPOS", false, "", false},
+	}
+
+	for _, tt := range tests {
+		route, found := client.Cached(now, tt.callSign)
+
+		if found != tt.wantFound || route.Codes("-") != tt.wantCodes || route.Plausible != tt.wantPlausible {
+			t.Errorf("%q: got %t %q plausible %t, want %t %q plausible %t", tt.callSign, found, route.Codes("-"),
+				route.Plausible, tt.wantFound, tt.wantCodes, tt.wantPlausible)
+		}
+	}
+
+	// routes found and not found are both remembered
+	err = client.Update(t.Context(), now.Add(time.Minute), adsb.Station{}, planes)
+	if err != nil {
+		t.Fatalf("error updating: %s", err)
+	}
+
+	if got := stand.asked(); len(got) != 0 {
+		t.Errorf("got requests %q for cached routes, want none", got)
+	}
+}
+
+func TestUpdateRateLimit(t *testing.T) {
+	t.Parallel()
+
+	stand := newAPI(t)
+	client := stand.client()
+	client.BatchSize = 2
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	planes := []adsb.Aircraft{plane("UAL1"), plane("N123AB"), plane("ZZZ9")}
+
+	steps := []struct {
+		name  string
+		after time.Duration
+		want  [][]string
+	}{
+		{"first batch", 0, [][]string{{"UAL1", "N123AB"}}},
+		{"too soon", client.MinInterval - time.Second, nil},
+		{"next batch", client.MinInterval, [][]string{{"ZZZ9"}}},
+		{"all cached", 2 * client.MinInterval, nil},
+	}
+
+	for _, step := range steps {
+		err := client.Update(t.Context(), now.Add(step.after), adsb.Station{}, planes)
+		if err != nil {
+			t.Fatalf("%s: error updating: %s", step.name, err)
+		}
+
+		if got := stand.asked(); !slices.EqualFunc(got, step.want, slices.Equal) {
+			t.Errorf("%s: got requests %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func TestUpdateBackoff(t *testing.T) {
+	t.Parallel()
+
+	stand := newAPI(t)
+	stand.status.Store(http.StatusServiceUnavailable)
+
+	client := stand.client()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	planes := []adsb.Aircraft{plane("UAL1")}
+
+	err := client.Update(t.Context(), now, adsb.Station{}, planes)
+	if !errors.Is(err, ErrBadStatus) {
+		t.Fatalf("got error %v, want %v", err, ErrBadStatus)
+	}
+
+	stand.status.Store(http.StatusOK)
+
+	steps := []struct {
+		name  string
+		after time.Duration
+		want  int
+	}{
+		{"backing off", client.MinInterval, 0},
+		{"backed off", client.Backoff, 1},
+	}
+
+	stand.asked()
+
+	for _, step := range steps {
+		err = client.Update(t.Context(), now.Add(step.after), adsb.Station{}, planes)
+		if err != nil {
+			t.Fatalf("%s: error updating: %s", step.name, err)
+		}
+
+		if got := stand.asked(); len(got) != step.want {
+			t.Errorf("%s: got requests %q, want %d", step.name, got, step.want)
+		}
+	}
+
+	if _, found := client.Cached(now.Add(client.Backoff), "UAL1"); !found {
+		t.Errorf("got no route after backing off, want one")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	t.Parallel()
+
+	stand := newAPI(t)
+	client := stand.client()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	planes := []adsb.Aircraft{plane("UAL1"), plane("ZZZ9")}
+
+	err := client.Update(t.Context(), now, adsb.Station{}, planes)
+	if err != nil {
+		t.Fatalf("error updating: %s", err)
+	}
+
+	stand.asked()
+
+	steps := []struct {
+		name      string
+		after     time.Duration
+		wantFound bool
+		want      [][]string
+	}{
+		{"cached", client.NegativeTTL, true, nil},
+		{"not found expired", client.NegativeTTL + time.Second, true, [][]string{{"ZZZ9"}}},
+		{"found expired", client.TTL + time.Second, false, [][]string{{"UAL1", "ZZZ9"}}},
+	}
+
+	for _, step := range steps {
+		at := now.Add(step.after)
+
+		if _, found := client.Cached(at, "UAL1"); found != step.wantFound {
+			t.Errorf("%s: got found %t, want %t", step.name, found, step.wantFound)
+		}
+
+		err = client.Update(t.Context(), at, adsb.Station{}, planes)
+		if err != nil {
+			t.Fatalf("%s: error updating: %s", step.name, err)
+		}
+
+		if got := stand.asked(); !slices.EqualFunc(got, step.want, slices.Equal) {
+			t.Errorf("%s: got requests %q, want %q", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package watchlist
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/events"
+)
+
+var ErrEmptyEntry = errors.New("watchlist entry matches nothing")
+
+// event types
+const (
+	Appeared = "watch_appeared"
+	Left     = "watch_left"
+)
+
+// Entry is one thing to watch for. An aircraft matches when it matches every field that is set. Hex, Registration and
+// Type are compared without regard to case. CallSign is a shell style glob and CallSignRegex a regular expression, both
+// matched against the callsign in upper case, so letters in them should be upper case too.
+type Entry struct {
+	Name          string `json:"name,omitempty"`
+	Hex           string `json:"hex,omitempty"`
+	CallSign      string `json:"callsign,omitempty"`
+	CallSignRegex string `json:"callsign_regex,omitempty"`
+	Registration  string `json:"registration,omitempty"`
+	Type          string `json:"type,omitempty"`
+}
+
+// Status is what has been seen of an entry
+type Status struct {
+	Name string `json:"name"`
+	// FirstSeen and LastSeen are zero if nothing matching the entry has been seen
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+	LastSeen  time.Time `json:"last_seen,omitzero"`
+	// Present are the hex codes of matching aircraft in range now
+	Present []string `json:"present,omitempty"`
+}
+
+// Watchlist follows the aircraft matching its entries as they come and go
+type Watchlist struct {
+	mu sync.Mutex
+
+	entries []*watchedEntry
+}
+
+type watchedEntry struct {
+	Entry
+
+	callSignRegex *regexp.Regexp
+	firstSeen     time.Time
+	lastSeen      time.Time
+	present       map[string]*presence
+}
+
+// presence is a matching aircraft in range and when it last matched
+type presence struct {
+	aircraft adsb.Aircraft
+	lastSeen time.Time
+}
+
+// Load reads a watchlist from a JSON file holding a list of entries. An empty path gives an empty watchlist.
+func Load(path string) (*Watchlist, error) {
+	if path == "" {
+		return New(nil)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading watchlist: %w", err)
+	}
+
+	var entries []Entry
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing watchlist %s: %w", path, err)
+	}
+
+	watchlist, err := New(entries)
+	if err != nil {
+		return nil, fmt.Errorf("error in watchlist %s: %w", path, err)
+	}
+
+	return watchlist, nil
+}
+
+// New creates a watchlist of entries. Entries without a name are named after what they match.
+func New(entries []Entry) (*Watchlist, error) {
+	watchlist := &Watchlist{entries: make([]*watchedEntry, 0, len(entries))}
+
+	for i, entry := range entries {
+		watched, err := newWatchedEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("error in entry %d: %w", i+1, err)
+		}
+
+		watchlist.entries = append(watchlist.entries, watched)
+	}
+
+	return watchlist, nil
+}
+
+// Replace watches for the entries of other, which was just loaded, in place of these. An entry with the same name as
+// one of these keeps what has been seen of it, so aircraft already in range don't appear again.
+func (w *Watchlist) Replace(other *Watchlist) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, entry := range other.entries {
+		index := slices.IndexFunc(w.entries, func(old *watchedEntry) bool { return old.Name == entry.Name })
+		if index < 0 {
+			continue
+		}
+
+		old := w.entries[index]
+		entry.firstSeen = old.firstSeen
+		entry.lastSeen = old.lastSeen
+		entry.present = old.present
+	}
+
+	w.entries = other.entries
+}
+
+func newWatchedEntry(entry Entry) (*watchedEntry, error) {
+	if entry.Hex == "" && entry.CallSign == "" && entry.CallSignRegex == "" && entry.Registration == "" &&
+		entry.Type == "" {
+		return nil, ErrEmptyEntry
+	}
+
+	watched := &watchedEntry{Entry: entry, present: make(map[string]*presence)}
+
+	if entry.CallSign != "" {
+		_, err := path.Match(entry.CallSign, "")
+		if err != nil {
+			return nil, fmt.Errorf("error in callsign %q: %w", entry.CallSign, err)
+		}
+	}
+
+	if entry.CallSignRegex != "" {
+		var err error
+
+		watched.callSignRegex, err = regexp.Compile(entry.CallSignRegex)
+		if err != nil {
+			return nil, fmt.Errorf("error in callsign_regex: %w", err)
+		}
+	}
+
+	if watched.Name == "" {
+		watched.Name = strings.Join(nonEmpty(entry.Hex, entry.CallSign, entry.CallSignRegex, entry.Registration,
+			entry.Type), " ")
+	}
+
+	return watched, nil
+}
+
+func (e *watchedEntry) matches(aircraft adsb.Aircraft) bool {
+	callSign := strings.ToUpper(strings.TrimSpace(aircraft.CallSign))
+
+	if e.Hex != "" && !strings.EqualFold(e.Hex, aircraft.Hex) {
+		return false
+	}
+
+	if e.CallSign != "" {
+		matched, err := path.Match(e.CallSign, callSign)
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	if e.callSignRegex != nil && !e.callSignRegex.MatchString(callSign) {
+		return false
+	}
+
+	if e.Registration != "" && !strings.EqualFold(e.Registration, aircraft.Registration) {
+		return false
+	}
+
+	return e.Type == "" || strings.EqualFold(e.Type, aircraft.TypeCode)
+}
+
+// Match returns the name of the first entry the aircraft matches
+func (w *Watchlist) Match(aircraft adsb.Aircraft) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, entry := range w.entries {
+		if entry.matches(aircraft) {
+			return entry.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// Update checks every aircraft against the watchlist at now, returning an event for each aircraft that has appeared
+// matching an entry and each one that has left. An aircraft has left once it hasn't matched for as long as station
+// keeps positions, so one missed update or a callsign that's blank for a moment doesn't make it leave and come back.
+func (w *Watchlist) Update(now time.Time, station adsb.Station, myADSBData adsb.Data) []events.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	gone := cmp.Or(station.MaxPositionAge, adsb.DefaultMaxPositionAge)
+
+	var found []events.Event
+
+	for _, entry := range w.entries {
+		for _, flight := range myADSBData.Planes {
+			if !entry.matches(flight) {
+				continue
+			}
+
+			if entry.firstSeen.IsZero() {
+				entry.firstSeen = now
+			}
+
+			entry.lastSeen = now
+
+			if _, ok := entry.present[flight.Hex]; !ok {
+				found = append(found, entry.event(now, Appeared, flight))
+			}
+
+			entry.present[flight.Hex] = &presence{aircraft: flight, lastSeen: now}
+		}
+
+		for _, hex := range slices.Sorted(maps.Keys(entry.present)) {
+			current := entry.present[hex]
+			if now.Sub(current.lastSeen) < gone {
+				continue
+			}
+
+			delete(entry.present, hex)
+
+			found = append(found, entry.event(now, Left, current.aircraft))
+		}
+	}
+
+	return found
+}
+
+// Statuses returns what has been seen of each entry, in the order they are listed
+func (w *Watchlist) Statuses() []Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	statuses := make([]Status, 0, len(w.entries))
+
+	for _, entry := range w.entries {
+		status := Status{Name: entry.Name, FirstSeen: entry.firstSeen, LastSeen: entry.lastSeen}
+
+		for hex := range entry.present {
+			status.Present = append(status.Present, hex)
+		}
+
+		slices.Sort(status.Present)
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func (e *watchedEntry) event(now time.Time, eventType string, aircraft adsb.Aircraft) events.Event {
+	name := strings.TrimSpace(aircraft.CallSign)
+	if name == "" {
+		name = aircraft.Hex
+	}
+
+	event := events.Event{
+		Time:    now,
+		Type:    eventType,
+		Key:     fmt.Sprintf("%s:%s:%s", eventType, e.Name, aircraft.Hex),
+		Hex:     aircraft.Hex,
+		Title:   fmt.Sprintf("%s %s", e.Name, name),
+		Message: fmt.Sprintf("%s (%s) matching %s is in range", name, aircraft.Hex, e.Name),
+	}
+
+	if eventType == Left {
+		event.Title = fmt.Sprintf("%s left", name)
+		event.Message = fmt.Sprintf("%s (%s) matching %s has left range", name, aircraft.Hex, e.Name)
+	}
+
+	return event
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,210 @@
+package watchlist
+
+import (
+	"errors"
+	"path"
+	"regexp/syntax"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	plane := adsb.Aircraft{Hex: "a1b2c3", CallSign: "swa1234 ", Registration: "N12345", TypeCode: "B738"}
+
+	tests := []struct {
+		name  string
+		entry Entry
+		want  bool
+	}{
+		{"hex", Entry{Hex: "A1B2C3"}, true},
+		{"other hex", Entry{Hex: "a1b2c4"}, false},
+		{"callsign", Entry{CallSign: "SWA1234"}, true},
+		{"callsign glob", Entry{CallSign: "SWA*"}, true},
+		{"callsign glob class", Entry{CallSign: "SWA[0-9]*"}, true},
+		{"callsign glob elsewhere", Entry{CallSign: "UAL*"}, false},
+		{"callsign regex", Entry{CallSignRegex: `^SWA\d+$`}, true},
+		{"callsign regex class", Entry{CallSignRegex: `^[A-Z]{3}[0-9]{4}$`}, true},
+		{"callsign regex elsewhere", Entry{CallSignRegex: `^UAL`}, false},
+		{"registration", Entry{Registration: "n12345"}, true},
+		{"other registration", Entry{Registration: "N54321"}, false},
+		{"type", Entry{Type: "b738"}, true},
+		{"other type", Entry{Type: "A320"}, false},
+		{"every field", Entry{Hex: "a1b2c3", CallSign: "SWA*", Registration: "N12345", Type: "B738"}, true},
+		{"all but one field", Entry{Hex: "a1b2c3", CallSign: "SWA*", Registration: "N12345", Type: "A320"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.entry.Name = tt.name
+
+			watched, err := New([]Entry{tt.entry})
+			if err != nil {
+				t.Fatalf("error creating watchlist: %s", err)
+			}
+
+			name, ok := watched.Match(plane)
+			if ok != tt.want || (ok && name != tt.name) {
+				t.Errorf("got %q and %t, want %t", name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		entry    Entry
+		wantName string
+		wantErr  error
+	}{
+		{"named", Entry{Name: "friends", Hex: "a1b2c3"}, "friends", nil},
+		{"named after its fields", Entry{CallSign: "SWA*", Type: "B738"}, "SWA* B738", nil},
+		{"empty", Entry{Name: "nothing"}, "", ErrEmptyEntry},
+		{"bad glob", Entry{CallSign: "SWA["}, "", path.ErrBadPattern},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			watched, err := New([]Entry{tt.entry})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && watched.Statuses()[0].Name != tt.wantName {
+				t.Errorf("got name %q, want %q", watched.Statuses()[0].Name, tt.wantName)
+			}
+		})
+	}
+
+	var syntaxErr *syntax.Error
+
+	_, err := New([]Entry{{CallSignRegex: "SWA("}})
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("got error %v for a bad callsign_regex, want a syntax error", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	watched, err := New([]Entry{{Name: "southwest", CallSign: "SWA*"}, {Name: "jet", Hex: "a1b2c3"}})
+	if err != nil {
+		t.Fatalf("error creating watchlist: %s", err)
+	}
+
+	swa1 := adsb.Aircraft{Hex: "abc001", CallSign: "SWA1"}
+	swa2 := adsb.Aircraft{Hex: "abc002", CallSign: "SWA2"}
+	jet := adsb.Aircraft{Hex: "a1b2c3"}
+	station := adsb.Station{MaxPositionAge: time.Minute}
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name   string
+		after  time.Duration
+		planes []adsb.Aircraft
+		want   []string
+	}{
+		{"nothing", 0, []adsb.Aircraft{{Hex: "ffffff", CallSign: "UAL1"}}, nil},
+		{"appears", 10 * time.Second, []adsb.Aircraft{swa1}, []string{Appeared + ":southwest:abc001"}},
+		{"still there", 20 * time.Second, []adsb.Aircraft{swa1}, nil},
+		{"two more appear", 30 * time.Second, []adsb.Aircraft{swa2, jet, swa1},
+			[]string{Appeared + ":southwest:abc002", Appeared + ":jet:a1b2c3"}},
+		// a missed update doesn't make an aircraft leave
+		{"missed", 40 * time.Second, []adsb.Aircraft{swa2}, nil},
+		{"back again", 50 * time.Second, []adsb.Aircraft{swa2, jet, swa1}, nil},
+		{"not gone long enough", 109 * time.Second, []adsb.Aircraft{swa2}, nil},
+		{"gone", 110 * time.Second, []adsb.Aircraft{swa2}, []string{Left + ":southwest:abc001", Left + ":jet:a1b2c3"}},
+		{"appears again", 120 * time.Second, []adsb.Aircraft{swa2, swa1}, []string{Appeared + ":southwest:abc001"}},
+	}
+
+	for _, step := range steps {
+		found := watched.Update(start.Add(step.after), station, adsb.Data{Planes: step.planes})
+
+		got := make([]string, 0, len(found))
+
+		for _, event := range found {
+			got = append(got, event.Key)
+		}
+
+		if !slices.Equal(got, step.want) {
+			t.Errorf("%s: got events %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	want := []Status{
+		{Name: "southwest", FirstSeen: start.Add(10 * time.Second), LastSeen: start.Add(120 * time.Second),
+			Present: []string{"abc001", "abc002"}},
+		{Name: "jet", FirstSeen: start.Add(30 * time.Second), LastSeen: start.Add(50 * time.Second)},
+	}
+
+	checkStatuses(t, watched.Statuses(), want)
+}
+
+func TestReplace(t *testing.T) {
+	t.Parallel()
+
+	watched, err := New([]Entry{{Name: "southwest", CallSign: "SWA*"}, {Name: "jet", Hex: "a1b2c3"}})
+	if err != nil {
+		t.Fatalf("error creating watchlist: %s", err)
+	}
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	planes := adsb.Data{Planes: []adsb.Aircraft{{Hex: "abc001", CallSign: "SWA1"}, {Hex: "a1b2c3"}}}
+
+	watched.Update(start, adsb.Station{}, planes)
+
+	reloaded, err := New([]Entry{{Name: "southwest", CallSign: "SWA1*"}, {Name: "jet", Hex: "a1b2c4"},
+		{Name: "new", Hex: "abc001"}})
+	if err != nil {
+		t.Fatalf("error creating watchlist: %s", err)
+	}
+
+	watched.Replace(reloaded)
+
+	// southwest keeps its aircraft, new hasn't seen it before, and the jet leaves once it's been gone long enough
+	found := watched.Update(start.Add(time.Second), adsb.Station{}, planes)
+	if len(found) != 1 || found[0].Key != Appeared+":new:abc001" {
+		t.Errorf("got %+v after reloading, want new appearing alone", found)
+	}
+
+	found = watched.Update(start.Add(adsb.DefaultMaxPositionAge), adsb.Station{}, planes)
+	if len(found) != 1 || found[0].Key != Left+":jet:a1b2c3" {
+		t.Errorf("got %+v after reloading, want the jet leaving alone", found)
+	}
+
+	want := []Status{
+		{Name: "southwest", FirstSeen: start, LastSeen: start.Add(adsb.DefaultMaxPositionAge),
+			Present: []string{"abc001"}},
+		{Name: "jet", FirstSeen: start, LastSeen: start},
+		{Name: "new", FirstSeen: start.Add(time.Second), LastSeen: start.Add(adsb.DefaultMaxPositionAge),
+			Present: []string{"abc001"}},
+	}
+
+	checkStatuses(t, watched.Statuses(), want)
+}
+
+func checkStatuses(t *testing.T, got []Status, want []Status) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d statuses, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i].Name != want[i].Name || !got[i].FirstSeen.Equal(want[i].FirstSeen) ||
+			!got[i].LastSeen.Equal(want[i].LastSeen) || !slices.Equal(got[i].Present, want[i].Present) {
+			t.Errorf("got status %+v, want %+v", got[i], want[i])
+		}
+	}
+}
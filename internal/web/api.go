@@ -0,0 +1,264 @@
+package web
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/oled"
+	"github.com/swills/luma-adsb/internal/routes"
+)
+
+const (
+	// apiVersion is the version of the API, a change that breaks clients gets a new version with its own paths
+	apiVersion = "1"
+	// apiPrefix starts the paths of this version of the API
+	apiPrefix = "/api/v" + apiVersion
+)
+
+// endpoint is one path of the API, documented in the schema by the type it responds with
+type endpoint struct {
+	path        string
+	description string
+	response    reflect.Type
+	respond     func(snapshot Snapshot) any
+}
+
+var apiEndpoints = []endpoint{
+	{
+		path:        apiPrefix + "/summary",
+		description: "What the display shows, the aircraft counts, closest aircraft, feeder tallies and system state",
+		response:    reflect.TypeFor[Summary](),
+		respond:     func(snapshot Snapshot) any { return NewSummary(snapshot.Data) },
+	},
+	{
+		path:        apiPrefix + "/aircraft",
+		description: "The aircraft passing the configured filter, nearest first followed by those with no position",
+		response:    reflect.TypeFor[AircraftList](),
+		respond: func(snapshot Snapshot) any {
+			return AircraftList{
+				Time:     snapshot.Data.Now,
+				Aircraft: NewAircraftList(snapshot.Data.Station, snapshot.Data.Aircraft),
+			}
+		},
+	},
+	{
+		path:        apiPrefix + "/feeders",
+		description: "The status of each feeder and the tallies of good and bad connections",
+		response:    reflect.TypeFor[FeederList](),
+		respond: func(snapshot Snapshot) any {
+			return FeederList{
+				Time:    snapshot.Data.Now,
+				Good:    snapshot.Data.FeedersGood,
+				Bad:     snapshot.Data.FeedersBad,
+				Feeders: NewFeederList(snapshot.Data.Feeders),
+			}
+		},
+	},
+	{
+		path:        apiPrefix + "/system",
+		description: "The CPU temperature, whether an adsb.im update is available and the health of the display",
+		response:    reflect.TypeFor[System](),
+		respond: func(snapshot Snapshot) any {
+			return System{
+				Time:            snapshot.Data.Now,
+				CPUTempC:        snapshot.Data.CPUTempC,
+				UpdateAvailable: snapshot.Data.UpdateAvailable,
+				Display:         snapshot.Display,
+			}
+		},
+	},
+}
+
+// Summary is what the display works out from the aircraft passing the configured filter
+type Summary struct {
+	Time            time.Time `json:"time"`
+	Total           int       `json:"total"`
+	WithPosition    int       `json:"with_position"`
+	WithoutPosition int       `json:"without_position"`
+	FeedersGood     int       `json:"feeders_good"`
+	FeedersBad      int       `json:"feeders_bad"`
+	CPUTempC        int       `json:"cpu_temp_c"`
+	UpdateAvailable bool      `json:"update_available"`
+	Closest         *Closest  `json:"closest,omitempty"`
+}
+
+func (Summary) fieldDocs() map[string]string {
+	return map[string]string{
+		"total":            "aircraft passing the configured filter",
+		"with_position":    "aircraft with a recent position",
+		"without_position": "aircraft without a recent position",
+		"feeders_good":     "beast and MLAT connections of enabled feeders that are good",
+		"feeders_bad":      "beast and MLAT connections of enabled feeders that are down",
+		"closest":          "the aircraft shown as the closest, left out if none have a position",
+	}
+}
+
+// Closest is the aircraft shown as the closest along with what's known about its flight
+type Closest struct {
+	Aircraft
+
+	Name      string    `json:"name"`
+	Elevation *float64  `json:"elevation,omitempty"`
+	Watched   string    `json:"watched,omitempty"`
+	Flight    *Flight   `json:"flight,omitempty"`
+	Route     *Route    `json:"route,omitempty"`
+	Approach  *Approach `json:"approach,omitempty"`
+}
+
+func (Closest) fieldDocs() map[string]string {
+	return map[string]string{
+		"name":      "the name shown on the display",
+		"elevation": "angle above the horizon in degrees, left out if the altitude isn't known",
+		"watched":   "the watchlist entry the aircraft matches",
+		"flight":    "the callsign decoded into the airline and flight number",
+		"route":     "where the flight is going, left out if it isn't known or doesn't fit where the aircraft is",
+		"approach":  "the predicted closest approach, left out if the aircraft isn't sending its speed and track",
+	}
+}
+
+// Flight is a callsign decoded into the airline and flight number
+type Flight struct {
+	Airline string `json:"airline"`
+	ICAO    string `json:"icao"`
+	IATA    string `json:"iata,omitempty"`
+	Number  string `json:"number"`
+}
+
+func (Flight) fieldDocs() map[string]string {
+	return map[string]string{
+		"airline": "the airline's name",
+		"icao":    "the airline's ICAO designator",
+		"iata":    "the flight number with the airline's IATA code, like WN1234",
+		"number":  "the flight number without leading zeros",
+	}
+}
+
+// Route is where a flight is going
+type Route struct {
+	Airports []routes.Airport `json:"airports"`
+}
+
+func (Route) fieldDocs() map[string]string {
+	return map[string]string{
+		"airports": "the origin first and the destination last, with any stops in between",
+	}
+}
+
+// Approach is the predicted closest approach of an aircraft to the station
+type Approach struct {
+	Seconds            float64 `json:"seconds"`
+	Distance           float64 `json:"distance"`
+	HorizontalDistance float64 `json:"horizontal_distance"`
+	Approaching        bool    `json:"approaching"`
+	Overhead           bool    `json:"overhead"`
+}
+
+func (Approach) fieldDocs() map[string]string {
+	return map[string]string{
+		"seconds":             "seconds until the closest approach, 0 if the aircraft is moving away",
+		"distance":            "slant range at the closest approach in miles",
+		"horizontal_distance": "distance along the ground at the closest approach in miles",
+		"overhead":            "whether the aircraft is predicted to pass over the station",
+	}
+}
+
+// AircraftList is the aircraft passing the configured filter
+type AircraftList struct {
+	Time     time.Time  `json:"time"`
+	Aircraft []Aircraft `json:"aircraft"`
+}
+
+// FeederList is the status of each feeder
+type FeederList struct {
+	Time    time.Time `json:"time"`
+	Good    int       `json:"good"`
+	Bad     int       `json:"bad"`
+	Feeders []Feeder  `json:"feeders"`
+}
+
+func (FeederList) fieldDocs() map[string]string {
+	return map[string]string{
+		"good": "beast and MLAT connections of enabled feeders that are good",
+		"bad":  "beast and MLAT connections of enabled feeders that are down",
+	}
+}
+
+// System is the state of the system luma-adsb runs on
+type System struct {
+	Time            time.Time   `json:"time"`
+	CPUTempC        int         `json:"cpu_temp_c"`
+	UpdateAvailable bool        `json:"update_available"`
+	Display         oled.Health `json:"display"`
+}
+
+// NewSummary works out the API summary from what the display shows
+func NewSummary(data *layout.Data) Summary {
+	summary := Summary{
+		Time:            data.Now,
+		Total:           data.Total,
+		WithPosition:    data.WithPosition,
+		WithoutPosition: data.WithoutPosition,
+		FeedersGood:     data.FeedersGood,
+		FeedersBad:      data.FeedersBad,
+		CPUTempC:        data.CPUTempC,
+		UpdateAvailable: data.UpdateAvailable,
+	}
+
+	if data.Closest != nil {
+		summary.Closest = newClosest(data)
+	}
+
+	return summary
+}
+
+func newClosest(data *layout.Data) *Closest {
+	closestPlane := data.Closest
+
+	closest := &Closest{
+		Aircraft: newAircraft(data.Station, closestPlane.Aircraft),
+		Name:     closestPlane.Name,
+		Watched:  data.Watched[closestPlane.Hex],
+	}
+
+	if closestPlane.HasElevation {
+		closest.Elevation = &closestPlane.Elevation
+	}
+
+	if flight := closestPlane.Flight; flight != nil {
+		closest.Flight = &Flight{
+			Airline: flight.Airline.Name,
+			ICAO:    flight.Airline.ICAO,
+			IATA:    flight.IATA(),
+			Number:  flight.Number,
+		}
+	}
+
+	if route := closestPlane.Route; route != nil {
+		closest.Route = &Route{Airports: route.Airports}
+	}
+
+	if approach := closestPlane.Approach; approach != nil {
+		closest.Approach = &Approach{
+			Seconds:            approach.Time.Seconds(),
+			Distance:           approach.Distance,
+			HorizontalDistance: approach.HorizontalDistance,
+			Approaching:        approach.Approaching,
+			Overhead:           approach.Overhead(),
+		}
+	}
+
+	return closest
+}
+
+// handleAPI responds with what respond gives for the current snapshot
+func (s *Server) handleAPI(respond func(snapshot Snapshot) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, respond(s.snapshot()))
+	}
+}
+
+func (s *Server) handleSchema(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.schema)
+}
@@ -0,0 +1,218 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/oled"
+	"github.com/swills/luma-adsb/internal/routes"
+)
+
+func testSnapshot() Snapshot {
+	seen, speed, track := 1.0, 450.0, 90.0
+	plane := adsb.Aircraft{
+		Hex: "a1b2c3", CallSign: "SWA1234 ", Latitude: 51.6, Longitude: -0.1, SeenPos: &seen, Altitude: 35000.0,
+		GroundSpeed: &speed, Track: &track,
+	}
+	station := adsb.Station{Latitude: 51.5, Longitude: -0.1}
+	airlines := adsb.NewAirlines(nil)
+	flight, _ := airlines.Decode(plane.CallSign)
+
+	return Snapshot{
+		Data: &layout.Data{
+			Now:          time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+			Station:      station,
+			Total:        2,
+			WithPosition: 1,
+			FeedersGood:  1,
+			Feeders:      map[string]adsb.FeederInfo{"adsbx": {Enabled: true, BeastStatus: "good"}},
+			Aircraft:     []adsb.Aircraft{plane, {Hex: "~0abcde", Altitude: "ground"}},
+			Closest: &layout.Closest{
+				Aircraft:         plane,
+				RelativePosition: adsb.RelativePosition{Distance: 6.9, HasElevation: true, Elevation: 45},
+				Name:             "Southwest 1234",
+				Flight:           &flight,
+				Route:            &routes.Route{CallSign: "SWA1234", Airports: []routes.Airport{{}, {}}},
+				Approach:         &adsb.Approach{Time: time.Minute, Distance: 1, Approaching: true},
+			},
+			Watched: map[string]string{"a1b2c3": "friends"},
+		},
+		Display: oled.Health{State: oled.HealthOK},
+	}
+}
+
+// TestAPIMatchesSchema checks that every endpoint responds with JSON that fits the schema the API publishes
+func TestAPIMatchesSchema(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer(nil, testSnapshot)
+
+	var document struct {
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+
+	get(t, server, apiPrefix+"/openapi.json", &document)
+
+	if len(document.Paths) != len(apiEndpoints) {
+		t.Errorf("got %d paths in the schema, want %d", len(document.Paths), len(apiEndpoints))
+	}
+
+	for _, ep := range apiEndpoints {
+		if !strings.HasPrefix(ep.path, "/api/v"+apiVersion+"/") {
+			t.Errorf("%s: not under the API version", ep.path)
+		}
+
+		path, ok := document.Paths[ep.path]
+		if !ok {
+			t.Errorf("%s: missing from the schema", ep.path)
+
+			continue
+		}
+
+		schema := lookupSchema(path, "get", "responses", "200", "content", "application/json", "schema")
+
+		var response any
+
+		get(t, server, ep.path, &response)
+
+		checkSchema(t, ep.path, document.Components.Schemas, schema, response)
+	}
+}
+
+func get(t *testing.T, server *Server, path string, result any) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: got status %d", path, rec.Code)
+	}
+
+	err := json.Unmarshal(rec.Body.Bytes(), result)
+	if err != nil {
+		t.Fatalf("%s: error decoding response: %s", path, err)
+	}
+}
+
+// lookupSchema follows keys down through nested objects, nil if any of them is missing
+func lookupSchema(value any, keys ...string) map[string]any {
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		value = object[key]
+	}
+
+	schema, _ := value.(map[string]any)
+
+	return schema
+}
+
+// checkSchema checks value against schema, following references into components
+func checkSchema(t *testing.T, where string, components map[string]any, schema map[string]any, value any) {
+	t.Helper()
+
+	if schema == nil {
+		t.Errorf("%s: no schema", where)
+
+		return
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		checkSchema(t, where, components, lookupSchema(components, strings.TrimPrefix(ref, schemaRefPrefix)), value)
+
+		return
+	}
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, part := range allOf {
+			partSchema, _ := part.(map[string]any)
+			checkSchema(t, where, components, partSchema, value)
+		}
+
+		return
+	}
+
+	switch schema["type"] {
+	case "object":
+		checkObject(t, where, components, schema, value)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			t.Errorf("%s: got %T, want an array", where, value)
+
+			return
+		}
+
+		for _, item := range items {
+			checkSchema(t, where+"[]", components, lookupSchema(schema, "items"), item)
+		}
+	case "string":
+		checkType[string](t, where, value)
+	case "number", "integer":
+		checkType[float64](t, where, value)
+	case "boolean":
+		checkType[bool](t, where, value)
+	}
+}
+
+func checkObject(t *testing.T, where string, components map[string]any, schema map[string]any, value any) {
+	t.Helper()
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		t.Errorf("%s: got %T, want an object", where, value)
+
+		return
+	}
+
+	if additional := lookupSchema(schema, "additionalProperties"); additional != nil {
+		for key, item := range object {
+			checkSchema(t, where+"."+key, components, additional, item)
+		}
+
+		return
+	}
+
+	properties := lookupSchema(schema, "properties")
+
+	for key, item := range object {
+		property := lookupSchema(properties, key)
+		if property == nil {
+			t.Errorf("%s: %s isn't in the schema", where, key)
+
+			continue
+		}
+
+		checkSchema(t, where+"."+key, components, property, item)
+	}
+
+	required, _ := schema["required"].([]any)
+
+	for _, key := range required {
+		name, _ := key.(string)
+		if _, ok := object[name]; !ok {
+			t.Errorf("%s: required %s is missing", where, name)
+		}
+	}
+}
+
+func checkType[T any](t *testing.T, where string, value any) {
+	t.Helper()
+
+	if _, ok := value.(T); !ok {
+		t.Errorf("%s: got %T for a %T", where, value, *new(T))
+	}
+}
@@ -0,0 +1,157 @@
+package web
+
+import (
+	"cmp"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// fieldDocumenter is implemented by API types to describe their fields in the schema, by JSON name
+type fieldDocumenter interface {
+	fieldDocs() map[string]string
+}
+
+// schemaBuilder works out JSON schemas from Go types the way encoding/json marshals them, collecting each struct as a
+// named component
+type schemaBuilder struct {
+	components map[string]any
+}
+
+// openAPIDocument describes the endpoints in an OpenAPI document generated from the types they respond with
+func openAPIDocument(endpoints []endpoint) map[string]any {
+	builder := &schemaBuilder{components: make(map[string]any)}
+
+	paths := make(map[string]any, len(endpoints))
+
+	for _, ep := range endpoints {
+		paths[ep.path] = map[string]any{
+			"get": map[string]any{
+				"description": ep.description,
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "OK",
+						"content": map[string]any{
+							"application/json": map[string]any{"schema": builder.schemaOf(ep.response)},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "luma-adsb",
+			"version": apiVersion,
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": builder.components},
+	}
+}
+
+func (b *schemaBuilder) schemaOf(typ reflect.Type) map[string]any {
+	if typ == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return b.schemaOf(typ.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schemaOf(typ.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schemaOf(typ.Elem())}
+	case reflect.Struct:
+		if _, ok := b.components[typ.Name()]; !ok {
+			// claim the name first so a type that refers back to itself doesn't recurse forever
+			b.components[typ.Name()] = nil
+			b.components[typ.Name()] = b.objectSchema(typ)
+		}
+
+		return map[string]any{"$ref": schemaRefPrefix + typ.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (b *schemaBuilder) objectSchema(typ reflect.Type) map[string]any {
+	properties := make(map[string]any)
+
+	var required []string
+
+	b.addFields(typ, properties, &required)
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// addFields adds the fields of typ to properties, flattening embedded structs as encoding/json does. Fields that can be
+// left out of the JSON aren't required.
+func (b *schemaBuilder) addFields(typ reflect.Type, properties map[string]any, required *[]string) {
+	var docs map[string]string
+
+	if documented, ok := reflect.Zero(typ).Interface().(fieldDocumenter); ok {
+		docs = documented.fieldDocs()
+	}
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(field.Type, properties, required)
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name = cmp.Or(name, field.Name)
+
+		schema := b.schemaOf(field.Type)
+
+		if doc, ok := docs[name]; ok {
+			// OpenAPI 3.0 ignores anything alongside a $ref
+			if _, isRef := schema["$ref"]; isRef {
+				schema = map[string]any{"allOf": []any{schema}}
+			}
+
+			schema["description"] = doc
+		}
+
+		properties[name] = schema
+
+		if field.Type.Kind() != reflect.Pointer && !strings.Contains(options, "omit") {
+			*required = append(*required, name)
+		}
+	}
+}
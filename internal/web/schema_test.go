@@ -0,0 +1,110 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type schemaBase struct {
+	ID string `json:"id"`
+}
+
+type schemaChild struct {
+	Name string `json:"name"`
+}
+
+// schemaNode refers back to itself through Children
+type schemaNode struct {
+	Children []schemaNode `json:"children"`
+}
+
+type schemaTest struct {
+	schemaBase
+
+	Count    int     `json:"count"`
+	Ratio    float64 `json:"ratio,omitempty"`
+	Flag     bool    `json:"flag,omitzero"`
+	Untagged string
+	Optional *int                   `json:"optional"`
+	When     time.Time              `json:"when"`
+	Tags     []string               `json:"tags"`
+	Counts   map[string]uint8       `json:"counts"`
+	Child    schemaChild            `json:"child"`
+	Children []*schemaChild         `json:"children"`
+	Node     *schemaNode            `json:"node,omitempty"`
+	Anything any                    `json:"anything"`
+	Skipped  string                 `json:"-"`
+	hidden   string                 //nolint:unused
+	Extra    map[string]schemaChild `json:"extra"`
+}
+
+func (schemaTest) fieldDocs() map[string]string {
+	return map[string]string{
+		"count": "how many",
+		"child": "the child",
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	t.Parallel()
+
+	builder := &schemaBuilder{components: make(map[string]any)}
+
+	got := builder.schemaOf(reflect.TypeFor[*schemaTest]())
+	if want := map[string]any{"$ref": schemaRefPrefix + "schemaTest"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	childRef := map[string]any{"$ref": schemaRefPrefix + "schemaChild"}
+	want := map[string]any{
+		"schemaTest": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id":       map[string]any{"type": "string"},
+				"count":    map[string]any{"type": "integer", "description": "how many"},
+				"ratio":    map[string]any{"type": "number"},
+				"flag":     map[string]any{"type": "boolean"},
+				"Untagged": map[string]any{"type": "string"},
+				"optional": map[string]any{"type": "integer"},
+				"when":     map[string]any{"type": "string", "format": "date-time"},
+				"tags":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"counts": map[string]any{
+					"type": "object", "additionalProperties": map[string]any{"type": "integer"},
+				},
+				"child":    map[string]any{"allOf": []any{childRef}, "description": "the child"},
+				"children": map[string]any{"type": "array", "items": childRef},
+				"node":     map[string]any{"$ref": schemaRefPrefix + "schemaNode"},
+				"anything": map[string]any{},
+				"extra":    map[string]any{"type": "object", "additionalProperties": childRef},
+			},
+			"required": []string{
+				"id", "count", "Untagged", "when", "tags", "counts", "child", "children", "anything", "extra",
+			},
+		},
+		"schemaChild": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			"required":   []string{"name"},
+		},
+		"schemaNode": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"children": map[string]any{
+					"type": "array", "items": map[string]any{"$ref": schemaRefPrefix + "schemaNode"},
+				},
+			},
+			"required": []string{"children"},
+		},
+	}
+
+	for name, component := range want {
+		if !reflect.DeepEqual(builder.components[name], component) {
+			t.Errorf("%s: got\n%v\nwant\n%v", name, builder.components[name], component)
+		}
+	}
+
+	if len(builder.components) != len(want) {
+		t.Errorf("got %d components, want %d", len(builder.components), len(want))
+	}
+}
@@ -0,0 +1,224 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// frameInterval is how often the display is checked for a new frame to send, it only changes every 125ms
+	frameInterval = 250 * time.Millisecond
+	stateInterval = time.Second
+
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// FrameSource gives the frames rendered to the display, each with a number that changes when a new one is rendered
+type FrameSource interface {
+	Frame() (*image.RGBA, uint64)
+}
+
+// Server serves the dashboard, a page mirroring the display and showing the aircraft, feeders and system state, and the
+// JSON API
+type Server struct {
+	display  FrameSource
+	snapshot func() Snapshot
+	mux      *http.ServeMux
+	// schema is the OpenAPI document describing the API
+	schema map[string]any
+}
+
+// NewServer creates a dashboard and API showing the frames rendered to display and the state returned by snapshot
+func NewServer(display FrameSource, snapshot func() Snapshot) *Server {
+	server := &Server{
+		display:  display,
+		snapshot: snapshot,
+		mux:      http.NewServeMux(),
+		schema:   openAPIDocument(apiEndpoints),
+	}
+
+	server.mux.HandleFunc("GET /{$}", server.handleIndex)
+	server.mux.HandleFunc("GET /frame.png", server.handleFrame)
+	server.mux.HandleFunc("GET /state.json", server.handleState)
+	server.mux.HandleFunc("GET /events", server.handleEvents)
+
+	for _, ep := range apiEndpoints {
+		server.mux.HandleFunc("GET "+ep.path, server.handleAPI(ep.respond))
+	}
+
+	server.mux.HandleFunc("GET "+apiPrefix+"/openapi.json", server.handleSchema)
+
+	return server
+}
+
+// Handle serves handler on pattern alongside the dashboard and API
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves handler on addr until ctx is done
+func ListenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving http: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	_, _ = w.Write(indexHTML)
+}
+
+func (s *Server) handleFrame(w http.ResponseWriter, _ *http.Request) {
+	frame, _, err := s.encodeFrame()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+
+	_, _ = w.Write(frame)
+}
+
+func (s *Server) handleState(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, NewState(s.snapshot()))
+}
+
+// handleEvents streams the frames as base64 PNGs in "frame" events as they're rendered, and the state as JSON in
+// "state" events every second
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	controller := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+
+	frameTicker := time.NewTicker(frameInterval)
+	defer frameTicker.Stop()
+
+	stateTicker := time.NewTicker(stateInterval)
+	defer stateTicker.Stop()
+
+	// no frame has this number, so the first check always sends the current frame
+	lastFrame := ^uint64(0)
+
+	err := s.sendState(w, controller)
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-frameTicker.C:
+			lastFrame, err = s.sendFrame(w, controller, lastFrame)
+		case <-stateTicker.C:
+			err = s.sendState(w, controller)
+		}
+	}
+}
+
+// sendFrame sends the current frame if it isn't lastFrame, returning the frame number sent
+func (s *Server) sendFrame(w http.ResponseWriter, controller *http.ResponseController, lastFrame uint64) (
+	uint64, error) {
+	frame, number, err := s.encodeFrame()
+	if err != nil {
+		return lastFrame, err
+	}
+
+	if number == lastFrame {
+		return lastFrame, nil
+	}
+
+	return number, sendEvent(w, controller, "frame", []byte(base64.StdEncoding.EncodeToString(frame)))
+}
+
+func (s *Server) sendState(w http.ResponseWriter, controller *http.ResponseController) error {
+	state, err := json.Marshal(NewState(s.snapshot()))
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+
+	return sendEvent(w, controller, "state", state)
+}
+
+// encodeFrame returns the current frame as a PNG along with its number
+func (s *Server) encodeFrame() ([]byte, uint64, error) {
+	frame, number := s.display.Frame()
+
+	var buf bytes.Buffer
+
+	err := png.Encode(&buf, frame)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error encoding frame: %w", err)
+	}
+
+	return buf.Bytes(), number, nil
+}
+
+func sendEvent(w http.ResponseWriter, controller *http.ResponseController, event string, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	if err != nil {
+		return fmt.Errorf("error sending %s event: %w", event, err)
+	}
+
+	err = controller.Flush()
+	if err != nil {
+		return fmt.Errorf("error sending %s event: %w", event, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	_, _ = w.Write(body)
+}
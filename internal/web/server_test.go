@@ -0,0 +1,241 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+// testFrames gives blank frames with a single pixel lit along the top, as far across as the frame's number
+type testFrames struct {
+	number atomic.Uint64
+}
+
+func (f *testFrames) Frame() (*image.RGBA, uint64) {
+	number := f.number.Load()
+	frame := image.NewRGBA(image.Rect(0, 0, oled.Width, oled.Height))
+	frame.Set(int(number), 0, color.White)
+
+	return frame, number
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer(&testFrames{}, testSnapshot)
+
+	tests := []struct {
+		path        string
+		wantStatus  int
+		contentType string
+		check       func(t *testing.T, body []byte)
+	}{
+		{"/", http.StatusOK, "text/html; charset=utf-8", func(t *testing.T, body []byte) {
+			t.Helper()
+
+			if !bytes.Contains(body, []byte("<title>luma-adsb</title>")) {
+				t.Errorf("got %.100s, want the dashboard", body)
+			}
+		}},
+		{"/frame.png", http.StatusOK, "image/png", func(t *testing.T, body []byte) {
+			t.Helper()
+
+			checkFrame(t, body, 0)
+		}},
+		{"/state.json", http.StatusOK, "application/json", func(t *testing.T, body []byte) {
+			t.Helper()
+
+			checkState(t, body)
+		}},
+		{"/missing", http.StatusNotFound, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			if tt.check == nil {
+				return
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("got content type %q, want %q", got, tt.contentType)
+			}
+
+			tt.check(t, rec.Body.Bytes())
+		})
+	}
+}
+
+func TestServerEvents(t *testing.T) {
+	t.Parallel()
+
+	frames := &testFrames{}
+	server := NewServer(frames, testSnapshot)
+	done := make(chan struct{})
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+
+		server.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/events", nil)
+	if err != nil {
+		t.Fatalf("error creating request: %s", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error requesting events: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("got content type %q, want text/event-stream", got)
+	}
+
+	events := bufio.NewReader(resp.Body)
+
+	// the state is sent straight away, then the frame, then a new frame as soon as there is one
+	event, data := readEvent(t, events)
+	if event != "state" {
+		t.Fatalf("got %s event first, want state", event)
+	}
+
+	checkState(t, data)
+
+	event, data = readEvent(t, events)
+	if event != "frame" {
+		t.Fatalf("got %s event second, want frame", event)
+	}
+
+	checkFrame(t, decodeBase64(t, data), 0)
+
+	frames.number.Store(5)
+
+	event, data = readEvent(t, events)
+	if event != "frame" {
+		t.Fatalf("got %s event after a new frame, want frame", event)
+	}
+
+	checkFrame(t, decodeBase64(t, data), 5)
+
+	// the same frame isn't sent again, so the next event is the state a second after the first
+	event, data = readEvent(t, events)
+	if event != "state" {
+		t.Fatalf("got %s event, want state", event)
+	}
+
+	checkState(t, data)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("still streaming events after the request was cancelled")
+	}
+}
+
+// readEvent reads the next server-sent event, returning its name and data
+func readEvent(t *testing.T, events *bufio.Reader) (string, []byte) {
+	t.Helper()
+
+	var (
+		event string
+		data  []byte
+	)
+
+	for {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error reading event: %s", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = []byte(strings.TrimPrefix(line, "data: "))
+		default:
+			t.Fatalf("got line %q, want an event or its data", line)
+		}
+	}
+}
+
+func decodeBase64(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		t.Fatalf("error decoding frame: %s", err)
+	}
+
+	return decoded
+}
+
+// checkFrame checks body is a PNG of a display frame with only the pixel for number lit along the top
+func checkFrame(t *testing.T, body []byte, number int) {
+	t.Helper()
+
+	frame, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("error decoding frame: %s", err)
+	}
+
+	if frame.Bounds() != image.Rect(0, 0, oled.Width, oled.Height) {
+		t.Fatalf("got a frame of %s, want %dx%d", frame.Bounds(), oled.Width, oled.Height)
+	}
+
+	for x := range oled.Width {
+		red, _, _, _ := frame.At(x, 0).RGBA()
+		if lit := red > 0; lit != (x == number) {
+			t.Errorf("got pixel %d lit %t, want %t", x, lit, x == number)
+		}
+	}
+}
+
+// checkState checks body is the JSON state for testSnapshot
+func checkState(t *testing.T, body []byte) {
+	t.Helper()
+
+	var state State
+
+	err := json.Unmarshal(body, &state)
+	if err != nil {
+		t.Fatalf("error decoding state: %s", err)
+	}
+
+	if len(state.Aircraft) != 2 || state.Aircraft[0].Hex != "a1b2c3" || state.Display.State != oled.HealthOK {
+		t.Errorf("got state %+v, want the aircraft and display health of the snapshot", state)
+	}
+}
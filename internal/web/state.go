@@ -0,0 +1,161 @@
+package web
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/swills/luma-adsb/internal/adsb"
+	"github.com/swills/luma-adsb/internal/layout"
+	"github.com/swills/luma-adsb/internal/oled"
+)
+
+// Snapshot is the shared state of luma-adsb at one moment
+type Snapshot struct {
+	// Data is what the display works out from the aircraft passing the configured filter
+	Data    *layout.Data
+	Display oled.Health
+}
+
+// State is a Snapshot as shown on the dashboard
+type State struct {
+	Time            time.Time   `json:"time"`
+	UpdateAvailable bool        `json:"update_available"`
+	CPUTempC        int         `json:"cpu_temp_c"`
+	Display         oled.Health `json:"display"`
+	Aircraft        []Aircraft  `json:"aircraft"`
+	Feeders         []Feeder    `json:"feeders"`
+}
+
+// Aircraft is one aircraft and where it is as seen from the station. The fields about where it is are left out if it
+// has no recent position.
+type Aircraft struct {
+	Hex          string   `json:"hex"`
+	CallSign     string   `json:"callsign,omitempty"`
+	Registration string   `json:"registration,omitempty"`
+	TypeCode     string   `json:"type,omitempty"`
+	Squawk       string   `json:"squawk,omitempty"`
+	Altitude     *float64 `json:"altitude,omitempty"`
+	OnGround     bool     `json:"on_ground,omitempty"`
+	GroundSpeed  *float64 `json:"ground_speed,omitempty"`
+	Track        *float64 `json:"track,omitempty"`
+	Distance     *float64 `json:"distance,omitempty"`
+	SlantRange   *float64 `json:"slant_range,omitempty"`
+	Bearing      *float64 `json:"bearing,omitempty"`
+	Compass      string   `json:"compass,omitempty"`
+	Seen         float64  `json:"seen"`
+}
+
+func (Aircraft) fieldDocs() map[string]string {
+	return map[string]string{
+		"hex":          "ICAO address",
+		"type":         "ICAO aircraft type designator",
+		"altitude":     "barometric altitude in feet",
+		"ground_speed": "ground speed in knots",
+		"track":        "track in degrees, 0 being north",
+		"distance":     "distance along the ground from the station in miles",
+		"slant_range":  "straight line distance from the station in miles",
+		"bearing":      "bearing from the station in degrees, 0 being north",
+		"compass":      "bearing as one of the 16 points of the compass",
+		"seen":         "seconds since the aircraft was last heard",
+	}
+}
+
+// Feeder is the status of one feeder
+type Feeder struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Beast   string `json:"beast,omitempty"`
+	MLAT    string `json:"mlat,omitempty"`
+}
+
+// NewState works out the dashboard state from a snapshot
+func NewState(snapshot Snapshot) State {
+	data := snapshot.Data
+
+	return State{
+		Time:            data.Now,
+		UpdateAvailable: data.UpdateAvailable,
+		CPUTempC:        data.CPUTempC,
+		Display:         snapshot.Display,
+		Aircraft:        NewAircraftList(data.Station, data.Aircraft),
+		Feeders:         NewFeederList(data.Feeders),
+	}
+}
+
+// NewAircraftList lists the aircraft nearest first, followed by those with no position ordered by hex
+func NewAircraftList(station adsb.Station, planes []adsb.Aircraft) []Aircraft {
+	aircraft := make([]Aircraft, 0, len(planes))
+
+	for _, flight := range planes {
+		aircraft = append(aircraft, newAircraft(station, flight))
+	}
+
+	slices.SortStableFunc(aircraft, func(a, b Aircraft) int {
+		switch {
+		case a.SlantRange == nil && b.SlantRange == nil:
+			return strings.Compare(a.Hex, b.Hex)
+		case a.SlantRange == nil:
+			return 1
+		case b.SlantRange == nil:
+			return -1
+		default:
+			return cmp.Compare(*a.SlantRange, *b.SlantRange)
+		}
+	})
+
+	return aircraft
+}
+
+// NewFeederList lists the feeders by name
+func NewFeederList(feederStatus map[string]adsb.FeederInfo) []Feeder {
+	feeders := make([]Feeder, 0, len(feederStatus))
+
+	for _, name := range slices.Sorted(maps.Keys(feederStatus)) {
+		info := feederStatus[name]
+
+		feeders = append(feeders, Feeder{
+			Name:    name,
+			Enabled: info.Enabled,
+			Beast:   info.BeastStatus,
+			MLAT:    info.MLATStatus,
+		})
+	}
+
+	return feeders
+}
+
+func newAircraft(station adsb.Station, flight adsb.Aircraft) Aircraft {
+	aircraft := Aircraft{
+		Hex:          flight.Hex,
+		CallSign:     strings.TrimSpace(flight.CallSign),
+		Registration: flight.Registration,
+		TypeCode:     flight.TypeCode,
+		Squawk:       flight.Squawk,
+		GroundSpeed:  flight.GroundSpeed,
+		Track:        flight.Track,
+		Seen:         flight.Seen,
+	}
+
+	switch altitude := flight.Altitude.(type) {
+	case float64:
+		aircraft.Altitude = &altitude
+	case string:
+		aircraft.OnGround = altitude == "ground"
+	}
+
+	// aircraft with no recent position are still listed, just without where they are
+	relative, err := station.RelativeTo(flight)
+	if err != nil {
+		return aircraft
+	}
+
+	aircraft.Distance = &relative.Distance
+	aircraft.SlantRange = &relative.SlantRange
+	aircraft.Bearing = &relative.Bearing
+	aircraft.Compass = relative.Compass
+
+	return aircraft
+}